@@ -0,0 +1,31 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/miloradbozic/packing-service/internal/app"
+)
+
+// Migrations and templates are bundled into the binary so it runs from any working directory
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// embeddedAssets returns the bundled files rooted at their directories
+func embeddedAssets() (app.Assets, error) {
+	migrations, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return app.Assets{}, err
+	}
+
+	templates, err := fs.Sub(templateFiles, "templates")
+	if err != nil {
+		return app.Assets{}, err
+	}
+
+	return app.Assets{Migrations: migrations, Templates: templates}, nil
+}
@@ -1,32 +1,108 @@
 package app
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/broadcast"
+	"github.com/miloradbozic/packing-service/internal/certs"
 	"github.com/miloradbozic/packing-service/internal/config"
 	"github.com/miloradbozic/packing-service/internal/database"
 	"github.com/miloradbozic/packing-service/internal/handlers"
+	"github.com/miloradbozic/packing-service/internal/logging"
+	"github.com/miloradbozic/packing-service/internal/metrics"
+	"github.com/miloradbozic/packing-service/internal/ratelimit"
 	"github.com/miloradbozic/packing-service/internal/service"
+	"github.com/miloradbozic/packing-service/internal/tracing"
+	"github.com/miloradbozic/packing-service/internal/webhooks"
 )
 
+// Assets holds the migration and template files the application needs at runtime
+type Assets struct {
+	Migrations fs.FS
+	Templates  fs.FS
+}
+
+// WithOverrides replaces embedded assets with directories on disk where the config sets them
+func (a Assets) WithOverrides(cfg config.AssetsConfig) Assets {
+	if cfg.MigrationsDir != "" {
+		a.Migrations = os.DirFS(cfg.MigrationsDir)
+	}
+	if cfg.TemplatesDir != "" {
+		a.Templates = os.DirFS(cfg.TemplatesDir)
+	}
+	return a
+}
+
 // App represents the application with all its dependencies
 type App struct {
 	config *config.Config
+	assets Assets
 	db     *database.DB
 	router *mux.Router
+	// metrics instruments every request to router
+	metrics *metrics.Metrics
+	jwks    *auth.KeySet
+	// certs and tlsConfig serve HTTPS when TLS is enabled
+	certs     *certs.Reloader
+	tlsConfig *tls.Config
+	// configPath is the file the config was loaded from, and snapshot the
+	// effective config after reloads
+	configPath string
+	snapshot   atomic.Pointer[handlers.ConfigSnapshot]
+	reloadMu   sync.Mutex
+	// validator, limiter and idempotency take the reloadable settings
+	validator   *handlers.RequestValidator
+	limiter     *handlers.RateLimiter
+	idempotency *handlers.Idempotency
+	// dispatcher delivers webhook events, and is nil without a database
+	dispatcher *webhooks.Dispatcher
+	// broker fans out pack size changes to event streams, fed by listener
+	broker   *broadcast.Broker
+	listener *database.PackSizeListener
+	// workers run in the background and must finish before the database is closed
+	workers []worker
+	// shutdownTracing flushes spans that have not been exported yet
+	shutdownTracing func(context.Context) error
+}
+
+// worker is a background task that can be waited for on shutdown
+type worker interface {
+	Wait()
 }
 
 // New creates a new application instance
-func New() (*App, error) {
+func New(assets Assets) (*App, error) {
 	app := &App{}
 	
 	if err := app.loadConfig(); err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
+	app.assets = assets.WithOverrides(app.config.Assets)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), app.config.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup tracing: %w", err)
+	}
+	app.shutdownTracing = shutdownTracing
+	
+	if err := app.setupTLS(); err != nil {
+		return nil, fmt.Errorf("failed to setup tls: %w", err)
+	}
 	
 	if err := app.setupDatabase(); err != nil {
 		return nil, fmt.Errorf("failed to setup database: %w", err)
@@ -35,23 +111,29 @@ func New() (*App, error) {
 	if err := app.setupRoutes(); err != nil {
 		return nil, fmt.Errorf("failed to setup routes: %w", err)
 	}
+
+	if err := app.setupWebhooks(); err != nil {
+		return nil, fmt.Errorf("failed to setup webhooks: %w", err)
+	}
+
+	app.setupPackSizeListener()
 	
 	return app, nil
 }
 
 // loadConfig loads the application configuration
 func (a *App) loadConfig() error {
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config.yaml"
-	}
-
-	cfg, err := config.Load(configPath)
+	a.configPath = config.DefaultPath()
+	cfg, err := config.Load(a.configPath)
 	if err != nil {
 		return err
 	}
+	if err := logging.Setup(cfg.Logging); err != nil {
+		return err
+	}
 	
 	a.config = cfg
+	a.snapshot.Store(&handlers.ConfigSnapshot{Config: *cfg, LoadedAt: time.Now()})
 	return nil
 }
 
@@ -64,74 +146,582 @@ func (a *App) setupDatabase() error {
 	}
 	a.db = db
 
+	migrator, err := NewMigrator(db, a.config.Database, a.assets.Migrations)
+	if err != nil {
+		return err
+	}
+
+	// Leave migrations to the migrate command, but refuse to start on a modified schema
+	if a.config.Database.SkipMigrations {
+		return checkMigrations(context.Background(), migrator)
+	}
+
 	// Run migrations
-	migrator := database.NewMigrator(db)
-	if err := migrator.RunMigrations("migrations"); err != nil {
+	if err := migrator.RunMigrations(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// NewMigrator creates a migrator configured from the database settings
+func NewMigrator(db *database.DB, cfg config.DatabaseConfig, migrations fs.FS) (*database.Migrator, error) {
+	migrator := database.NewMigrator(db, migrations)
+
+	if cfg.MigrationLockTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.MigrationLockTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration_lock_timeout: %w", err)
+		}
+		migrator.SetLockTimeout(timeout)
+	}
+
+	return migrator, nil
+}
+
+// checkMigrations reports pending and modified migrations without applying anything
+func checkMigrations(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
+	}
+
+	for _, status := range statuses {
+		if status.Drift {
+			return fmt.Errorf("applied migration %s was modified", status.Version)
+		}
+		if !status.Applied {
+			slog.Warn("Migration is pending; run the migrate command to apply it", "version", status.Version)
+		}
+	}
+
+	return nil
+}
+
 // setupRoutes configures all the HTTP routes
 func (a *App) setupRoutes() error {
 	// Initialize repository and services
 	packSizeRepo := database.NewPackSizeRepository(a.db)
+	tenantRepo := database.NewTenantRepository(a.db)
+	apiKeyRepo := database.NewAPIKeyRepository(a.db)
+	userRepo := database.NewUserRepository(a.db)
+	webhookRepo := database.NewWebhookRepository(a.db)
 	packingService := service.NewPackingService(packSizeRepo)
+	// Calculations are recorded in the outbox for webhook delivery; pack size
+	// changes record their events in their own transactions
+	packingService.SetEventRecorder(database.NewOutboxRepository(a.db))
+
+	// Prometheus metrics for requests, calculations and the connection pool
+	appMetrics := metrics.New()
+	if a.db != nil {
+		appMetrics.RegisterDB(a.db.DB, "packing")
+	}
+	packingService.SetObserver(appMetrics)
 
 	// Initialize handlers
-	apiHandler := handlers.NewAPIHandler(packingService, packSizeRepo)
-	webHandler, err := handlers.NewWebHandler(packingService, packSizeRepo)
+	validator := handlers.NewRequestValidator(a.config.Validation)
+	a.validator = validator
+	configHandler := handlers.NewConfigHandler(a.configSnapshot)
+	apiHandler := handlers.NewAPIHandler(packingService, packSizeRepo, validator)
+	tenantHandler := handlers.NewTenantHandler(tenantRepo, a.config.Tenancy, validator)
+	tenantResolver := handlers.NewTenantResolver(tenantRepo, a.config.Tenancy)
+	tokenValidator, oidcClient, err := a.setupTokens()
+	if err != nil {
+		return err
+	}
+	authenticator := handlers.NewAuthenticator(apiKeyRepo, tokenValidator, a.config.Auth)
+	if a.config.Server.TLS.ClientCAFile != "" {
+		certMapper, err := a.certificateMapper()
+		if err != nil {
+			return err
+		}
+		authenticator.SetCertificateMapper(certMapper)
+	}
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo, validator)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, validator)
+	a.broker = broadcast.NewBroker()
+	packSizeStream := handlers.NewPackSizeStream(a.broker, handlers.DefaultStreamHeartbeat)
+	sessions, err := handlers.NewSessionManager(a.config.Auth.Session)
+	if err != nil {
+		return err
+	}
+	loginHandler, err := handlers.NewLoginHandler(userRepo, tenantRepo, sessions, oidcClient, a.config.Tenancy.DefaultTenant, a.assets.Templates)
+	if err != nil {
+		return err
+	}
+	webHandler, err := handlers.NewWebHandler(packingService, packSizeRepo, a.assets.Templates, sessions, a.config.Auth.Enabled, validator)
+	if err != nil {
+		return err
+	}
+	docsHandler, err := handlers.NewDocsHandler(a.assets.Templates)
 	if err != nil {
 		return err
 	}
+	readinessChecks, startupChecks, err := a.healthChecks()
+	if err != nil {
+		return err
+	}
+	healthHandler := handlers.NewHealthHandler(readinessChecks, startupChecks, handlers.DefaultHealthCheckTimeout)
 
 	// Setup router
 	router := mux.NewRouter()
-	
+	router.Use(tracing.Middleware, validator.LimitBody)
+
+	// Health checks and metrics
+	router.HandleFunc("/health", a.healthCheck).Methods("GET")
+	router.HandleFunc("/health/live", healthHandler.Live).Methods("GET")
+	router.HandleFunc("/health/ready", healthHandler.Ready).Methods("GET")
+	router.HandleFunc("/health/startup", healthHandler.Startup).Methods("GET")
+	router.Handle("/metrics", appMetrics.Handler()).Methods("GET")
+
+	// API documentation
+	router.HandleFunc("/api/v1/openapi.json", docsHandler.OpenAPI).Methods("GET")
+	router.HandleFunc("/api/v1/docs", docsHandler.Docs).Methods("GET")
+
+	// Tenant admin routes
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(tenantHandler.RequireAdmin)
+	admin.HandleFunc("/tenants", tenantHandler.ListTenants).Methods("GET")
+	admin.HandleFunc("/tenants", tenantHandler.CreateTenant).Methods("POST")
+	admin.HandleFunc("/config", configHandler.GetConfig).Methods("GET")
+
+	// Web UI login routes
+	login := router.NewRoute().Subrouter()
+	login.Use(sessions.Middleware)
+	login.HandleFunc("/login", loginHandler.LoginPage).Methods("GET")
+	login.HandleFunc("/login", sessions.RequireCSRF(loginHandler.Login)).Methods("POST")
+	login.HandleFunc("/login/oidc", loginHandler.OIDCLogin).Methods("GET")
+	login.HandleFunc("/login/oidc/callback", loginHandler.OIDCCallback).Methods("GET")
+	login.HandleFunc("/logout", sessions.RequireCSRF(loginHandler.Logout)).Methods("POST")
+
+	// Everything below acts on behalf of a single tenant. An API key or a logged
+	// in session selects its own tenant; otherwise the tenant header is used.
+	scoped := router.NewRoute().Subrouter()
+	scoped.Use(authenticator.Middleware, sessions.Middleware, tenantResolver.Middleware)
+
+	// Rate limiting runs after authentication, so clients are told apart by
+	// credential. It is always installed, so it can be enabled by a config reload.
+	limiter, err := a.setupRateLimiter()
+	if err != nil {
+		return err
+	}
+	a.limiter = limiter
+	scoped.Use(limiter.Middleware)
+
+	// Retried POST requests with an Idempotency-Key get the original response
+	idempotencyRepo := database.NewIdempotencyRepository(a.db)
+	a.workers = append(a.workers, idempotencyRepo)
+	idempotency, err := handlers.NewIdempotency(idempotencyRepo, a.config.Idempotency)
+	if err != nil {
+		return err
+	}
+	a.idempotency = idempotency
+	scoped.Use(idempotency.Middleware)
+
 	// Web UI routes
-	router.HandleFunc("/", webHandler.HomePage).Methods("GET", "POST")
+	scoped.HandleFunc("/", webHandler.HomePage).Methods("GET")
+	scoped.HandleFunc("/", sessions.RequireCSRF(authenticator.Require(auth.ScopeCalculate, webHandler.HomePage))).Methods("POST")
 
 	// API routes
-	api := router.PathPrefix("/api/v1").Subrouter()
+	api := scoped.PathPrefix("/api/v1").Subrouter()
 	
 	// Calculation routes
-	api.HandleFunc("/calculate", apiHandler.Calculate).Methods("POST")
-	api.HandleFunc("/config", apiHandler.GetConfig).Methods("GET")
+	api.HandleFunc("/calculate", authenticator.Require(auth.ScopeCalculate, apiHandler.Calculate)).Methods("POST")
+	api.HandleFunc("/config", authenticator.Require(auth.ScopeRead, apiHandler.GetConfig)).Methods("GET")
 	
 	// Pack size management routes
-	api.HandleFunc("/pack-sizes", apiHandler.ListPackSizes).Methods("GET")
-	api.HandleFunc("/pack-sizes", apiHandler.CreatePackSize).Methods("POST")
-	api.HandleFunc("/pack-sizes/{id}", apiHandler.GetPackSize).Methods("GET")
-	api.HandleFunc("/pack-sizes/{id}", apiHandler.UpdatePackSize).Methods("PUT")
-	api.HandleFunc("/pack-sizes/{id}", apiHandler.DeletePackSize).Methods("DELETE")
+	api.HandleFunc("/pack-sizes", authenticator.Require(auth.ScopeRead, apiHandler.ListPackSizes)).Methods("GET")
+	api.HandleFunc("/pack-sizes", authenticator.Require(auth.ScopeManage, apiHandler.CreatePackSize)).Methods("POST")
+	api.HandleFunc("/pack-sizes/export", authenticator.Require(auth.ScopeRead, apiHandler.ExportPackSizes)).Methods("GET")
+	api.HandleFunc("/pack-sizes/import", authenticator.Require(auth.ScopeManage, apiHandler.ImportPackSizes)).Methods("POST")
+	api.HandleFunc("/pack-sizes/stream", authenticator.Require(auth.ScopeRead, packSizeStream.Stream)).Methods("GET")
+	api.HandleFunc("/pack-sizes/{id}", authenticator.Require(auth.ScopeRead, apiHandler.GetPackSize)).Methods("GET")
+	api.HandleFunc("/pack-sizes/{id}", authenticator.Require(auth.ScopeManage, apiHandler.UpdatePackSize)).Methods("PUT")
+	api.HandleFunc("/pack-sizes/{id}", authenticator.Require(auth.ScopeManage, apiHandler.DeletePackSize)).Methods("DELETE")
 
-	// Health check
-	router.HandleFunc("/health", a.healthCheck).Methods("GET")
+	// API key management routes
+	api.HandleFunc("/api-keys", authenticator.Require(auth.ScopeAdmin, apiKeyHandler.ListAPIKeys)).Methods("GET")
+	api.HandleFunc("/api-keys", authenticator.Require(auth.ScopeAdmin, apiKeyHandler.CreateAPIKey)).Methods("POST")
+	api.HandleFunc("/api-keys/{id}/rotate", authenticator.Require(auth.ScopeAdmin, apiKeyHandler.RotateAPIKey)).Methods("POST")
+	api.HandleFunc("/api-keys/{id}", authenticator.Require(auth.ScopeAdmin, apiKeyHandler.RevokeAPIKey)).Methods("DELETE")
+
+	// Webhook subscription routes
+	api.HandleFunc("/webhooks", authenticator.Require(auth.ScopeAdmin, webhookHandler.ListWebhooks)).Methods("GET")
+	api.HandleFunc("/webhooks", authenticator.Require(auth.ScopeAdmin, webhookHandler.CreateWebhook)).Methods("POST")
+	api.HandleFunc("/webhooks/{id}", authenticator.Require(auth.ScopeAdmin, webhookHandler.GetWebhook)).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", authenticator.Require(auth.ScopeAdmin, webhookHandler.UpdateWebhook)).Methods("PUT")
+	api.HandleFunc("/webhooks/{id}", authenticator.Require(auth.ScopeAdmin, webhookHandler.DeleteWebhook)).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id}/deliveries", authenticator.Require(auth.ScopeAdmin, webhookHandler.ListDeliveries)).Methods("GET")
+	api.HandleFunc("/webhooks/{id}/deliveries/{delivery_id}/retry", authenticator.Require(auth.ScopeAdmin, webhookHandler.RetryDelivery)).Methods("POST")
 
 	a.router = router
+	a.metrics = appMetrics
+	return nil
+}
+
+// setupWebhooks starts delivering the events recorded in the outbox
+func (a *App) setupWebhooks() error {
+	if a.db == nil {
+		return nil
+	}
+	cfg := a.config.Webhooks
+
+	// Empty settings are left zero and get the dispatcher defaults
+	dispatcherConfig := webhooks.DispatcherConfig{MaxAttempts: cfg.MaxAttempts}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"poll_interval", cfg.PollInterval, &dispatcherConfig.PollInterval},
+		{"timeout", cfg.Timeout, &dispatcherConfig.Timeout},
+		{"initial_backoff", cfg.InitialBackoff, &dispatcherConfig.InitialBackoff},
+		{"max_backoff", cfg.MaxBackoff, &dispatcherConfig.MaxBackoff},
+		{"retention", cfg.Retention, &dispatcherConfig.Retention},
+	} {
+		value, err := parseDurationDefault(d.value, 0)
+		if err != nil {
+			return fmt.Errorf("invalid webhooks %s: %w", d.name, err)
+		}
+		*d.dst = value
+	}
+
+	for _, network := range cfg.AllowedNetworks {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return fmt.Errorf("invalid webhooks allowed network: %w", err)
+		}
+		dispatcherConfig.AllowedNetworks = append(dispatcherConfig.AllowedNetworks, prefix)
+	}
+
+	a.dispatcher = webhooks.NewDispatcher(database.NewWebhookStore(a.db), dispatcherConfig)
+	a.dispatcher.Start()
 	return nil
 }
 
+// setupPackSizeListener feeds the pack size changes of every instance to the
+// event streams
+func (a *App) setupPackSizeListener() {
+	if a.db == nil {
+		return
+	}
+	a.listener = database.NewPackSizeListener(a.db, a.broker)
+	a.listener.Start()
+}
+
+// setupTokens loads the JWKS and starts refreshing it when JWT bearer tokens or
+// OIDC login are enabled. It returns the bearer token validator and the OIDC
+// client, each nil when the feature is disabled.
+func (a *App) setupTokens() (*auth.TokenValidator, *auth.OIDCClient, error) {
+	cfg := a.config.Auth.JWT
+	oidcCfg := a.config.Auth.OIDC
+	if !cfg.Enabled && !oidcCfg.Enabled {
+		return nil, nil, nil
+	}
+
+	refreshInterval, err := parseDurationDefault(cfg.RefreshInterval, 15*time.Minute)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid jwt refresh_interval: %w", err)
+	}
+	leeway, err := parseDurationDefault(cfg.Leeway, time.Minute)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid jwt leeway: %w", err)
+	}
+
+	roleScopes, err := parseRoleScopes(cfg.RoleScopes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys, err := auth.NewKeySet(cfg.JWKSURL, cfg.JWKSFile, refreshInterval)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	validatorConfig := auth.TokenValidatorConfig{
+		Issuer:      cfg.Issuer,
+		Audience:    cfg.Audience,
+		RolesClaim:  cfg.RolesClaim,
+		RoleScopes:  roleScopes,
+		TenantClaim: cfg.TenantClaim,
+		Leeway:      leeway,
+	}
+
+	var validator *auth.TokenValidator
+	if cfg.Enabled {
+		validator, err = auth.NewTokenValidator(keys, validatorConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid jwt config: %w", err)
+		}
+	}
+
+	var oidc *auth.OIDCClient
+	if oidcCfg.Enabled {
+		// ID tokens are issued to the client, so they carry the client ID as audience
+		validatorConfig.Audience = oidcCfg.ClientID
+		idTokens, err := auth.NewTokenValidator(keys, validatorConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid oidc config: %w", err)
+		}
+
+		oidc, err = auth.NewOIDCClient(auth.OIDCClientConfig{
+			ClientID:         oidcCfg.ClientID,
+			ClientSecret:     oidcCfg.ClientSecret,
+			AuthorizationURL: oidcCfg.AuthorizationURL,
+			TokenURL:         oidcCfg.TokenURL,
+			RedirectURL:      oidcCfg.RedirectURL,
+			Scopes:           oidcCfg.Scopes,
+		}, idTokens)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid oidc config: %w", err)
+		}
+	}
+
+	keys.Start()
+	a.jwks = keys
+	return validator, oidc, nil
+}
+
+// setupRateLimiter creates the rate limiter with the configured bucket store
+func (a *App) setupRateLimiter() (*handlers.RateLimiter, error) {
+	cfg := a.config.RateLimit
+
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "", "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		postgresStore := database.NewRateLimitStore(a.db)
+		a.workers = append(a.workers, postgresStore)
+		store = postgresStore
+	default:
+		return nil, fmt.Errorf("unknown rate limit store '%s': must be memory or postgres", cfg.Store)
+	}
+
+	limiter, err := handlers.NewRateLimiter(store, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+	limiter.SetCost("POST /api/v1/calculate", handlers.CalculateCost(cfg.CalculateItemsPerToken))
+
+	return limiter, nil
+}
+
+// parseRoleScopes validates the scopes configured for each role
+func parseRoleScopes(roleScopes map[string][]string) (map[string][]auth.Scope, error) {
+	parsed := make(map[string][]auth.Scope, len(roleScopes))
+	for role, names := range roleScopes {
+		scopes, err := auth.ParseScopes(names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scopes for role %s: %w", role, err)
+		}
+		parsed[role] = scopes
+	}
+	return parsed, nil
+}
+
+// parseDurationDefault parses value, returning fallback when it is empty
+func parseDurationDefault(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.ParseDuration(value)
+}
+
 // healthCheck handles the health check endpoint
 func (a *App) healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
-// Run starts the HTTP server
+// Run starts the HTTP and HTTPS servers and serves until SIGTERM or SIGINT,
+// then drains in-flight requests. The config is reloaded on SIGHUP and when its
+// file changes.
 func (a *App) Run() error {
-	addr := fmt.Sprintf("%s:%d", a.config.Server.Host, a.config.Server.Port)
-	
-	log.Printf("Starting server on %s", addr)
-	log.Printf("Web UI available at http://localhost:%d", a.config.Server.Port)
-	log.Printf("API available at http://localhost:%d/api/v1", a.config.Server.Port)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	return http.ListenAndServe(addr, a.router)
+	endpoints, err := a.listen()
+	if err != nil {
+		return err
+	}
+
+	go a.watchConfig(ctx)
+	return a.serve(ctx, endpoints)
 }
 
-// Close cleans up resources
+// endpoint is a server together with the listener it serves
+type endpoint struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// listen opens the plain HTTP listener, unless it is disabled, and the TLS
+// listener when TLS is enabled
+func (a *App) listen() ([]endpoint, error) {
+	cfg := a.config.Server
+	var endpoints []endpoint
+	fail := func(err error) ([]endpoint, error) {
+		for _, e := range endpoints {
+			e.listener.Close()
+		}
+		return nil, err
+	}
+
+	if !cfg.TLS.Enabled || !cfg.TLS.DisablePlain {
+		server, err := a.newServer(fmt.Sprintf("%s:%d", cfg.Host, cfg.Port), nil)
+		if err != nil {
+			return fail(err)
+		}
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			return fail(fmt.Errorf("failed to listen on %s: %w", server.Addr, err))
+		}
+		endpoints = append(endpoints, endpoint{server: server, listener: listener})
+
+		slog.Info("Starting server", "addr", server.Addr)
+		slog.Info(fmt.Sprintf("Web UI available at http://localhost:%d", cfg.Port))
+		slog.Info(fmt.Sprintf("API available at http://localhost:%d/api/v1", cfg.Port))
+	}
+
+	if cfg.TLS.Enabled {
+		port := cfg.TLS.Port
+		if port == 0 {
+			port = defaultTLSPort
+		}
+		server, err := a.newServer(fmt.Sprintf("%s:%d", cfg.Host, port), a.tlsConfig)
+		if err != nil {
+			return fail(err)
+		}
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			return fail(fmt.Errorf("failed to listen on %s: %w", server.Addr, err))
+		}
+		endpoints = append(endpoints, endpoint{server: server, listener: tls.NewListener(listener, server.TLSConfig)})
+
+		slog.Info("Starting TLS server", "addr", server.Addr, "client_ca", cfg.TLS.ClientCAFile != "")
+	}
+
+	return endpoints, nil
+}
+
+// newServer creates an HTTP server with the configured timeouts. tlsConfig is
+// nil for plain HTTP.
+func (a *App) newServer(addr string, tlsConfig *tls.Config) (*http.Server, error) {
+	cfg := a.config.Server
+	readTimeout, err := parseDurationDefault(cfg.ReadTimeout, 15*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server read_timeout: %w", err)
+	}
+	readHeaderTimeout, err := parseDurationDefault(cfg.ReadHeaderTimeout, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server read_header_timeout: %w", err)
+	}
+	writeTimeout, err := parseDurationDefault(cfg.WriteTimeout, 30*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server write_timeout: %w", err)
+	}
+	idleTimeout, err := parseDurationDefault(cfg.IdleTimeout, 120*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server idle_timeout: %w", err)
+	}
+
+	server := &http.Server{
+		Addr: addr,
+		// Request IDs, access logs and metrics cover every request, including unmatched ones
+		Handler:           logging.Middleware(a.metrics.Instrument(a.router)),
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	// Event streams never finish on their own, so they are ended as soon as
+	// draining starts; clients reconnect to another instance
+	if a.broker != nil {
+		server.RegisterOnShutdown(a.broker.Close)
+	}
+	return server, nil
+}
+
+// serve runs the servers until ctx is done or one of them fails, then stops
+// accepting connections and waits up to the shutdown timeout for in-flight
+// requests. Requests still running after that are cut off.
+func (a *App) serve(ctx context.Context, endpoints []endpoint) error {
+	shutdownTimeout, err := parseDurationDefault(a.config.Server.ShutdownTimeout, 25*time.Second)
+	if err != nil {
+		return fmt.Errorf("invalid server shutdown_timeout: %w", err)
+	}
+
+	serveErrs := make(chan error, len(endpoints))
+	for _, e := range endpoints {
+		go func() {
+			serveErrs <- e.server.Serve(e.listener)
+		}()
+	}
+
+	var serveErr error
+	select {
+	case err := <-serveErrs:
+		serveErr = fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining in-flight requests", "timeout", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	drainErrs := make(chan error, len(endpoints))
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.server.Shutdown(shutdownCtx); err != nil {
+				e.server.Close()
+				drainErrs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(drainErrs)
+
+	if serveErr != nil {
+		return serveErr
+	}
+	if err := <-drainErrs; err != nil {
+		return fmt.Errorf("failed to drain in-flight requests: %w", err)
+	}
+	slog.Info("Server stopped")
+	return nil
+}
+
+// Close cleans up resources once the server has stopped: background work
+// first, then trace export and finally the database pool it depends on
 func (a *App) Close() error {
+	if a.jwks != nil {
+		a.jwks.Stop()
+	}
+	if a.certs != nil {
+		a.certs.Stop()
+	}
+	if a.dispatcher != nil {
+		a.dispatcher.Stop()
+	}
+	if a.listener != nil {
+		a.listener.Stop()
+	}
+	for _, w := range a.workers {
+		w.Wait()
+	}
+	if a.shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}
 	if a.db != nil {
 		return a.db.Close()
 	}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/handlers"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// healthChecks returns the readiness and startup checks. Startup waits for the
+// database and the schema; readiness also needs pack sizes to calculate with.
+func (a *App) healthChecks() ([]handlers.HealthCheck, []handlers.HealthCheck, error) {
+	if a.db == nil {
+		return nil, nil, nil
+	}
+
+	migrator, err := NewMigrator(a.db, a.config.Database, a.assets.Migrations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slug := readinessTenant(a.config.Tenancy)
+
+	databaseCheck := handlers.HealthCheck{Name: "database", Check: a.db.PingContext}
+	migrationsCheck := handlers.HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+		return migrationsCurrent(ctx, migrator)
+	}}
+	packSizesCheck := handlers.HealthCheck{Name: "pack_sizes", Check: func(ctx context.Context) error {
+		return packSizesConfigured(ctx, database.NewTenantRepository(a.db), database.NewPackSizeRepository(a.db), slug)
+	}}
+
+	readiness := []handlers.HealthCheck{databaseCheck, migrationsCheck}
+	if slug != "" {
+		readiness = append(readiness, packSizesCheck)
+	}
+	startup := []handlers.HealthCheck{databaseCheck, migrationsCheck}
+	return readiness, startup, nil
+}
+
+// readinessTenant returns the tenant whose pack sizes readiness checks, or ""
+// when there is none: with tenants required, the default tenant may not even
+// exist, and one unconfigured tenant must not take every instance out of service
+func readinessTenant(cfg config.TenancyConfig) string {
+	if cfg.ReadinessTenant != "" {
+		return cfg.ReadinessTenant
+	}
+	if cfg.RequireTenant {
+		return ""
+	}
+	if cfg.DefaultTenant == "" {
+		return "default"
+	}
+	return cfg.DefaultTenant
+}
+
+// migrationsCurrent fails while a migration is pending, modified or unknown
+func migrationsCurrent(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
+	}
+
+	for _, status := range statuses {
+		switch {
+		case status.Drift:
+			return fmt.Errorf("applied migration %s was modified", status.Version)
+		case status.Missing:
+			return fmt.Errorf("applied migration %s is unknown to this version", status.Version)
+		case !status.Applied:
+			return fmt.Errorf("migration %s is pending", status.Version)
+		}
+	}
+	return nil
+}
+
+// packSizesConfigured fails unless the tenant has at least one active pack size
+func packSizesConfigured(ctx context.Context, tenants database.TenantRepositoryInterface, packSizes database.PackSizeRepositoryInterface, slug string) error {
+	t, err := tenants.GetBySlug(ctx, slug)
+	if err != nil {
+		return err
+	}
+
+	active := true
+	ctx = tenant.WithTenant(ctx, tenant.Tenant{ID: t.ID, Slug: t.Slug})
+	page, err := packSizes.List(ctx, database.PackSizeQuery{Active: &active, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(page.PackSizes) == 0 {
+		return errors.New("no active pack sizes configured for tenant '" + slug + "'")
+	}
+	return nil
+}
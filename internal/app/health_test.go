@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+)
+
+func TestReadinessTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.TenancyConfig
+		expected string
+	}{
+		{name: "Default tenant", cfg: config.TenancyConfig{DefaultTenant: "acme"}, expected: "acme"},
+		{name: "Built-in default tenant", expected: "default"},
+		{name: "Configured readiness tenant", cfg: config.TenancyConfig{DefaultTenant: "acme", ReadinessTenant: "globex"}, expected: "globex"},
+		{name: "Skipped when tenants are required", cfg: config.TenancyConfig{DefaultTenant: "acme", RequireTenant: true}},
+		{name: "Configured with tenants required", cfg: config.TenancyConfig{RequireTenant: true, ReadinessTenant: "globex"}, expected: "globex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readinessTenant(tt.cfg); got != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/handlers"
+	"github.com/miloradbozic/packing-service/internal/logging"
+)
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 5 * time.Second
+
+// watchConfig reloads the config on SIGHUP and whenever the config file
+// changes, until ctx is done
+func (a *App) watchConfig(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	version := fileVersion(a.configPath)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			a.reloadConfig("SIGHUP")
+		case <-ticker.C:
+			if current := fileVersion(a.configPath); current != version {
+				version = current
+				a.reloadConfig("file change")
+			}
+		}
+	}
+}
+
+func (a *App) reloadConfig(trigger string) {
+	if err := a.Reload(); err != nil {
+		slog.Error("Rejected config reload, keeping the running config", "trigger", trigger, "error", err)
+	}
+}
+
+// Reload reads the config again and applies its reloadable settings: logging,
+// validation limits, rate limiting (except the store) and the idempotency TTL.
+// The new settings are all validated before any is applied, so an invalid
+// config changes nothing. Other changed settings are logged as needing a restart.
+func (a *App) Reload() error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	cfg, err := config.Load(a.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	logger, loggingErr := logging.New(cfg.Logging, os.Stderr)
+	policy, rateLimitErr := handlers.NewRateLimitPolicy(cfg.RateLimit)
+	ttl, idempotencyErr := handlers.IdempotencyTTL(cfg.Idempotency)
+	if err := errors.Join(loggingErr, rateLimitErr, idempotencyErr); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	slog.SetDefault(logger)
+	a.validator.Configure(cfg.Validation)
+	a.limiter.SetPolicy(policy)
+	a.limiter.SetCost("POST /api/v1/calculate", handlers.CalculateCost(cfg.RateLimit.CalculateItemsPerToken))
+	a.idempotency.SetTTL(ttl)
+
+	// Everything else keeps running with the settings it started with
+	effective := *a.config
+	effective.Logging = cfg.Logging
+	effective.Validation = cfg.Validation
+	effective.RateLimit = cfg.RateLimit
+	effective.RateLimit.Store = a.config.RateLimit.Store
+	effective.Idempotency = cfg.Idempotency
+
+	pending := restartRequired(a.config, cfg)
+	for _, setting := range pending {
+		slog.Warn("Config change takes effect after a restart", "setting", setting)
+	}
+
+	a.snapshot.Store(&handlers.ConfigSnapshot{Config: effective, LoadedAt: time.Now(), PendingRestart: pending})
+	slog.Info("Reloaded config", "path", a.configPath)
+	return nil
+}
+
+// restartRequired lists the settings that differ between the running and the
+// new config but cannot be reloaded
+func restartRequired(running, next *config.Config) []string {
+	sections := []struct {
+		name          string
+		running, next interface{}
+	}{
+		{"server", running.Server, next.Server},
+		{"database", running.Database, next.Database},
+		{"assets", running.Assets, next.Assets},
+		{"tenancy", running.Tenancy, next.Tenancy},
+		{"auth", running.Auth, next.Auth},
+		{"tracing", running.Tracing, next.Tracing},
+		{"rate_limit.store", running.RateLimit.Store, next.RateLimit.Store},
+	}
+
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.running, section.next) {
+			changed = append(changed, section.name)
+		}
+	}
+	return changed
+}
+
+// configSnapshot returns the effective config
+func (a *App) configSnapshot() handlers.ConfigSnapshot {
+	if snapshot := a.snapshot.Load(); snapshot != nil {
+		return *snapshot
+	}
+	return handlers.ConfigSnapshot{Config: *a.config}
+}
+
+// fileVersion identifies the contents of a file by size and modification time;
+// it is empty when the file cannot be read
+func fileVersion(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+	}
+
+	running := config.Default()
+	a := &App{
+		config:     &running,
+		configPath: path,
+		assets:     Assets{Templates: os.DirFS("../../templates")},
+	}
+	if err := a.setupRoutes(); err != nil {
+		t.Fatalf("failed to set up routes: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		content          string
+		expectError      bool
+		expectedMaxItems int
+		expectedPending  []string
+	}{
+		{
+			name:             "Reloadable settings",
+			content:          "server:\n  port: 8080\nvalidation:\n  max_order_items: 500\nrate_limit:\n  enabled: true\n",
+			expectedMaxItems: 500,
+		},
+		{
+			name:             "Invalid log level changes nothing",
+			content:          "server:\n  port: 8080\nvalidation:\n  max_order_items: 20\nlogging:\n  level: loud\n",
+			expectError:      true,
+			expectedMaxItems: 500,
+		},
+		{
+			name:             "Port change needs a restart",
+			content:          "server:\n  port: 9090\nvalidation:\n  max_order_items: 30\n",
+			expectedMaxItems: 30,
+			expectedPending:  []string{"server"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write(tt.content)
+			err := a.Reload()
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+
+			if got := a.validator.Limits().MaxOrderItems; got != tt.expectedMaxItems {
+				t.Errorf("expected max order items %d, got %d", tt.expectedMaxItems, got)
+			}
+			if tt.expectError {
+				return
+			}
+
+			snapshot := a.configSnapshot()
+			if !reflect.DeepEqual(snapshot.PendingRestart, tt.expectedPending) {
+				t.Errorf("expected pending restart %v, got %v", tt.expectedPending, snapshot.PendingRestart)
+			}
+			if snapshot.Config.Server.Port != 8080 {
+				t.Errorf("expected the running port 8080 in the effective config, got %d", snapshot.Config.Server.Port)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/openapi"
+)
+
+// TestSetupRoutes_Documented checks that the OpenAPI document describes
+// exactly the routes the router serves
+func TestSetupRoutes_Documented(t *testing.T) {
+	a := &App{
+		config: &config.Config{},
+		assets: Assets{Templates: os.DirFS("../../templates")},
+	}
+	if err := a.setupRoutes(); err != nil {
+		t.Fatalf("failed to set up routes: %v", err)
+	}
+
+	var routes []string
+	err := a.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			routes = append(routes, method+" "+path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %v", err)
+	}
+	if len(routes) == 0 {
+		t.Fatal("expected routes")
+	}
+	sort.Strings(routes)
+
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("failed to load openapi document: %v", err)
+	}
+	documented := doc.Operations()
+
+	if strings.Join(routes, "\n") != strings.Join(documented, "\n") {
+		t.Errorf("routes and openapi document differ\nroutes:\n  %s\ndocumented:\n  %s",
+			strings.Join(routes, "\n  "), strings.Join(documented, "\n  "))
+	}
+}
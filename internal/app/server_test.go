@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/metrics"
+)
+
+func TestNewServer(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          config.ServerConfig
+		expectedRead time.Duration
+		expectedIdle time.Duration
+		expectError  bool
+	}{
+		{name: "Defaults", expectedRead: 15 * time.Second, expectedIdle: 120 * time.Second},
+		{name: "Configured", cfg: config.ServerConfig{ReadTimeout: "3s", IdleTimeout: "1m"}, expectedRead: 3 * time.Second, expectedIdle: time.Minute},
+		{name: "Invalid timeout", cfg: config.ServerConfig{WriteTimeout: "soon"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &App{config: &config.Config{Server: tt.cfg}, router: mux.NewRouter(), metrics: metrics.New()}
+			server, err := a.newServer("127.0.0.1:0", nil)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if server.ReadTimeout != tt.expectedRead || server.IdleTimeout != tt.expectedIdle {
+				t.Errorf("expected read %s and idle %s, got %s and %s", tt.expectedRead, tt.expectedIdle, server.ReadTimeout, server.IdleTimeout)
+			}
+		})
+	}
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	tests := []struct {
+		name            string
+		shutdownTimeout string
+		expectError     bool
+	}{
+		{name: "Request finishes within the deadline", shutdownTimeout: "5s"},
+		{name: "Request outlives the deadline", shutdownTimeout: "10ms", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			router := mux.NewRouter()
+			router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.Write([]byte("done"))
+			})
+
+			a := &App{config: &config.Config{Server: config.ServerConfig{ShutdownTimeout: tt.shutdownTimeout}}, router: router, metrics: metrics.New()}
+			server, err := a.newServer("127.0.0.1:0", nil)
+			if err != nil {
+				t.Fatalf("failed to create server: %v", err)
+			}
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			served := make(chan error, 1)
+			go func() { served <- a.serve(ctx, []endpoint{{server: server, listener: listener}}) }()
+
+			type result struct {
+				body string
+				err  error
+			}
+			responses := make(chan result, 1)
+			go func() {
+				resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+				if err != nil {
+					responses <- result{err: err}
+					return
+				}
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				responses <- result{body: string(body), err: err}
+			}()
+
+			<-started
+			cancel()
+			if tt.expectError {
+				err := <-served
+				close(release)
+				if err == nil {
+					t.Error("expected an error once the deadline passed, got none")
+				}
+				return
+			}
+
+			// Shutdown waits for the request instead of returning straight away
+			select {
+			case err := <-served:
+				t.Fatalf("serve returned before the request finished: %v", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			close(release)
+
+			if err := <-served; err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res := <-responses; res.err != nil || res.body != "done" {
+				t.Errorf("expected the in-flight request to complete, got %q (%v)", res.body, res.err)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	tests := []struct {
+		name          string
+		tls           config.TLSConfig
+		expectedPlain bool
+		expectedTLS   bool
+	}{
+		{name: "Plain only", expectedPlain: true},
+		{name: "Plain and TLS", tls: config.TLSConfig{Enabled: true}, expectedPlain: true, expectedTLS: true},
+		{name: "TLS only", tls: config.TLSConfig{Enabled: true, DisablePlain: true}, expectedTLS: true},
+		{name: "Disabling plain needs TLS", tls: config.TLSConfig{DisablePlain: true}, expectedPlain: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.tls.Port = freePort(t)
+			a := &App{
+				config:    &config.Config{Server: config.ServerConfig{Host: "127.0.0.1", TLS: tt.tls}},
+				router:    mux.NewRouter(),
+				metrics:   metrics.New(),
+				tlsConfig: &tls.Config{},
+			}
+			endpoints, err := a.listen()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer func() {
+				for _, e := range endpoints {
+					e.listener.Close()
+				}
+			}()
+
+			var plain, secure bool
+			for _, e := range endpoints {
+				if e.server.TLSConfig != nil {
+					secure = true
+				} else {
+					plain = true
+				}
+			}
+			if plain != tt.expectedPlain || secure != tt.expectedTLS {
+				t.Errorf("expected plain %v and TLS %v, got %v and %v", tt.expectedPlain, tt.expectedTLS, plain, secure)
+			}
+		})
+	}
+}
+
+// freePort returns a port that is free to listen on, for listeners whose zero
+// port means a default
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
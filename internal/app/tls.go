@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/certs"
+)
+
+// defaultTLSPort is the port of the TLS listener when none is configured
+const defaultTLSPort = 8443
+
+// setupTLS loads the certificates of the TLS listener and starts watching them
+// for changes
+func (a *App) setupTLS() error {
+	cfg := a.config.Server.TLS
+	if !cfg.Enabled {
+		return nil
+	}
+
+	clientAuth, err := certs.ParseClientAuth(cfg.ClientAuth, cfg.ClientCAFile != "")
+	if err != nil {
+		return err
+	}
+	reloadInterval, err := parseDurationDefault(cfg.ReloadInterval, time.Minute)
+	if err != nil {
+		return fmt.Errorf("invalid tls reload_interval: %w", err)
+	}
+
+	reloader, err := certs.NewReloader(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile, reloadInterval)
+	if err != nil {
+		return err
+	}
+	reloader.Start()
+
+	a.certs = reloader
+	a.tlsConfig = reloader.TLSConfig(clientAuth)
+	return nil
+}
+
+// certificateMapper maps the client certificates of mutual TLS to scopes
+func (a *App) certificateMapper() (*auth.CertificateMapper, error) {
+	cfg := a.config.Auth.ClientCert
+	roleScopes, err := parseRoleScopes(cfg.RoleScopes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client_cert config: %w", err)
+	}
+	mapper, err := auth.NewCertificateMapper(cfg.Roles, cfg.Tenants, roleScopes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client_cert config: %w", err)
+	}
+	return mapper, nil
+}
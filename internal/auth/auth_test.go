@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrincipal_Has(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []Scope
+		scope    Scope
+		expected bool
+	}{
+		{name: "Direct scope", granted: []Scope{ScopeCalculate}, scope: ScopeCalculate, expected: true},
+		{name: "Calculate cannot read", granted: []Scope{ScopeCalculate}, scope: ScopeRead, expected: false},
+		{name: "Manage implies read", granted: []Scope{ScopeManage}, scope: ScopeRead, expected: true},
+		{name: "Manage cannot calculate", granted: []Scope{ScopeManage}, scope: ScopeCalculate, expected: false},
+		{name: "Read cannot manage", granted: []Scope{ScopeRead}, scope: ScopeManage, expected: false},
+		{name: "Admin implies manage", granted: []Scope{ScopeAdmin}, scope: ScopeManage, expected: true},
+		{name: "No scopes", scope: ScopeRead, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Principal{Scopes: tt.granted}
+			if got := p.Has(tt.scope); got != tt.expected {
+				t.Errorf("expected Has(%s) = %v, got %v", tt.scope, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"read", " calculate", "read"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scopes) != 2 || scopes[0] != ScopeCalculate || scopes[1] != ScopeRead {
+		t.Errorf("expected [calculate read], got %v", scopes)
+	}
+
+	if _, err := ParseScopes([]string{"superuser"}); err == nil {
+		t.Errorf("expected error for unknown scope")
+	}
+	if _, err := ParseScopes(nil); err == nil {
+		t.Errorf("expected error for empty scopes")
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(key, KeyPrefix) || !strings.HasPrefix(key, prefix) {
+		t.Errorf("expected key %q to start with %q and %q", key, KeyPrefix, prefix)
+	}
+
+	other, _, _ := GenerateKey()
+	if key == other {
+		t.Errorf("expected distinct keys")
+	}
+	if HashKey(key) == HashKey(other) || HashKey(key) != HashKey(key) {
+		t.Errorf("expected hashes to be deterministic and distinct per key")
+	}
+}
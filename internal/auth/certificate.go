@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+)
+
+// CertificateMapper maps verified TLS client certificates to principals, for
+// internal clients authenticating with mutual TLS
+type CertificateMapper struct {
+	// roles maps client identities to role names
+	roles map[string][]string
+	// tenants maps client identities to the slug of their tenant
+	tenants    map[string]string
+	roleScopes map[string][]Scope
+}
+
+// NewCertificateMapper creates a mapper granting each client identity the
+// scopes of its roles, within its tenant. Roles map to scopes like token roles
+// do. Only identities with the admin scope may be left without a tenant, since
+// they select one with the tenant header of each request.
+func NewCertificateMapper(roles map[string][]string, tenants map[string]string, roleScopes map[string][]Scope) (*CertificateMapper, error) {
+	identities := make([]string, 0, len(roles))
+	for identity := range roles {
+		identities = append(identities, identity)
+	}
+	sort.Strings(identities)
+
+	for _, identity := range identities {
+		if tenants[identity] != "" {
+			continue
+		}
+		principal := Principal{Scopes: scopesForRoles(roles[identity], roleScopes)}
+		if len(principal.Scopes) > 0 && !principal.Has(ScopeAdmin) {
+			return nil, fmt.Errorf("client identity %s needs a tenant unless its roles grant admin", identity)
+		}
+	}
+
+	return &CertificateMapper{roles: roles, tenants: tenants, roleScopes: roleScopes}, nil
+}
+
+// Principal returns the principal of a verified client certificate. Its
+// identity is the first URI SAN, DNS SAN or subject common name that has roles,
+// in that order; certificates without one get a principal without scopes.
+func (m *CertificateMapper) Principal(cert *x509.Certificate) Principal {
+	identities := Identities(cert)
+	for _, identity := range identities {
+		if roles, ok := m.roles[identity]; ok {
+			return Principal{
+				Subject:    "cert:" + identity,
+				TenantSlug: m.tenants[identity],
+				Scopes:     scopesForRoles(roles, m.roleScopes),
+			}
+		}
+	}
+
+	subject := "cert:unknown"
+	if len(identities) > 0 {
+		subject = "cert:" + identities[0]
+	}
+	return Principal{Subject: subject}
+}
+
+// Identities lists the names a certificate identifies its holder by: URI SANs
+// (such as SPIFFE IDs), then DNS SANs, then the subject common name
+func Identities(cert *x509.Certificate) []string {
+	var identities []string
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	identities = append(identities, cert.DNSNames...)
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	return identities
+}
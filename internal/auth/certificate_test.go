@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+)
+
+func TestCertificateMapper_Principal(t *testing.T) {
+	mapper, err := NewCertificateMapper(
+		map[string][]string{
+			"spiffe://internal/inventory-sync": {"sync"},
+			"reporting.internal":               {"read"},
+			"billing":                          {"calculate", "unknown"},
+			"ops":                              {"admin"},
+		},
+		map[string]string{
+			"spiffe://internal/inventory-sync": "acme",
+			"reporting.internal":               "acme",
+			"billing":                          "globex",
+		},
+		map[string][]Scope{"sync": {ScopeManage}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spiffeID, _ := url.Parse("spiffe://internal/inventory-sync")
+
+	tests := []struct {
+		name            string
+		cert            *x509.Certificate
+		expectedSubject string
+		expectedTenant  string
+		expectedScope   Scope
+	}{
+		{
+			name:            "URI SAN with a mapped role",
+			cert:            &x509.Certificate{URIs: []*url.URL{spiffeID}, Subject: pkix.Name{CommonName: "billing"}},
+			expectedSubject: "cert:spiffe://internal/inventory-sync",
+			expectedTenant:  "acme",
+			expectedScope:   ScopeManage,
+		},
+		{
+			name:            "DNS SAN named after a scope",
+			cert:            &x509.Certificate{DNSNames: []string{"reporting.internal"}},
+			expectedSubject: "cert:reporting.internal",
+			expectedTenant:  "acme",
+			expectedScope:   ScopeRead,
+		},
+		{
+			name:            "Common name",
+			cert:            &x509.Certificate{DNSNames: []string{"billing.internal"}, Subject: pkix.Name{CommonName: "billing"}},
+			expectedSubject: "cert:billing",
+			expectedTenant:  "globex",
+			expectedScope:   ScopeCalculate,
+		},
+		{
+			name:            "Admin without a tenant",
+			cert:            &x509.Certificate{Subject: pkix.Name{CommonName: "ops"}},
+			expectedSubject: "cert:ops",
+			expectedScope:   ScopeAdmin,
+		},
+		{
+			name:            "Unmapped identity",
+			cert:            &x509.Certificate{Subject: pkix.Name{CommonName: "stranger"}},
+			expectedSubject: "cert:stranger",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := mapper.Principal(tt.cert)
+			if principal.Subject != tt.expectedSubject {
+				t.Errorf("expected subject '%s', got '%s'", tt.expectedSubject, principal.Subject)
+			}
+			if principal.TenantSlug != tt.expectedTenant {
+				t.Errorf("expected tenant '%s', got '%s'", tt.expectedTenant, principal.TenantSlug)
+			}
+			if tt.expectedScope == "" {
+				if len(principal.Scopes) != 0 {
+					t.Errorf("expected no scopes, got %v", principal.Scopes)
+				}
+				return
+			}
+			if len(principal.Scopes) != 1 || principal.Scopes[0] != tt.expectedScope {
+				t.Errorf("expected scopes [%s], got %v", tt.expectedScope, principal.Scopes)
+			}
+		})
+	}
+}
+
+func TestNewCertificateMapper_RequiresTenant(t *testing.T) {
+	_, err := NewCertificateMapper(
+		map[string][]string{"inventory-sync": {"manage"}},
+		nil,
+		nil,
+	)
+	if err == nil {
+		t.Error("expected an error for a client that can manage without a tenant")
+	}
+}
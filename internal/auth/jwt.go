@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenValidatorConfig describes which bearer tokens are accepted and how their
+// claims map to scopes
+type TokenValidatorConfig struct {
+	Issuer   string
+	Audience string
+	// RolesClaim is a dot separated path to the roles claim, e.g. "realm_access.roles"
+	RolesClaim string
+	// RoleScopes maps role names to scopes; roles named after a scope map to it directly
+	RoleScopes map[string][]Scope
+	// TenantClaim names the claim carrying the tenant slug; empty leaves tenant selection to the request
+	TenantClaim string
+	Leeway      time.Duration
+}
+
+// TokenValidator verifies JWT bearer tokens against a key set
+type TokenValidator struct {
+	keys   *KeySet
+	config TokenValidatorConfig
+	parser *jwt.Parser
+}
+
+func NewTokenValidator(keys *KeySet, cfg TokenValidatorConfig) (*TokenValidator, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, fmt.Errorf("issuer and audience are required")
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+	)
+
+	return &TokenValidator{keys: keys, config: cfg, parser: parser}, nil
+}
+
+// Validate verifies the token signature and standard claims and returns its principal
+func (v *TokenValidator) Validate(token string) (Principal, error) {
+	claims, err := v.parse(token)
+	if err != nil {
+		return Principal{}, err
+	}
+	return v.principal(claims)
+}
+
+// ValidateIDToken validates an OIDC ID token, which must also carry the nonce
+// sent with the authorization request
+func (v *TokenValidator) ValidateIDToken(token, nonce string) (Principal, error) {
+	claims, err := v.parse(token)
+	if err != nil {
+		return Principal{}, err
+	}
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return Principal{}, fmt.Errorf("invalid token: nonce mismatch")
+	}
+	return v.principal(claims)
+}
+
+func (v *TokenValidator) parse(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	return claims, nil
+}
+
+// principal maps validated claims to a principal
+func (v *TokenValidator) principal(claims jwt.MapClaims) (Principal, error) {
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return Principal{}, fmt.Errorf("invalid token: missing subject")
+	}
+
+	principal := Principal{Subject: "jwt:" + subject}
+	principal.Scopes = v.scopesFor(stringList(claimAt(claims, v.config.RolesClaim)))
+
+	if v.config.TenantClaim != "" {
+		slug, _ := claimAt(claims, v.config.TenantClaim).(string)
+		if slug == "" {
+			return Principal{}, fmt.Errorf("invalid token: missing %s claim", v.config.TenantClaim)
+		}
+		principal.TenantSlug = slug
+	}
+
+	return principal, nil
+}
+
+// scopesFor maps roles to the scopes they grant
+func (v *TokenValidator) scopesFor(roles []string) []Scope {
+	return scopesForRoles(roles, v.config.RoleScopes)
+}
+
+// scopesForRoles maps roles to scopes with roleScopes. Roles without an entry
+// that are named after a scope grant that scope; other roles grant nothing.
+func scopesForRoles(roles []string, roleScopes map[string][]Scope) []Scope {
+	seen := make(map[Scope]bool)
+	var scopes []Scope
+	add := func(scope Scope) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	for _, role := range roles {
+		if mapped, ok := roleScopes[role]; ok {
+			for _, scope := range mapped {
+				add(scope)
+			}
+			continue
+		}
+		if parsed, err := ParseScopes([]string{role}); err == nil {
+			add(parsed[0])
+		}
+	}
+	return scopes
+}
+
+// claimAt follows a dot separated path through nested claim objects
+func claimAt(claims jwt.MapClaims, path string) interface{} {
+	var current interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// stringList accepts a JSON array of strings or a space separated string
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestKeySet writes a JWKS file holding a fresh RSA key and loads it
+func newTestKeySet(t *testing.T) (*KeySet, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	keys, err := NewKeySet("", path, 0)
+	if err != nil {
+		t.Fatalf("failed to load JWKS: %v", err)
+	}
+	return keys, key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestTokenValidator_Validate(t *testing.T) {
+	keys, key := newTestKeySet(t)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	validator, err := NewTokenValidator(keys, TokenValidatorConfig{
+		Issuer:      "https://idp.example.com",
+		Audience:    "packing-service",
+		RolesClaim:  "realm_access.roles",
+		RoleScopes:  map[string][]Scope{"pack-admin": {ScopeManage}},
+		TenantClaim: "tenant",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":          "https://idp.example.com",
+			"aud":          "packing-service",
+			"sub":          "alice",
+			"exp":          time.Now().Add(time.Hour).Unix(),
+			"tenant":       "acme",
+			"realm_access": map[string]interface{}{"roles": []string{"pack-admin", "calculate", "unrelated"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(jwt.MapClaims)
+		signer  *rsa.PrivateKey
+		kid     string
+		wantErr bool
+	}{
+		{name: "Valid token", modify: func(jwt.MapClaims) {}},
+		{name: "Wrong issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "Wrong audience", modify: func(c jwt.MapClaims) { c["aud"] = "other" }, wantErr: true},
+		{name: "Expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: true},
+		{name: "Missing expiry", modify: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: true},
+		{name: "Missing tenant", modify: func(c jwt.MapClaims) { delete(c, "tenant") }, wantErr: true},
+		{name: "Signed by another key", modify: func(jwt.MapClaims) {}, signer: otherKey, wantErr: true},
+		{name: "Unknown key ID", modify: func(jwt.MapClaims) {}, kid: "rotated", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			signer, kid := key, "test"
+			if tt.signer != nil {
+				signer = tt.signer
+			}
+			if tt.kid != "" {
+				kid = tt.kid
+			}
+
+			principal, err := validator.Validate(signToken(t, signer, kid, claims))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if principal.Subject != "jwt:alice" || principal.TenantSlug != "acme" {
+				t.Errorf("unexpected principal %+v", principal)
+			}
+			if !principal.Has(ScopeManage) || !principal.Has(ScopeCalculate) || principal.Has(ScopeAdmin) {
+				t.Errorf("expected manage and calculate scopes, got %v", principal.Scopes)
+			}
+		})
+	}
+}
+
+func TestStringList(t *testing.T) {
+	if got := stringList("read calculate"); len(got) != 2 || got[1] != "calculate" {
+		t.Errorf("expected space separated roles to split, got %v", got)
+	}
+	if got := stringList(42); got != nil {
+		t.Errorf("expected nil for non-string claim, got %v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OIDCClientConfig describes a confidential client of an OIDC provider
+type OIDCClientConfig struct {
+	ClientID         string
+	ClientSecret     string
+	AuthorizationURL string
+	TokenURL         string
+	RedirectURL      string
+	Scopes           []string
+}
+
+// OIDCClient runs the authorization code flow and validates the returned ID tokens
+type OIDCClient struct {
+	config    OIDCClientConfig
+	validator *TokenValidator
+	client    *http.Client
+}
+
+// NewOIDCClient creates a client. The validator must accept the client ID as audience.
+func NewOIDCClient(cfg OIDCClientConfig, validator *TokenValidator) (*OIDCClient, error) {
+	if cfg.ClientID == "" || cfg.AuthorizationURL == "" || cfg.TokenURL == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("client_id, authorization_url, token_url and redirect_url are required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	return &OIDCClient{
+		config:    cfg,
+		validator: validator,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// AuthCodeURL returns the provider URL the user is redirected to for login
+func (c *OIDCClient) AuthCodeURL(state, nonce string) string {
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {c.config.ClientID},
+		"redirect_uri":  {c.config.RedirectURL},
+		"scope":         {strings.Join(c.config.Scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+
+	separator := "?"
+	if strings.Contains(c.config.AuthorizationURL, "?") {
+		separator = "&"
+	}
+	return c.config.AuthorizationURL + separator + params.Encode()
+}
+
+// Exchange redeems an authorization code and returns the principal of the validated ID token
+func (c *OIDCClient) Exchange(ctx context.Context, code, nonce string) (Principal, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.config.RedirectURL},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Principal{}, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Principal{}, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Principal{}, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Principal{}, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return Principal{}, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return Principal{}, fmt.Errorf("token response has no id_token")
+	}
+
+	return c.validator.ValidateIDToken(token.IDToken, nonce)
+}
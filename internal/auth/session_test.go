@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSigner_DecodeSession(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+
+	session, err := NewSession(time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	session.Subject = "user:1"
+	value, err := signer.Encode(session)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, err := signer.DecodeSession(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.Subject != "user:1" || decoded.CSRFToken != session.CSRFToken {
+		t.Errorf("expected %+v, got %+v", session, decoded)
+	}
+
+	if _, err := NewSigner([]byte("other")).DecodeSession(value); err != ErrInvalidSession {
+		t.Errorf("expected ErrInvalidSession for another secret, got %v", err)
+	}
+	if _, err := signer.DecodeSession(value + "x"); err != ErrInvalidSession {
+		t.Errorf("expected ErrInvalidSession for a tampered value, got %v", err)
+	}
+
+	session.ExpiresAt = time.Now().Add(-time.Minute)
+	expired, _ := signer.Encode(session)
+	if _, err := signer.DecodeSession(expired); err != ErrInvalidSession {
+		t.Errorf("expected ErrInvalidSession for an expired session, got %v", err)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !CheckPassword("s3cret-pass", hash) {
+		t.Errorf("expected the password to match")
+	}
+	if CheckPassword("wrong", hash) {
+		t.Errorf("expected a wrong password to fail")
+	}
+	if CheckPassword("s3cret-pass", "plaintext") {
+		t.Errorf("expected a malformed hash to fail")
+	}
+}
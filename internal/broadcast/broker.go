@@ -0,0 +1,106 @@
+// Package broadcast fans out live changes to the subscribers of a tenant, such
+// as the Server-Sent Events streams of the web UI.
+package broadcast
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// EventResync tells subscribers that changes may have been missed, so they
+// should reload their state
+const EventResync = "resync"
+
+// subscriptionBuffer is the number of messages a subscriber may fall behind by
+const subscriptionBuffer = 32
+
+// Message is a change pushed to subscribers
+type Message struct {
+	// TenantID selects the subscribers; 0 sends the message to all of them
+	TenantID int
+	// Event names the change, e.g. pack_size.created
+	Event string
+	// Data is the JSON payload of the change
+	Data json.RawMessage
+}
+
+// Broker delivers published messages to the subscribers of their tenant
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the messages of one tenant on C. C is closed when the
+// subscription ends: on Close, when the broker closes, or when the subscriber
+// falls too far behind, in which case it should reload its state.
+type Subscription struct {
+	C <-chan Message
+
+	c        chan Message
+	tenantID int
+	broker   *Broker
+}
+
+// Subscribe starts receiving the messages of a tenant
+func (b *Broker) Subscribe(tenantID int) *Subscription {
+	c := make(chan Message, subscriptionBuffer)
+	s := &Subscription{C: c, c: c, tenantID: tenantID, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c)
+		return s
+	}
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Publish delivers a message without blocking. Subscribers whose buffer is
+// full are dropped rather than slowing down the others.
+func (b *Broker) Publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		if m.TenantID != 0 && m.TenantID != s.tenantID {
+			continue
+		}
+		select {
+		case s.c <- m:
+		default:
+			b.remove(s)
+		}
+	}
+}
+
+// Close ends all subscriptions, and any made later
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		b.remove(s)
+	}
+}
+
+// remove ends a subscription; b.mu must be held
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.c)
+	}
+}
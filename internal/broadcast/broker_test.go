@@ -0,0 +1,65 @@
+package broadcast
+
+import (
+	"testing"
+)
+
+func TestBroker_Publish(t *testing.T) {
+	broker := NewBroker()
+	acme := broker.Subscribe(1)
+	globex := broker.Subscribe(2)
+	defer acme.Close()
+	defer globex.Close()
+
+	broker.Publish(Message{TenantID: 1, Event: "pack_size.created", Data: []byte(`{"id":1}`)})
+	broker.Publish(Message{Event: EventResync})
+
+	if m := <-acme.C; m.Event != "pack_size.created" || string(m.Data) != `{"id":1}` {
+		t.Errorf("unexpected message %+v", m)
+	}
+	if m := <-acme.C; m.Event != EventResync {
+		t.Errorf("expected resync, got %+v", m)
+	}
+	if m := <-globex.C; m.Event != EventResync {
+		t.Errorf("expected only the resync for another tenant, got %+v", m)
+	}
+	select {
+	case m := <-globex.C:
+		t.Errorf("unexpected message %+v", m)
+	default:
+	}
+}
+
+func TestBroker_DropsSlowSubscribers(t *testing.T) {
+	broker := NewBroker()
+	slow := broker.Subscribe(1)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		broker.Publish(Message{TenantID: 1, Event: "pack_size.updated"})
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("expected %d buffered messages before the subscription closed, got %d", subscriptionBuffer, received)
+	}
+	slow.Close()
+}
+
+func TestBroker_Close(t *testing.T) {
+	broker := NewBroker()
+	before := broker.Subscribe(1)
+	broker.Close()
+	after := broker.Subscribe(1)
+
+	if _, ok := <-before.C; ok {
+		t.Error("expected the subscription to be closed with the broker")
+	}
+	if _, ok := <-after.C; ok {
+		t.Error("expected subscriptions made after Close to be closed")
+	}
+	before.Close()
+	broker.Close()
+}
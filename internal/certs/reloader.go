@@ -0,0 +1,204 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate and key, and optionally the CAs that sign
+// client certificates, from files on disk. The files are checked periodically
+// and reloaded when they change, so renewed certificates are picked up without a
+// restart.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	interval     time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	// versions holds the size and modification time of each file when last loaded
+	versions map[string]string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReloader loads the files once and returns the reloader. clientCAFile may
+// be empty when client certificates are not verified.
+func NewReloader(certFile, keyFile, clientCAFile string, interval time.Duration) (*Reloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("cert_file and key_file are required")
+	}
+
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		interval:     interval,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start checks the files every interval until Stop is called
+func (r *Reloader) Start() {
+	if r.interval <= 0 || r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if _, err := r.Reload(); err != nil {
+					slog.Error("Failed to reload TLS certificates, keeping previous ones", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the background checks
+func (r *Reloader) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+}
+
+// Reload loads the files again if any of them changed since they were last
+// loaded. It reports whether they were reloaded; on error the previous
+// certificates stay in use.
+func (r *Reloader) Reload() (bool, error) {
+	versions, err := r.fileVersions()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	changed := false
+	for file, version := range versions {
+		if r.versions[file] != version {
+			changed = true
+		}
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	if err := r.load(); err != nil {
+		return false, err
+	}
+	slog.Info("Reloaded TLS certificates", "cert_file", r.certFile)
+	return true, nil
+}
+
+// load reads and parses the files, replacing the current certificates
+func (r *Reloader) load() error {
+	versions, err := r.fileVersions()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate and key: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("failed to parse client CA file: no PEM certificates found")
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.versions = versions
+	r.mu.Unlock()
+	return nil
+}
+
+// fileVersions identifies the current contents of each file by size and
+// modification time
+func (r *Reloader) fileVersions() (map[string]string, error) {
+	versions := make(map[string]string, 3)
+	for _, file := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		versions[file] = fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+	}
+	return versions, nil
+}
+
+// TLSConfig returns a server configuration that uses the current certificates
+// for every new connection. Client certificates are verified against the client
+// CAs when clientAuth asks for verification.
+func (r *Reloader) TLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   clientAuth,
+				ClientCAs:    r.clientCAs,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+// ParseClientAuth maps the client_auth setting to a TLS client auth type.
+// Without a client CA no certificates are requested; with one they are
+// required unless mode is optional.
+func ParseClientAuth(mode string, hasClientCA bool) (tls.ClientAuthType, error) {
+	if !hasClientCA {
+		if mode != "" {
+			return tls.NoClientCert, fmt.Errorf("client_auth '%s' requires a client_ca_file", mode)
+		}
+		return tls.NoClientCert, nil
+	}
+
+	switch mode {
+	case "", "require":
+		return tls.RequireAndVerifyClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unknown client_auth '%s': must be require or optional", mode)
+	}
+}
@@ -0,0 +1,271 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM encoded certificate and key for commonName
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFiles writes the files and moves their modification time forward, so
+// a rewrite within the same clock tick still counts as a change
+func writeFiles(t *testing.T, files map[string][]byte, modTime time.Time) {
+	t.Helper()
+	for path, data := range files {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to touch %s: %v", path, err)
+		}
+	}
+}
+
+func servedCommonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	config, err := r.TLSConfig(tls.NoClientCert).GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse served certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader_Reload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	cert, key := ca.issue(t, "old.example.com", x509.ExtKeyUsageServerAuth)
+	writeFiles(t, map[string][]byte{certFile: cert, keyFile: key}, time.Now().Add(-time.Minute))
+
+	r, err := NewReloader(certFile, keyFile, "", 0)
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+
+	if reloaded, err := r.Reload(); err != nil || reloaded {
+		t.Errorf("expected unchanged files to be skipped, got reloaded %v and error %v", reloaded, err)
+	}
+
+	cert, key = ca.issue(t, "new.example.com", x509.ExtKeyUsageServerAuth)
+	writeFiles(t, map[string][]byte{certFile: cert, keyFile: key}, time.Now())
+	if reloaded, err := r.Reload(); err != nil || !reloaded {
+		t.Fatalf("expected changed files to be reloaded, got reloaded %v and error %v", reloaded, err)
+	}
+	if name := servedCommonName(t, r); name != "new.example.com" {
+		t.Errorf("expected the renewed certificate, got '%s'", name)
+	}
+
+	// A half written renewal keeps the previous certificate in use
+	writeFiles(t, map[string][]byte{keyFile: []byte("not a key")}, time.Now().Add(time.Minute))
+	if _, err := r.Reload(); err == nil {
+		t.Error("expected an error for an invalid key, got none")
+	}
+	if name := servedCommonName(t, r); name != "new.example.com" {
+		t.Errorf("expected the previous certificate to stay in use, got '%s'", name)
+	}
+}
+
+func TestNewReloader_Invalid(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	cert, key := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	badCA := filepath.Join(dir, "bad-ca.crt")
+	writeFiles(t, map[string][]byte{certFile: cert, keyFile: key, badCA: []byte("garbage")}, time.Now())
+
+	tests := []struct {
+		name         string
+		certFile     string
+		keyFile      string
+		clientCAFile string
+	}{
+		{name: "Missing key file setting", certFile: certFile},
+		{name: "Missing certificate", certFile: filepath.Join(dir, "missing.crt"), keyFile: keyFile},
+		{name: "Invalid client CA", certFile: certFile, keyFile: keyFile, clientCAFile: badCA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReloader(tt.certFile, tt.keyFile, tt.clientCAFile, 0); err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	cert, key := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	writeFiles(t, map[string][]byte{certFile: cert, keyFile: key, caFile: ca.pem}, time.Now())
+
+	r, err := NewReloader(certFile, keyFile, caFile, 0)
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}))
+	server.TLS = r.TLSConfig(tls.RequireAndVerifyClientCert)
+	server.StartTLS()
+	defer server.Close()
+
+	clientCert, clientKey := ca.issue(t, "inventory-sync", x509.ExtKeyUsageClientAuth)
+	clientPair, err := tls.X509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("failed to load client certificate: %v", err)
+	}
+	otherCert, otherKey := newTestCA(t).issue(t, "intruder", x509.ExtKeyUsageClientAuth)
+	otherPair, err := tls.X509KeyPair(otherCert, otherKey)
+	if err != nil {
+		t.Fatalf("failed to load client certificate: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name         string
+		certificates []tls.Certificate
+		expectError  bool
+	}{
+		{name: "Client signed by the CA", certificates: []tls.Certificate{clientPair}},
+		{name: "No client certificate", expectError: true},
+		{name: "Client signed by another CA", certificates: []tls.Certificate{otherPair}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				ServerName:   "localhost",
+				RootCAs:      roots,
+				Certificates: tt.certificates,
+			}}}
+			resp, err := client.Get(server.URL)
+			if tt.expectError {
+				if err == nil {
+					resp.Body.Close()
+					t.Error("expected the handshake to fail, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestParseClientAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		hasClientCA bool
+		expected    tls.ClientAuthType
+		expectError bool
+	}{
+		{name: "No client CA", expected: tls.NoClientCert},
+		{name: "Mode without client CA", mode: "require", expectError: true},
+		{name: "Required by default", hasClientCA: true, expected: tls.RequireAndVerifyClientCert},
+		{name: "Optional", mode: "optional", hasClientCA: true, expected: tls.VerifyClientCertIfGiven},
+		{name: "Unknown mode", mode: "sometimes", hasClientCA: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientAuth, err := ParseClientAuth(tt.mode, tt.hasClientCA)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if clientAuth != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, clientAuth)
+			}
+		})
+	}
+}
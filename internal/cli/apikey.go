@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// runAPIKey creates an API key, which is how the first admin key is bootstrapped
+func runAPIKey(args []string) error {
+	flags := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	tenantSlug := flags.String("tenant", "default", "tenant the key belongs to")
+	name := flags.String("name", "", "descriptive name of the key")
+	scopeList := flags.String("scopes", "admin", "comma separated scopes: calculate, read, manage, admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("-name is required")
+	}
+
+	scopes, err := auth.ParseScopes(strings.Split(*scopeList, ","))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.NewConnection(&cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	t, err := database.NewTenantRepository(db).GetBySlug(context.Background(), *tenantSlug)
+	if err != nil {
+		return err
+	}
+	ctx := tenant.WithTenant(context.Background(), tenant.Tenant{ID: t.ID, Slug: t.Slug})
+
+	key, prefix, err := auth.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+
+	apiKey, err := database.NewAPIKeyRepository(db).Create(ctx, *name, prefix, auth.HashKey(key), names)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created API key %d (%s) for tenant %s with scopes %s\n", apiKey.ID, apiKey.Name, t.Slug, strings.Join(names, ","))
+	fmt.Println("Store it now, it will not be shown again:")
+	fmt.Println(key)
+	return nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/miloradbozic/packing-service/internal/app"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/logging"
+	"github.com/miloradbozic/packing-service/internal/service"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{name: "serve", summary: "Start the HTTP server (default)", run: runServe},
+		{name: "export", summary: "Export pack sizes as json, yaml or csv", run: runExport},
+		{name: "import", summary: "Import pack sizes from a json, yaml or csv file", run: runImport},
+		{name: "apikey", summary: "Create an API key for a tenant", run: runAPIKey},
+		{name: "user", summary: "Create or update a web UI login", run: runUser},
+		{name: "migrate", summary: "Manage database migrations (up, down, status, redo)", run: runMigrate},
+		{name: "config", summary: "Validate the config and print it with secrets redacted (check)", run: runConfig},
+	}
+}
+
+// assets are the embedded files handed over by main
+var assets app.Assets
+
+// Run dispatches to the subcommand named by the first argument.
+// Without arguments the HTTP server is started.
+func Run(args []string, embedded app.Assets) error {
+	assets = embedded
+
+	if len(args) == 0 {
+		return runServe(nil)
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(os.Stdout)
+		return nil
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd.run(args[1:])
+		}
+	}
+
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command '%s'", name)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: packing-service <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+func runServe(args []string) error {
+	application, err := app.New(assets)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+
+	// Close after the server has drained, so requests never see a closed pool
+	runErr := application.Run()
+	if err := application.Close(); err != nil && runErr == nil {
+		return fmt.Errorf("failed to close application: %w", err)
+	}
+	return runErr
+}
+
+// loadConfig reads the configuration file named by CONFIG_PATH
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := logging.Setup(cfg.Logging); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// openService connects to the configured database, builds a packing service and
+// returns a context scoped to the tenant with the given slug
+func openService(cfg *config.Config, tenantSlug string) (*service.PackingService, *database.DB, context.Context, error) {
+	db, err := database.NewConnection(&cfg.Database)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	t, err := database.NewTenantRepository(db).GetBySlug(context.Background(), tenantSlug)
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+	ctx := tenant.WithTenant(context.Background(), tenant.Tenant{ID: t.ID, Slug: t.Slug})
+
+	packSizeRepo := database.NewPackSizeRepository(db)
+	return service.NewPackingService(packSizeRepo), db, ctx, nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func runConfig(args []string) error {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: packing-service config check")
+		fmt.Fprintln(flags.Output(), "Validates the config file named by CONFIG_PATH and the environment,")
+		fmt.Fprintln(flags.Output(), "then prints the effective config with secrets redacted.")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || flags.Arg(0) != "check" {
+		flags.Usage()
+		return fmt.Errorf("expected the config action 'check'")
+	}
+
+	return checkConfig(os.Stdout, config.DefaultPath())
+}
+
+// checkConfig loads the config and writes it to w with secrets redacted, or
+// writes every problem found and returns an error
+func checkConfig(w io.Writer, path string) error {
+	cfg, err := config.Load(path)
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		fmt.Fprintf(w, "%s is invalid:\n", path)
+		for _, problem := range invalid.Problems {
+			fmt.Fprintf(w, "  - %s\n", problem)
+		}
+		return fmt.Errorf("found %d config problems", len(invalid.Problems))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Fprintf(w, "# Effective config from %s and the environment, secrets redacted\n", path)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg.Redacted()); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/app"
+	"github.com/miloradbozic/packing-service/internal/database"
+)
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	path := flags.String("path", "", "directory containing the migration files (default embedded)")
+	steps := flags.Int("steps", 1, "number of migrations to roll back (down only)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: packing-service migrate [flags] up|down|status|redo")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one migrate action")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if *path != "" {
+		cfg.Assets.MigrationsDir = *path
+	}
+
+	db, err := database.NewConnection(&cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := app.NewMigrator(db, cfg.Database, assets.WithOverrides(cfg.Assets).Migrations)
+	if err != nil {
+		return err
+	}
+
+	switch action := flags.Arg(0); action {
+	case "up":
+		applied, err := migrator.Up()
+		printVersions("Applied", applied)
+		return err
+	case "down":
+		rolledBack, err := migrator.Down(*steps)
+		printVersions("Rolled back", rolledBack)
+		return err
+	case "redo":
+		version, err := migrator.Redo()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Redone: %s\n", version)
+		return nil
+	case "status":
+		statuses, err := migrator.Status(context.Background())
+		if err != nil {
+			return err
+		}
+		printStatus(statuses)
+		return nil
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown migrate action '%s'", action)
+	}
+}
+
+func printVersions(label string, versions []string) {
+	if len(versions) == 0 {
+		fmt.Printf("%s: none\n", label)
+		return
+	}
+	for _, version := range versions {
+		fmt.Printf("%s: %s\n", label, version)
+	}
+}
+
+func printStatus(statuses []database.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state := "pending"
+		appliedAt := "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Drift {
+			state = "modified"
+		}
+		if status.Missing {
+			state = "missing"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", status.Version, state, appliedAt)
+	}
+	w.Flush()
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/handlers"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/service"
+	"github.com/miloradbozic/packing-service/internal/transfer"
+)
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatName := flags.String("format", "json", "output format: json, yaml or csv")
+	output := flags.String("output", "", "output file (default stdout)")
+	tenantSlug := flags.String("tenant", "default", "tenant whose pack sizes are exported")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	packingService, db, ctx, err := openService(cfg, *tenantSlug)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	packSizes, err := packingService.ExportPackSizes(ctx)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return transfer.Encode(w, format, packSizes)
+}
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	input := flags.String("file", "", "input file (default stdin)")
+	formatName := flags.String("format", "", "input format: json, yaml or csv (default from file extension)")
+	modeName := flags.String("mode", "merge", "import mode: merge or replace")
+	dryRun := flags.Bool("dry-run", false, "show the changes without applying them")
+	tenantSlug := flags.String("tenant", "default", "tenant whose pack sizes are imported")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *formatName == "" && *input != "" {
+		*formatName = strings.TrimPrefix(filepath.Ext(*input), ".")
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	mode, err := service.ParseImportMode(*modeName)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	packSizes, err := transfer.Decode(r, format)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Imports are held to the same limits as the API
+	if err := validatePackSizes(packSizes, handlers.NewRequestValidator(cfg.Validation).Limits()); err != nil {
+		return err
+	}
+
+	packingService, db, ctx, err := openService(cfg, *tenantSlug)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	result, err := packingService.ImportPackSizes(ctx, packSizes, mode, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if result.DryRun {
+		fmt.Println("Dry run - no changes applied")
+	}
+	fmt.Printf("Mode:      %s\n", result.Mode)
+	fmt.Printf("Created:   %v\n", result.Created)
+	fmt.Printf("Updated:   %v\n", result.Updated)
+	fmt.Printf("Deleted:   %v\n", result.Deleted)
+	fmt.Printf("Unchanged: %v\n", result.Unchanged)
+
+	return nil
+}
+
+// validatePackSizes reports every imported pack size the limits reject
+func validatePackSizes(packSizes []database.PackSize, limits models.Limits) error {
+	var messages []string
+	for i, ps := range packSizes {
+		for _, field := range models.ValidatePackSize(fmt.Sprintf("pack_sizes[%d]", i), ps.Size, limits) {
+			messages = append(messages, field.Message)
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid pack sizes: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/database"
+)
+
+// runUser creates or updates a local web UI user. The password is read from
+// the first line of standard input so it does not end up in the shell history.
+func runUser(args []string) error {
+	flags := flag.NewFlagSet("user", flag.ContinueOnError)
+	tenantSlug := flags.String("tenant", "default", "tenant the user belongs to")
+	username := flags.String("username", "", "login name")
+	scopeList := flags.String("scopes", "calculate,read", "comma separated scopes: calculate, read, manage, admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+
+	scopes, err := auth.ParseScopes(strings.Split(*scopeList, ","))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Password:")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if len(password) < 8 {
+		return fmt.Errorf("password must be at least 8 characters")
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.NewConnection(&cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	t, err := database.NewTenantRepository(db).GetBySlug(context.Background(), *tenantSlug)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+
+	user, err := database.NewUserRepository(db).Upsert(context.Background(), t.ID, *username, hash, names)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved user %s for tenant %s with scopes %s\n", user.Username, user.TenantSlug, strings.Join(user.Scopes, ","))
+	return nil
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/url"
 	"os"
 	"strconv"
@@ -11,16 +14,57 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Assets      AssetsConfig      `yaml:"assets"`
+	Tenancy     TenancyConfig     `yaml:"tenancy"`
+	Auth        AuthConfig        `yaml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Validation  ValidationConfig  `yaml:"validation"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Logging     LoggingConfig     `yaml:"logging"`
 }
 
 type ServerConfig struct {
 	Port int    `yaml:"port"`
 	Host string `yaml:"host"`
+	// ReadTimeout bounds reading a whole request, ReadHeaderTimeout just its headers
+	ReadTimeout       string `yaml:"read_timeout"`
+	ReadHeaderTimeout string `yaml:"read_header_timeout"`
+	// WriteTimeout bounds handling a request and writing its response
+	WriteTimeout string `yaml:"write_timeout"`
+	// IdleTimeout is how long keep-alive connections wait for the next request
+	IdleTimeout string `yaml:"idle_timeout"`
+	// ShutdownTimeout is how long in-flight requests may take to finish after SIGTERM or SIGINT
+	ShutdownTimeout string `yaml:"shutdown_timeout"`
+
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig serves HTTPS on a listener of its own, next to the plain HTTP one
+type TLSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Port of the TLS listener (default 8443)
+	Port     int    `yaml:"port"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile enables mutual TLS: client certificates must be signed by one of its CAs
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is require (default with a client CA) or optional, which also admits clients without a certificate
+	ClientAuth string `yaml:"client_auth"`
+	// ReloadInterval is how often the files are checked for changes (default 1m)
+	ReloadInterval string `yaml:"reload_interval"`
+	// DisablePlain turns off the plain HTTP listener
+	DisablePlain bool `yaml:"disable_plain"`
 }
 
 type DatabaseConfig struct {
+	// Driver selects the SQL driver: postgres (lib/pq, default) or pgx
+	Driver string `yaml:"driver"`
+	// DSN is used as-is when set, instead of the individual connection settings
+	DSN             string `yaml:"dsn"`
 	Host            string `yaml:"host"`
 	Port            int    `yaml:"port"`
 	User            string `yaml:"user"`
@@ -30,75 +74,336 @@ type DatabaseConfig struct {
 	MaxOpenConns    int    `yaml:"max_open_conns"`
 	MaxIdleConns    int    `yaml:"max_idle_conns"`
 	ConnMaxLifetime string `yaml:"conn_max_lifetime"`
+	// ConnectTimeout is how long startup retries an unreachable database
+	ConnectTimeout string `yaml:"connect_timeout"`
+	// RowLevelSecurity scopes every query with the app.tenant_id setting used by
+	// the Postgres row-level security policies
+	RowLevelSecurity bool `yaml:"row_level_security"`
+	// SkipMigrations leaves migrations to the migrate command instead of running them on startup
+	SkipMigrations       bool   `yaml:"skip_migrations"`
+	MigrationLockTimeout string `yaml:"migration_lock_timeout"`
+}
+
+// AssetsConfig overrides the migrations and templates embedded in the binary.
+// When a directory is set its files are read from disk instead, which is handy
+// for editing templates during development.
+type AssetsConfig struct {
+	MigrationsDir string `yaml:"migrations_dir"`
+	TemplatesDir  string `yaml:"templates_dir"`
+}
+
+// TenancyConfig controls how requests are mapped to tenants
+type TenancyConfig struct {
+	// Header names the request header carrying the tenant slug (default X-Tenant-ID)
+	Header string `yaml:"header"`
+	// DefaultTenant is used for requests without a tenant header (default "default")
+	DefaultTenant string `yaml:"default_tenant"`
+	// RequireTenant rejects requests that do not name a tenant
+	RequireTenant bool `yaml:"require_tenant"`
+	// AdminToken protects the tenant admin API, which is disabled when empty
+	AdminToken string `yaml:"admin_token"`
+	// DefaultPackSizes seed newly provisioned tenants
+	DefaultPackSizes []int `yaml:"default_pack_sizes"`
+	// ReadinessTenant must have an active pack size for the service to be
+	// ready. It defaults to the default tenant, and the check is skipped when
+	// tenants are required and none is set.
+	ReadinessTenant string `yaml:"readiness_tenant"`
+}
+
+// AuthConfig controls API authentication
+type AuthConfig struct {
+	// Enabled requires an API key or bearer token with a matching scope on every API route
+	Enabled bool          `yaml:"enabled"`
+	JWT     JWTConfig     `yaml:"jwt"`
+	Session SessionConfig `yaml:"session"`
+	OIDC    OIDCConfig    `yaml:"oidc"`
+	// ClientCert authenticates internal clients by their TLS client certificate
+	ClientCert ClientCertConfig `yaml:"client_cert"`
+}
+
+// ClientCertConfig maps verified client certificates of mutual TLS to roles
+type ClientCertConfig struct {
+	// Roles maps client identities, a certificate's URI SAN, DNS SAN or common name, to roles
+	Roles map[string][]string `yaml:"roles"`
+	// Tenants maps client identities to tenant slugs; only admin clients may
+	// go without one and pick the tenant with the tenant header
+	Tenants map[string]string `yaml:"tenants"`
+	// RoleScopes maps role names to scopes; roles named after a scope need no entry
+	RoleScopes map[string][]string `yaml:"role_scopes"`
+}
+
+// SessionConfig controls the signed session cookies of the web UI
+type SessionConfig struct {
+	// Secret signs session cookies. When empty a random secret is generated on
+	// startup, which logs everyone out on restart and breaks multiple replicas.
+	Secret string `yaml:"secret"`
+	// TTL is how long a login lasts (default 12h)
+	TTL string `yaml:"ttl"`
+	// SecureCookie marks cookies as HTTPS only
+	SecureCookie bool `yaml:"secure_cookie"`
+}
+
+// OIDCConfig enables web UI login through an OIDC provider. ID tokens are
+// verified with the issuer, key set and role mapping of the jwt section.
+type OIDCConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	ClientID         string   `yaml:"client_id"`
+	ClientSecret     string   `yaml:"client_secret"`
+	AuthorizationURL string   `yaml:"authorization_url"`
+	TokenURL         string   `yaml:"token_url"`
+	RedirectURL      string   `yaml:"redirect_url"`
+	Scopes           []string `yaml:"scopes"`
+}
+
+// JWTConfig accepts JWT bearer tokens issued by an OIDC provider
+type JWTConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// JWKSURL or JWKSFile supplies the signing keys; exactly one must be set
+	JWKSURL  string `yaml:"jwks_url"`
+	JWKSFile string `yaml:"jwks_file"`
+	// RefreshInterval is how often the key set is reloaded (default 15m)
+	RefreshInterval string `yaml:"refresh_interval"`
+	// Leeway tolerates clock skew when checking exp and nbf (default 1m)
+	Leeway string `yaml:"leeway"`
+	// RolesClaim is a dot separated path to the roles claim (default "roles")
+	RolesClaim string `yaml:"roles_claim"`
+	// RoleScopes maps role names to scopes; roles named after a scope need no entry
+	RoleScopes map[string][]string `yaml:"role_scopes"`
+	// TenantClaim names the claim holding the tenant slug; required for bearer tokens
+	TenantClaim string `yaml:"tenant_claim"`
+}
+
+// RateLimitConfig controls the token bucket rate limiter of the API. Clients
+// are identified by their API key, token or session, or by IP address.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Store keeps the buckets: memory (default, per replica) or postgres (shared)
+	Store string `yaml:"store"`
+	// Rate and Burst are the default limit: Burst tokens, refilled at Rate tokens per second
+	Rate  float64 `yaml:"rate"`
+	Burst float64 `yaml:"burst"`
+	// TrustForwardedFor takes the client IP from X-Forwarded-For, for deployments behind a proxy
+	TrustForwardedFor bool `yaml:"trust_forwarded_for"`
+	// TrustedProxies is the number of proxies in front of the service that
+	// append to X-Forwarded-For (default 1); entries left of them are ignored
+	TrustedProxies int `yaml:"trusted_proxies"`
+	// CalculateItemsPerToken charges calculations one extra token per this many items (default 100000)
+	CalculateItemsPerToken int `yaml:"calculate_items_per_token"`
+	// Rules override the default limit; the first matching rule applies
+	Rules []RateLimitRule `yaml:"rules"`
+}
+
+// RateLimitRule sets the limit for a route, a role, or both
+type RateLimitRule struct {
+	// Route is a path template such as /api/v1/pack-sizes/{id}, optionally
+	// preceded by a method: "POST /api/v1/calculate"
+	Route string `yaml:"route"`
+	// Role is a scope the caller must hold, or "anonymous" for unauthenticated callers
+	Role  string  `yaml:"role"`
+	Rate  float64 `yaml:"rate"`
+	Burst float64 `yaml:"burst"`
+}
+
+// IdempotencyConfig controls how long responses to requests with an
+// Idempotency-Key header are kept for replay
+type IdempotencyConfig struct {
+	// TTL defaults to 24h
+	TTL string `yaml:"ttl"`
+}
+
+// WebhooksConfig controls the delivery of webhook events
+type WebhooksConfig struct {
+	// PollInterval is how often new events and due retries are picked up (default 5s)
+	PollInterval string `yaml:"poll_interval"`
+	// Timeout bounds each request to a subscriber (default 10s)
+	Timeout string `yaml:"timeout"`
+	// MaxAttempts is how often a delivery is tried before it is dead (default 8)
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the wait after the first failed attempt, doubled after
+	// each further failure up to MaxBackoff (defaults 30s and 1h)
+	InitialBackoff string `yaml:"initial_backoff"`
+	MaxBackoff     string `yaml:"max_backoff"`
+	// Retention is how long delivered events are kept in the delivery log (default 168h)
+	Retention string `yaml:"retention"`
+	// AllowedNetworks lists CIDRs of internal receivers; other loopback,
+	// private and link-local addresses are refused
+	AllowedNetworks []string `yaml:"allowed_networks"`
+}
+
+// ValidationConfig bounds the requests the API accepts
+type ValidationConfig struct {
+	// MaxBodyBytes caps request bodies (default 1 MiB)
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// MaxOrderItems is the largest order a calculation accepts (default 10000000)
+	MaxOrderItems int `yaml:"max_order_items"`
+	// MaxPackSize is the largest pack size that can be created (default 1000000)
+	MaxPackSize int `yaml:"max_pack_size"`
+}
+
+// TracingConfig controls OpenTelemetry tracing. W3C trace context headers of
+// incoming requests are honored either way.
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Exporter sends spans to otlp (default, OTLP over HTTP) or stdout
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP collector URL, e.g. http://localhost:4318. When empty
+	// the OTEL_EXPORTER_OTLP_ENDPOINT environment variable or localhost:4318 is used.
+	Endpoint string `yaml:"endpoint"`
+	// ServiceName names the service in traces (default packing-service)
+	ServiceName string `yaml:"service_name"`
+	// SampleRatio is the fraction of new traces recorded (default 1); requests
+	// that carry a sampled parent are always recorded
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// LoggingConfig controls the structured log output
+type LoggingConfig struct {
+	// Level is debug, info (default), warn or error
+	Level string `yaml:"level"`
+	// Format is text (default) or json
+	Format string `yaml:"format"`
 }
 
+// DefaultFile is read when CONFIG_PATH is not set. Unlike a file named by
+// CONFIG_PATH it may be missing, for deployments configured only through
+// environment variables.
+const DefaultFile = "config.yaml"
 
+// DefaultPath returns the config file path from CONFIG_PATH, falling back to config.yaml
+func DefaultPath() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return DefaultFile
+}
+
+// Load applies the config file and then environment variables to the
+// defaults, and validates the result. Every problem found is reported in a
+// single *ValidationError.
 func Load(path string) (*Config, error) {
-	var config Config
-	
-	// Try to load from file first
+	config := Default()
+
 	file, err := os.Open(path)
-	if err == nil {
+	switch {
+	case err == nil:
 		defer file.Close()
 		decoder := yaml.NewDecoder(file)
-		if err := decoder.Decode(&config); err != nil {
-			return nil, fmt.Errorf("failed to decode config: %w", err)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to decode config %s: %w", path, err)
 		}
+	case errors.Is(err, fs.ErrNotExist) && path == DefaultFile:
+	default:
+		return nil, fmt.Errorf("failed to open config: %w", err)
 	}
 
-	// Override with environment variables if they exist
-	overrideWithEnvVars(&config)
-
-
+	problems := overrideWithEnvVars(&config)
+	problems = append(problems, config.problems()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
 	return &config, nil
 }
 
-func overrideWithEnvVars(config *Config) {
+// overrideWithEnvVars applies the environment variables that are set and
+// returns the ones with unusable values
+func overrideWithEnvVars(config *Config) []string {
+	env := &envReader{}
+
 	// Server configuration
-	if port := os.Getenv("PORT"); port != "" {
-		if p, err := strconv.Atoi(port); err == nil {
-			config.Server.Port = p
-		}
-	}
-	if host := os.Getenv("HOST"); host != "" {
-		config.Server.Host = host
-	}
+	env.int("PORT", &config.Server.Port)
+	env.string("HOST", &config.Server.Host)
+	env.string("SERVER_READ_TIMEOUT", &config.Server.ReadTimeout)
+	env.string("SERVER_READ_HEADER_TIMEOUT", &config.Server.ReadHeaderTimeout)
+	env.string("SERVER_WRITE_TIMEOUT", &config.Server.WriteTimeout)
+	env.string("SERVER_IDLE_TIMEOUT", &config.Server.IdleTimeout)
+	env.string("SERVER_SHUTDOWN_TIMEOUT", &config.Server.ShutdownTimeout)
+
+	// TLS
+	env.bool("TLS_ENABLED", &config.Server.TLS.Enabled)
+	env.int("TLS_PORT", &config.Server.TLS.Port)
+	env.string("TLS_CERT_FILE", &config.Server.TLS.CertFile)
+	env.string("TLS_KEY_FILE", &config.Server.TLS.KeyFile)
+	env.string("TLS_CLIENT_CA_FILE", &config.Server.TLS.ClientCAFile)
+	env.string("TLS_CLIENT_AUTH", &config.Server.TLS.ClientAuth)
+	env.bool("TLS_DISABLE_PLAIN", &config.Server.TLS.DisablePlain)
 
 	// Database configuration from DATABASE_URL (Heroku format)
-	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
+	var dbURL string
+	env.secret("DATABASE_URL", &dbURL)
+	if dbURL != "" {
 		if err := parseDatabaseURL(dbURL, config); err != nil {
-			// Log error but continue with other env vars
-			fmt.Printf("Warning: Failed to parse DATABASE_URL: %v\n", err)
+			env.problem("DATABASE_URL: %v", err)
 		}
 	}
-	
+
 	// Individual database environment variables
-	if host := os.Getenv("DB_HOST"); host != "" {
-		config.Database.Host = host
-	}
-	if port := os.Getenv("DB_PORT"); port != "" {
-		if p, err := strconv.Atoi(port); err == nil {
-			config.Database.Port = p
-		}
-	}
-	if user := os.Getenv("DB_USER"); user != "" {
-		config.Database.User = user
-	}
-	if password := os.Getenv("DB_PASSWORD"); password != "" {
-		config.Database.Password = password
-	}
-	if dbname := os.Getenv("DB_NAME"); dbname != "" {
-		config.Database.DBName = dbname
-	}
-	if sslmode := os.Getenv("DB_SSLMODE"); sslmode != "" {
-		config.Database.SSLMode = sslmode
-	}
+	env.string("DB_DRIVER", &config.Database.Driver)
+	env.secret("DB_DSN", &config.Database.DSN)
+	env.string("DB_CONNECT_TIMEOUT", &config.Database.ConnectTimeout)
+	env.string("DB_HOST", &config.Database.Host)
+	env.int("DB_PORT", &config.Database.Port)
+	env.string("DB_USER", &config.Database.User)
+	env.secret("DB_PASSWORD", &config.Database.Password)
+	env.string("DB_NAME", &config.Database.DBName)
+	env.string("DB_SSLMODE", &config.Database.SSLMode)
+	env.bool("DB_SKIP_MIGRATIONS", &config.Database.SkipMigrations)
+	env.string("DB_MIGRATION_LOCK_TIMEOUT", &config.Database.MigrationLockTimeout)
+
+	// Tenancy configuration
+	env.string("TENANT_HEADER", &config.Tenancy.Header)
+	env.secret("ADMIN_TOKEN", &config.Tenancy.AdminToken)
+
+	// Authentication
+	env.bool("AUTH_ENABLED", &config.Auth.Enabled)
+	env.bool("JWT_ENABLED", &config.Auth.JWT.Enabled)
+	env.string("JWT_ISSUER", &config.Auth.JWT.Issuer)
+	env.string("JWT_AUDIENCE", &config.Auth.JWT.Audience)
+	env.string("JWT_JWKS_URL", &config.Auth.JWT.JWKSURL)
+	env.string("JWT_JWKS_FILE", &config.Auth.JWT.JWKSFile)
+	env.string("JWT_TENANT_CLAIM", &config.Auth.JWT.TenantClaim)
+	env.secret("SESSION_SECRET", &config.Auth.Session.Secret)
+	env.string("OIDC_CLIENT_ID", &config.Auth.OIDC.ClientID)
+	env.secret("OIDC_CLIENT_SECRET", &config.Auth.OIDC.ClientSecret)
+
+	// Rate limiting
+	env.bool("RATE_LIMIT_ENABLED", &config.RateLimit.Enabled)
+	env.string("RATE_LIMIT_STORE", &config.RateLimit.Store)
+
+	env.string("IDEMPOTENCY_TTL", &config.Idempotency.TTL)
+
+	// Webhook delivery
+	env.string("WEBHOOKS_TIMEOUT", &config.Webhooks.Timeout)
+	env.int("WEBHOOKS_MAX_ATTEMPTS", &config.Webhooks.MaxAttempts)
+
+	// Request validation
+	env.int64("MAX_BODY_BYTES", &config.Validation.MaxBodyBytes)
+	env.int("MAX_ORDER_ITEMS", &config.Validation.MaxOrderItems)
+	env.int("MAX_PACK_SIZE", &config.Validation.MaxPackSize)
+
+	// Tracing
+	env.bool("TRACING_ENABLED", &config.Tracing.Enabled)
+	env.string("TRACING_EXPORTER", &config.Tracing.Exporter)
+	env.string("TRACING_ENDPOINT", &config.Tracing.Endpoint)
+
+	// Logging
+	env.string("LOG_LEVEL", &config.Logging.Level)
+	env.string("LOG_FORMAT", &config.Logging.Format)
+
+	// Asset overrides
+	env.string("MIGRATIONS_DIR", &config.Assets.MigrationsDir)
+	env.string("TEMPLATES_DIR", &config.Assets.TemplatesDir)
+
+	return env.problems
 }
 
 func parseDatabaseURL(dbURL string, config *Config) error {
+	// The parse error quotes the URL, password included, so it is not wrapped
 	u, err := url.Parse(dbURL)
 	if err != nil {
-		return fmt.Errorf("invalid database URL: %w", err)
+		return fmt.Errorf("invalid database URL")
 	}
 
 	// Extract host and port
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	configFile := writeFile("config.yaml", "server:\n  port: 9090\ndatabase:\n  host: db.internal\n")
+	typoFile := writeFile("typo.yaml", "server:\n  prot: 9090\n")
+	passwordFile := writeFile("db-password", "from-file\n")
+
+	tests := []struct {
+		name             string
+		path             string
+		env              map[string]string
+		check            func(t *testing.T, cfg *Config)
+		expectError      bool
+		expectedProblems []string
+	}{
+		{
+			name: "File overrides defaults",
+			path: configFile,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != 9090 || cfg.Database.Host != "db.internal" {
+					t.Errorf("expected the file settings, got port %d and host '%s'", cfg.Server.Port, cfg.Database.Host)
+				}
+				if cfg.Database.Port != 5432 || cfg.Tenancy.Header != "X-Tenant-ID" {
+					t.Errorf("expected defaults for unset settings, got port %d and header '%s'", cfg.Database.Port, cfg.Tenancy.Header)
+				}
+			},
+		},
+		{
+			name: "Missing default file uses defaults and environment",
+			path: DefaultFile,
+			env:  map[string]string{"PORT": "7070"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != 7070 || cfg.Database.Host != "localhost" {
+					t.Errorf("expected port 7070 and host localhost, got %d and '%s'", cfg.Server.Port, cfg.Database.Host)
+				}
+			},
+		},
+		{
+			name:        "Missing explicit file",
+			path:        filepath.Join(dir, "missing.yaml"),
+			expectError: true,
+		},
+		{
+			name:        "Unknown key",
+			path:        typoFile,
+			expectError: true,
+		},
+		{
+			name: "Secret from file",
+			path: configFile,
+			env:  map[string]string{"DB_PASSWORD_FILE": passwordFile},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Password != "from-file" {
+					t.Errorf("expected the password from the file, got '%s'", cfg.Database.Password)
+				}
+			},
+		},
+		{
+			name: "Problems are reported together",
+			path: configFile,
+			env: map[string]string{
+				"PORT":                "abc",
+				"AUTH_ENABLED":        "maybe",
+				"ADMIN_TOKEN":         "token",
+				"ADMIN_TOKEN_FILE":    passwordFile,
+				"SESSION_SECRET_FILE": filepath.Join(dir, "missing-secret"),
+				"IDEMPOTENCY_TTL":     "1 day",
+			},
+			expectedProblems: []string{
+				"PORT: 'abc' is not an integer",
+				"ADMIN_TOKEN and ADMIN_TOKEN_FILE are both set: use only one",
+				"AUTH_ENABLED: 'maybe' is not a boolean, use true or false",
+				"SESSION_SECRET_FILE: failed to read secret: open " + filepath.Join(dir, "missing-secret") + ": no such file or directory",
+				"idempotency.ttl '1 day' is not a duration, such as 30s or 5m",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			// The default file is read from the working directory, which has none
+			t.Chdir(t.TempDir())
+
+			cfg, err := Load(tt.path)
+			if tt.expectedProblems != nil {
+				var invalid *ValidationError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected a validation error, got %v", err)
+				}
+				if !reflect.DeepEqual(invalid.Problems, tt.expectedProblems) {
+					t.Errorf("expected problems %q, got %q", tt.expectedProblems, invalid.Problems)
+				}
+				return
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name             string
+		modify           func(cfg *Config)
+		expectedProblems []string
+	}{
+		{
+			name:   "Defaults are valid",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "Zero config",
+			modify: func(cfg *Config) {
+				*cfg = Config{}
+			},
+			expectedProblems: []string{
+				"server.port must be between 1 and 65535, got 0",
+				"database.host is required",
+				"database.port must be between 1 and 65535, got 0",
+				"database.user is required",
+				"database.dbname is required",
+				"database.sslmode '' is invalid: must be one of disable, allow, prefer, require, verify-ca, verify-full",
+			},
+		},
+		{
+			name: "DSN replaces the connection settings",
+			modify: func(cfg *Config) {
+				cfg.Database = DatabaseConfig{DSN: "postgres://localhost/packing"}
+			},
+		},
+		{
+			name: "TLS without certificate",
+			modify: func(cfg *Config) {
+				cfg.Server.TLS.Enabled = true
+				cfg.Server.TLS.ClientAuth = "optional"
+			},
+			expectedProblems: []string{
+				"server.tls.cert_file is required",
+				"server.tls.key_file is required",
+				"server.tls.client_auth 'optional' requires server.tls.client_ca_file",
+			},
+		},
+		{
+			name: "JWT without keys",
+			modify: func(cfg *Config) {
+				cfg.Auth.JWT.Enabled = true
+				cfg.Auth.JWT.Issuer = "https://issuer.example.com"
+			},
+			expectedProblems: []string{
+				"exactly one of auth.jwt.jwks_url and auth.jwt.jwks_file must be set",
+				"auth.jwt.audience is required",
+				"auth.jwt.tenant_claim is required",
+			},
+		},
+		{
+			name: "Out of range values",
+			modify: func(cfg *Config) {
+				cfg.Server.Port = 70000
+				cfg.RateLimit.Store = "redis"
+				cfg.Tracing.SampleRatio = 2
+				cfg.Validation.MaxPackSize = -1
+				cfg.Webhooks.MaxAttempts = -1
+				cfg.Webhooks.Timeout = "soon"
+				cfg.Webhooks.AllowedNetworks = []string{"10.0.0.0/8", "intranet"}
+			},
+			expectedProblems: []string{
+				"server.port must be between 1 and 65535, got 70000",
+				"rate_limit.store 'redis' is invalid: must be one of memory, postgres",
+				"webhooks.timeout 'soon' is not a duration, such as 30s or 5m",
+				"webhooks.max_attempts must not be negative, got -1",
+				"webhooks.allowed_networks: invalid CIDR 'intranet'",
+				"validation.max_pack_size must not be negative, got -1",
+				"tracing.sample_ratio must be between 0 and 1, got 2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if tt.expectedProblems == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(invalid.Problems, tt.expectedProblems) {
+				t.Errorf("expected problems %q, got %q", tt.expectedProblems, invalid.Problems)
+			}
+		})
+	}
+}
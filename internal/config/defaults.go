@@ -0,0 +1,78 @@
+package config
+
+// Default returns the config used before the file and environment variables
+// are applied. It holds the same values the components fall back to when a
+// setting is empty, so the effective config shows what is actually used.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:              8080,
+			Host:              "0.0.0.0",
+			ReadTimeout:       "15s",
+			ReadHeaderTimeout: "5s",
+			WriteTimeout:      "30s",
+			IdleTimeout:       "120s",
+			ShutdownTimeout:   "25s",
+
+			TLS: TLSConfig{
+				Port:           8443,
+				ReloadInterval: "1m",
+			},
+		},
+		Database: DatabaseConfig{
+			Driver:          "postgres",
+			Host:            "localhost",
+			Port:            5432,
+			User:            "packing_user",
+			DBName:          "packing_service",
+			SSLMode:         "require",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: "5m",
+			ConnectTimeout:  "30s",
+		},
+		Tenancy: TenancyConfig{
+			Header:        "X-Tenant-ID",
+			DefaultTenant: "default",
+		},
+		Auth: AuthConfig{
+			JWT: JWTConfig{
+				RefreshInterval: "15m",
+				Leeway:          "1m",
+				RolesClaim:      "roles",
+			},
+			Session: SessionConfig{
+				TTL: "12h",
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Store:                  "memory",
+			CalculateItemsPerToken: 100000,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: "24h",
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:   "5s",
+			Timeout:        "10s",
+			MaxAttempts:    8,
+			InitialBackoff: "30s",
+			MaxBackoff:     "1h",
+			Retention:      "168h",
+		},
+		Validation: ValidationConfig{
+			MaxBodyBytes:  1 << 20,
+			MaxOrderItems: 10000000,
+			MaxPackSize:   1000000,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "otlp",
+			ServiceName: "packing-service",
+			SampleRatio: 1,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envReader applies environment variables to config fields. Values that
+// cannot be used are collected as problems instead of being skipped, so a
+// typo such as PORT=abc is reported together with everything else wrong.
+type envReader struct {
+	problems []string
+}
+
+func (r *envReader) problem(format string, args ...interface{}) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+// string sets dst to the variable when it is set
+func (r *envReader) string(name string, dst *string) {
+	if value := os.Getenv(name); value != "" {
+		*dst = value
+	}
+}
+
+// secret sets dst to the variable, or to the contents of the file named by
+// the variable with a _FILE suffix, as mounted by Docker and Kubernetes secrets
+func (r *envReader) secret(name string, dst *string) {
+	value, path := os.Getenv(name), os.Getenv(name+"_FILE")
+	if path == "" {
+		r.string(name, dst)
+		return
+	}
+	if value != "" {
+		r.problem("%s and %s_FILE are both set: use only one", name, name)
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		r.problem("%s_FILE: failed to read secret: %v", name, err)
+		return
+	}
+	// Editors and echo end files with a newline that is not part of the secret
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		r.problem("%s_FILE: secret file %s is empty", name, path)
+		return
+	}
+	*dst = secret
+}
+
+func (r *envReader) int(name string, dst *int) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.problem("%s: '%s' is not an integer", name, value)
+		return
+	}
+	*dst = n
+}
+
+func (r *envReader) int64(name string, dst *int64) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		r.problem("%s: '%s' is not an integer", name, value)
+		return
+	}
+	*dst = n
+}
+
+func (r *envReader) bool(name string, dst *bool) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		r.problem("%s: '%s' is not a boolean, use true or false", name, value)
+		return
+	}
+	*dst = b
+}
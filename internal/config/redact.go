@@ -0,0 +1,38 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// RedactedValue replaces secrets in redacted configs
+const RedactedValue = "[redacted]"
+
+// dsnPassword matches the password of a key=value connection string
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// Redacted returns a copy of the config with secrets replaced, safe to show
+// in logs and admin endpoints. Unset secrets stay empty, so it remains visible
+// whether they are configured.
+func (c Config) Redacted() Config {
+	redact := func(value *string) {
+		if *value != "" {
+			*value = RedactedValue
+		}
+	}
+
+	redact(&c.Database.Password)
+	redact(&c.Tenancy.AdminToken)
+	redact(&c.Auth.Session.Secret)
+	redact(&c.Auth.OIDC.ClientSecret)
+	c.Database.DSN = redactDSN(c.Database.DSN)
+	return c
+}
+
+// redactDSN hides the password of a URL or key=value connection string
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+RedactedValue)
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig_Redacted(t *testing.T) {
+	tests := []struct {
+		name  string
+		dsn   string
+		check func(dsn string) bool
+	}{
+		{name: "URL", dsn: "postgres://app:hunter2@db:5432/packing", check: func(dsn string) bool {
+			return dsn == "postgres://app:xxxxx@db:5432/packing"
+		}},
+		{name: "Key value", dsn: "host=db user=app password='hunter2' dbname=packing", check: func(dsn string) bool {
+			return strings.Contains(dsn, "password="+RedactedValue) && strings.Contains(dsn, "dbname=packing")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{}
+			cfg.Database.DSN = tt.dsn
+			cfg.Database.Password = "hunter2"
+			cfg.Auth.Session.Secret = "session-secret"
+
+			redacted := cfg.Redacted()
+			if !tt.check(redacted.Database.DSN) || strings.Contains(redacted.Database.DSN, "hunter2") {
+				t.Errorf("expected the DSN password to be redacted, got %q", redacted.Database.DSN)
+			}
+			if redacted.Database.Password != RedactedValue || redacted.Auth.Session.Secret != RedactedValue {
+				t.Errorf("expected secrets to be redacted, got %q and %q", redacted.Database.Password, redacted.Auth.Session.Secret)
+			}
+			if redacted.Auth.OIDC.ClientSecret != "" {
+				t.Errorf("expected an unset secret to stay empty, got %q", redacted.Auth.OIDC.ClientSecret)
+			}
+			if cfg.Database.Password != "hunter2" {
+				t.Error("expected the original config to be unchanged")
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every problem found in a config, so they can all be
+// fixed at once instead of one per restart
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the settings for values the service cannot start with
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problems returns a description of every invalid setting, named by its key
+// in config.yaml
+func (c *Config) problems() []string {
+	v := &validator{}
+
+	v.port("server.port", c.Server.Port, false)
+	v.duration("server.read_timeout", c.Server.ReadTimeout)
+	v.duration("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.duration("server.write_timeout", c.Server.WriteTimeout)
+	v.duration("server.idle_timeout", c.Server.IdleTimeout)
+	v.duration("server.shutdown_timeout", c.Server.ShutdownTimeout)
+
+	tls := c.Server.TLS
+	v.port("server.tls.port", tls.Port, true)
+	v.duration("server.tls.reload_interval", tls.ReloadInterval)
+	v.oneOf("server.tls.client_auth", tls.ClientAuth, "", "require", "optional")
+	if tls.Enabled {
+		v.required("server.tls.cert_file", tls.CertFile)
+		v.required("server.tls.key_file", tls.KeyFile)
+	}
+	if tls.ClientAuth != "" && tls.ClientCAFile == "" {
+		v.add("server.tls.client_auth '%s' requires server.tls.client_ca_file", tls.ClientAuth)
+	}
+	if tls.DisablePlain && !tls.Enabled {
+		v.add("server.tls.disable_plain requires server.tls.enabled, or nothing would be served")
+	}
+
+	db := c.Database
+	v.oneOf("database.driver", strings.ToLower(db.Driver), "", "postgres", "pq", "pgx")
+	if db.DSN == "" {
+		v.required("database.host", db.Host)
+		v.port("database.port", db.Port, false)
+		v.required("database.user", db.User)
+		v.required("database.dbname", db.DBName)
+		v.oneOf("database.sslmode", db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	}
+	v.nonNegative("database.max_open_conns", int64(db.MaxOpenConns))
+	v.nonNegative("database.max_idle_conns", int64(db.MaxIdleConns))
+	v.duration("database.conn_max_lifetime", db.ConnMaxLifetime)
+	v.duration("database.connect_timeout", db.ConnectTimeout)
+	v.duration("database.migration_lock_timeout", db.MigrationLockTimeout)
+
+	for _, size := range c.Tenancy.DefaultPackSizes {
+		if size <= 0 {
+			v.add("tenancy.default_pack_sizes must be positive, got %d", size)
+		}
+	}
+
+	jwt := c.Auth.JWT
+	if jwt.Enabled || c.Auth.OIDC.Enabled {
+		v.required("auth.jwt.issuer", jwt.Issuer)
+		if (jwt.JWKSURL == "") == (jwt.JWKSFile == "") {
+			v.add("exactly one of auth.jwt.jwks_url and auth.jwt.jwks_file must be set")
+		}
+	}
+	if jwt.Enabled {
+		v.required("auth.jwt.audience", jwt.Audience)
+		// Without the claim any token could select any tenant with the header
+		v.required("auth.jwt.tenant_claim", jwt.TenantClaim)
+	}
+	v.duration("auth.jwt.refresh_interval", jwt.RefreshInterval)
+	v.duration("auth.jwt.leeway", jwt.Leeway)
+	v.duration("auth.session.ttl", c.Auth.Session.TTL)
+	if oidc := c.Auth.OIDC; oidc.Enabled {
+		v.required("auth.oidc.client_id", oidc.ClientID)
+		v.required("auth.oidc.authorization_url", oidc.AuthorizationURL)
+		v.required("auth.oidc.token_url", oidc.TokenURL)
+		v.required("auth.oidc.redirect_url", oidc.RedirectURL)
+	}
+
+	v.oneOf("rate_limit.store", c.RateLimit.Store, "", "memory", "postgres")
+	if c.RateLimit.Rate < 0 || c.RateLimit.Burst < 0 {
+		v.add("rate_limit.rate and rate_limit.burst must not be negative")
+	}
+	v.nonNegative("rate_limit.trusted_proxies", int64(c.RateLimit.TrustedProxies))
+	v.nonNegative("rate_limit.calculate_items_per_token", int64(c.RateLimit.CalculateItemsPerToken))
+	for i, rule := range c.RateLimit.Rules {
+		if rule.Rate <= 0 || rule.Burst <= 0 {
+			v.add("rate_limit.rules[%d]: rate and burst must be positive", i)
+		}
+	}
+
+	v.duration("idempotency.ttl", c.Idempotency.TTL)
+
+	v.duration("webhooks.poll_interval", c.Webhooks.PollInterval)
+	v.duration("webhooks.timeout", c.Webhooks.Timeout)
+	v.nonNegative("webhooks.max_attempts", int64(c.Webhooks.MaxAttempts))
+	v.duration("webhooks.initial_backoff", c.Webhooks.InitialBackoff)
+	v.duration("webhooks.max_backoff", c.Webhooks.MaxBackoff)
+	v.duration("webhooks.retention", c.Webhooks.Retention)
+	for _, network := range c.Webhooks.AllowedNetworks {
+		if _, err := netip.ParsePrefix(network); err != nil {
+			v.add("webhooks.allowed_networks: invalid CIDR '%s'", network)
+		}
+	}
+
+	v.nonNegative("validation.max_body_bytes", c.Validation.MaxBodyBytes)
+	v.nonNegative("validation.max_order_items", int64(c.Validation.MaxOrderItems))
+	v.nonNegative("validation.max_pack_size", int64(c.Validation.MaxPackSize))
+
+	v.oneOf("tracing.exporter", strings.ToLower(c.Tracing.Exporter), "", "otlp", "stdout")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.add("tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	var level slog.Level
+	if c.Logging.Level != "" && level.UnmarshalText([]byte(c.Logging.Level)) != nil {
+		v.add("logging.level '%s' is invalid: must be debug, info, warn or error", c.Logging.Level)
+	}
+	v.oneOf("logging.format", strings.ToLower(c.Logging.Format), "", "text", "json")
+
+	return v.problems
+}
+
+// validator collects the problems found by its checks
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key, value string) {
+	if value == "" {
+		v.add("%s is required", key)
+	}
+}
+
+// port checks that a port is in range; zero means the default when optional
+func (v *validator) port(key string, port int, optional bool) {
+	if optional && port == 0 {
+		return
+	}
+	if port < 1 || port > 65535 {
+		v.add("%s must be between 1 and 65535, got %d", key, port)
+	}
+}
+
+func (v *validator) nonNegative(key string, value int64) {
+	if value < 0 {
+		v.add("%s must not be negative, got %d", key, value)
+	}
+}
+
+// duration checks a Go duration such as 30s or 5m; empty means the default
+func (v *validator) duration(key, value string) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		v.add("%s '%s' is not a duration, such as 30s or 5m", key, value)
+		return
+	}
+	if d < 0 {
+		v.add("%s must not be negative, got %s", key, value)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	var options []string
+	for _, a := range allowed {
+		if a != "" {
+			options = append(options, a)
+		}
+	}
+	v.add("%s '%s' is invalid: must be one of %s", key, value, strings.Join(options, ", "))
+}
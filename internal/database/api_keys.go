@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// ErrAPIKeyNotFound is returned when no active API key matches
+var ErrAPIKeyNotFound = fmt.Errorf("api key %w", ErrNotFound)
+
+const apiKeyColumns = `k.id, k.tenant_id, t.slug, k.name, k.prefix, k.scopes, k.created_at, k.last_used_at, k.revoked_at`
+
+type APIKeyRepository struct {
+	db *DB
+}
+
+func NewAPIKeyRepository(db *DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	var scopes string
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.TenantID, &key.TenantSlug, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Split(scopes, ",")
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+// GetAll returns all API keys of the tenant, including revoked ones
+func (r *APIKeyRepository) GetAll(ctx context.Context) ([]APIKey, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys k JOIN tenants t ON t.id = k.tenant_id WHERE k.tenant_id = $1 ORDER BY k.id ASC`
+	rows, err := r.db.QueryContext(ctx, query, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// GetByHash returns the active API key with the given hash, across all tenants
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys k JOIN tenants t ON t.id = k.tenant_id WHERE k.key_hash = $1 AND k.revoked_at IS NULL`
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Create stores a new API key for the tenant
+func (r *APIKeyRepository) Create(ctx context.Context, name, prefix, keyHash string, scopes []string) (*APIKey, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	query := `
+		WITH k AS (
+			INSERT INTO api_keys (tenant_id, name, prefix, key_hash, scopes)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT ` + apiKeyColumns + ` FROM k JOIN tenants t ON t.id = k.tenant_id
+	`
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, t.ID, name, prefix, keyHash, strings.Join(scopes, ",")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Rotate revokes an active key and issues a replacement with the same name and scopes
+func (r *APIKeyRepository) Rotate(ctx context.Context, id int, prefix, keyHash string) (*APIKey, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var name, scopes string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL
+		RETURNING name, scopes
+	`, id, t.ID).Scan(&name, &scopes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key with id %d: %w", id, ErrAPIKeyNotFound)
+		}
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	query := `
+		WITH k AS (
+			INSERT INTO api_keys (tenant_id, name, prefix, key_hash, scopes)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT ` + apiKeyColumns + ` FROM k JOIN tenants t ON t.id = k.tenant_id
+	`
+	key, err := scanAPIKey(tx.QueryRowContext(ctx, query, t.ID, name, prefix, keyHash, scopes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit rotation: %w", err)
+	}
+
+	return key, nil
+}
+
+// Revoke marks an active API key as revoked
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
+	query := `UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, id, t.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("api key with id %d: %w", id, ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// TouchLastUsed records that a key was used, at most once a minute per key
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int) error {
+	query := `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+	return nil
+}
@@ -3,21 +3,49 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
-	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/XSAM/otelsql"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/lib/pq"
+	"github.com/miloradbozic/packing-service/internal/config"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// DefaultConnectTimeout is how long startup keeps retrying an unreachable database
+const DefaultConnectTimeout = 30 * time.Second
+
+const (
+	initialRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 5 * time.Second
 )
 
 type DB struct {
 	*sql.DB
+	// rowLevelSecurity makes repositories set app.tenant_id on every transaction
+	rowLevelSecurity bool
+	// dataSourceName opens the dedicated connections of LISTEN
+	dataSourceName string
 }
 
 func NewConnection(cfg *config.DatabaseConfig) (*DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+	driver, err := driverName(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if driver == "pgx" {
+		if err := checkListenerDSN(dataSourceName(cfg)); err != nil {
+			return nil, err
+		}
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	// Every statement gets a span under the repository method that ran it
+	db, err := otelsql.Open(driver, dataSourceName(cfg),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitConnPrepare: true, OmitRows: true}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
@@ -29,19 +57,76 @@ func NewConnection(cfg *config.DatabaseConfig) (*DB, error) {
 	if cfg.ConnMaxLifetime != "" {
 		duration, err := time.ParseDuration(cfg.ConnMaxLifetime)
 		if err != nil {
+			db.Close()
 			return nil, fmt.Errorf("invalid conn_max_lifetime: %w", err)
 		}
 		db.SetConnMaxLifetime(duration)
 	}
 
-	// Test the connection
-	if err := db.Ping(); err != nil {
+	connectTimeout := DefaultConnectTimeout
+	if cfg.ConnectTimeout != "" {
+		connectTimeout, err = time.ParseDuration(cfg.ConnectTimeout)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("invalid connect_timeout: %w", err)
+		}
+	}
+
+	// Test the connection, retrying while the database starts up
+	if err := pingWithRetry(db, connectTimeout); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{db}, nil
+	return &DB{DB: db, rowLevelSecurity: cfg.RowLevelSecurity, dataSourceName: dataSourceName(cfg)}, nil
 }
 
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
+
+// driverName maps the configured driver to a registered database/sql driver
+func driverName(driver string) (string, error) {
+	switch strings.ToLower(driver) {
+	case "", "postgres", "pq":
+		return "postgres", nil
+	case "pgx":
+		return "pgx", nil
+	default:
+		return "", fmt.Errorf("unsupported database driver '%s': must be postgres or pgx", driver)
+	}
+}
+
+// dataSourceName returns the configured DSN, or builds one from the individual settings
+func dataSourceName(cfg *config.DatabaseConfig) string {
+	if cfg.DSN != "" {
+		return cfg.DSN
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+}
+
+// pingWithRetry pings the database with exponential backoff until it answers or the timeout passes
+func pingWithRetry(db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	delay := initialRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := db.Ping()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("Database not ready, retrying", "attempt", attempt, "error", err, "retry_in", delay)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+)
+
+func TestDriverName(t *testing.T) {
+	tests := []struct {
+		driver      string
+		expected    string
+		expectError bool
+	}{
+		{driver: "", expected: "postgres"},
+		{driver: "postgres", expected: "postgres"},
+		{driver: "pq", expected: "postgres"},
+		{driver: "PGX", expected: "pgx"},
+		{driver: "mysql", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			name, err := driverName(tt.driver)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %q", name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if name != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, name)
+			}
+		})
+	}
+}
+
+func TestDataSourceName(t *testing.T) {
+	fields := config.DatabaseConfig{Host: "db", Port: 5432, User: "packing", Password: "secret", DBName: "packing", SSLMode: "disable"}
+	withDSN := fields
+	withDSN.DSN = "postgres://other@elsewhere/packing"
+
+	tests := []struct {
+		name     string
+		cfg      config.DatabaseConfig
+		expected string
+	}{
+		{
+			name:     "Built from the individual settings",
+			cfg:      fields,
+			expected: "host=db port=5432 user=packing password=secret dbname=packing sslmode=disable",
+		},
+		{
+			name:     "DSN takes precedence",
+			cfg:      withDSN,
+			expected: "postgres://other@elsewhere/packing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dataSourceName(&tt.cfg); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// flakyConnector fails the first failures connection attempts
+type flakyConnector struct {
+	failures int
+	attempts int
+}
+
+func (c *flakyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		return nil, errors.New("connection refused")
+	}
+	return stubConn{}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver {
+	return nil
+}
+
+type stubConn struct{}
+
+func (stubConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (stubConn) Close() error                              { return nil }
+func (stubConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func TestPingWithRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		timeout          time.Duration
+		expectedAttempts int
+		expectError      bool
+	}{
+		{
+			name:             "Database is up",
+			timeout:          time.Second,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Database comes up after a failed ping",
+			failures:         1,
+			timeout:          time.Second,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "Gives up at the deadline",
+			failures:         10,
+			timeout:          initialRetryDelay / 2,
+			expectedAttempts: 1,
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &flakyConnector{failures: tt.failures}
+			db := sql.OpenDB(connector)
+			defer db.Close()
+
+			err := pingWithRetry(db, tt.timeout)
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+			if connector.attempts != tt.expectedAttempts {
+				t.Errorf("expected %d connection attempts, got %d", tt.expectedAttempts, connector.attempts)
+			}
+		})
+	}
+}
+
+func TestCheckListenerDSN(t *testing.T) {
+	tests := []struct {
+		name        string
+		dsn         string
+		expectError bool
+	}{
+		{name: "Key value DSN", dsn: "host=db port=5432 user=packing dbname=packing sslmode=disable"},
+		{name: "URL DSN", dsn: "postgres://packing:secret@db:5432/packing?sslmode=disable"},
+		{name: "Unterminated quote", dsn: "host='db user=packing", expectError: true},
+		{name: "Invalid URL", dsn: "postgres://packing:secret@db:port/packing", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkListenerDSN(tt.dsn)
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
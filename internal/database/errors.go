@@ -0,0 +1,29 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when a requested record does not exist. The more
+	// specific not found errors of each repository wrap it.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicateSize is returned when a tenant already has a pack size of the same size
+	ErrDuplicateSize = errors.New("pack size already exists")
+)
+
+// uniqueViolation is the SQLSTATE Postgres reports for unique constraint violations
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a unique constraint violation. Both
+// lib/pq and pgx errors expose their SQLSTATE through a SQLState method.
+func isUniqueViolation(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	return errors.As(err, &sqlErr) && sqlErr.SQLState() == uniqueViolation
+}
+
+// notFound wraps ErrNotFound for the record of the given kind and ID
+func notFound(kind string, id int) error {
+	return fmt.Errorf("%s with id %d: %w", kind, id, ErrNotFound)
+}
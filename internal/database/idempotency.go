@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// idempotencyCleanupInterval is how often expired idempotency keys are deleted
+const idempotencyCleanupInterval = time.Hour
+
+type IdempotencyRepository struct {
+	db *DB
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+	cleanups    sync.WaitGroup
+}
+
+func NewIdempotencyRepository(db *DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, lastCleanup: time.Now()}
+}
+
+// Begin claims the key for a new request, or returns the record of the request
+// that claimed it first. The claim is held for lease until the response is
+// stored. Expired keys, and claims whose lease ran out because the request
+// never finished, are claimed anew.
+func (r *IdempotencyRepository) Begin(ctx context.Context, scope, key, fingerprint string, ttl, lease time.Duration) (*IdempotencyRecord, bool, error) {
+	r.cleanup()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+		AND (expires_at < CURRENT_TIMESTAMP OR (status_code IS NULL AND locked_until < CURRENT_TIMESTAMP))
+	`, scope, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to delete expired idempotency key: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (scope, key, fingerprint, expires_at, locked_until)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + $4 * INTERVAL '1 second', CURRENT_TIMESTAMP + $5 * INTERVAL '1 second')
+		ON CONFLICT (scope, key) DO NOTHING
+	`, scope, key, fingerprint, ttl.Seconds(), lease.Seconds())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	var record IdempotencyRecord
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT scope, key, fingerprint, status_code, content_type, body, created_at, expires_at
+		FROM idempotency_keys WHERE scope = $1 AND key = $2
+	`, scope, key).Scan(&record.Scope, &record.Key, &record.Fingerprint, &statusCode, &contentType, &record.Body, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit idempotency key: %w", err)
+	}
+
+	return &record, created == 1, nil
+}
+
+// Complete stores the response of the request that claimed the key
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $3, content_type = $4, body = $5, locked_until = NULL WHERE scope = $1 AND key = $2`
+	if _, err := r.db.ExecContext(ctx, query, scope, key, statusCode, contentType, body); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release deletes a claimed key whose request should not be replayed
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2`
+	if _, err := r.db.ExecContext(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// cleanup deletes expired keys in the background, at most once per cleanup interval
+func (r *IdempotencyRepository) cleanup() {
+	r.mu.Lock()
+	if time.Since(r.lastCleanup) < idempotencyCleanupInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCleanup = time.Now()
+	r.mu.Unlock()
+
+	r.cleanups.Add(1)
+	go func() {
+		defer r.cleanups.Done()
+		if _, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+			slog.Error("Failed to delete expired idempotency keys", "error", err)
+		}
+	}()
+}
+
+// Wait blocks until running cleanups have finished, so the database can be closed
+func (r *IdempotencyRepository) Wait() {
+	r.cleanups.Wait()
+}
@@ -1,10 +1,67 @@
 package database
 
-// PackSizeRepositoryInterface defines the interface for pack size repository operations
+import (
+	"context"
+	"time"
+)
+
+// PackSizeRepositoryInterface defines the interface for pack size repository operations.
+// All operations are scoped to the tenant carried by the context.
 type PackSizeRepositoryInterface interface {
-	GetAll() ([]PackSize, error)
-	GetByID(id int) (*PackSize, error)
-	Create(size int) (*PackSize, error)
-	Update(id int, size int) (*PackSize, error)
-	Delete(id int) error
+	GetAll(ctx context.Context) ([]PackSize, error)
+	// List returns one page of pack sizes matching the query
+	List(ctx context.Context, query PackSizeQuery) (*PackSizePage, error)
+	GetByID(ctx context.Context, id int) (*PackSize, error)
+	Create(ctx context.Context, size int, active bool) (*PackSize, error)
+	Update(ctx context.Context, id int, size int, active *bool) (*PackSize, error)
+	Delete(ctx context.Context, id int) error
+	Import(ctx context.Context, packSizes []PackSize, replace bool) error
+}
+
+// TenantRepositoryInterface defines the interface for tenant repository operations
+type TenantRepositoryInterface interface {
+	GetAll(ctx context.Context) ([]Tenant, error)
+	GetBySlug(ctx context.Context, slug string) (*Tenant, error)
+	Create(ctx context.Context, slug, name string, packSizes []int) (*Tenant, error)
+}
+
+// APIKeyRepositoryInterface defines the interface for API key repository operations.
+// Except for GetByHash, operations are scoped to the tenant carried by the context.
+type APIKeyRepositoryInterface interface {
+	GetAll(ctx context.Context) ([]APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	Create(ctx context.Context, name, prefix, keyHash string, scopes []string) (*APIKey, error)
+	Rotate(ctx context.Context, id int, prefix, keyHash string) (*APIKey, error)
+	Revoke(ctx context.Context, id int) error
+	TouchLastUsed(ctx context.Context, id int) error
+}
+
+// UserRepositoryInterface defines the interface for local user operations.
+// Usernames are unique across tenants, so lookups are not tenant scoped.
+type UserRepositoryInterface interface {
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	Upsert(ctx context.Context, tenantID int, username, passwordHash string, scopes []string) (*User, error)
+}
+
+// IdempotencyRepositoryInterface defines the interface for idempotency key operations
+type IdempotencyRepositoryInterface interface {
+	// Begin claims the key for a new request. If the key is already claimed it
+	// returns the existing record and false instead.
+	Begin(ctx context.Context, scope, key, fingerprint string, ttl, lease time.Duration) (*IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error
+	// Release forgets a claimed key, so that the request can be retried
+	Release(ctx context.Context, scope, key string) error
+}
+
+// WebhookRepositoryInterface defines the interface for webhook subscription
+// operations. All operations are scoped to the tenant carried by the context.
+type WebhookRepositoryInterface interface {
+	GetAll(ctx context.Context) ([]WebhookSubscription, error)
+	GetByID(ctx context.Context, id int) (*WebhookSubscription, error)
+	Create(ctx context.Context, url, secret string, events []string, active bool) (*WebhookSubscription, error)
+	Update(ctx context.Context, id int, url string, events []string, active *bool) (*WebhookSubscription, error)
+	Delete(ctx context.Context, id int) error
+	// Deliveries returns the delivery log of a subscription, newest first
+	Deliveries(ctx context.Context, subscriptionID int, status string, limit int) ([]WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, subscriptionID int, deliveryID int64) (*WebhookDelivery, error)
 }
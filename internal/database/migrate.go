@@ -1,134 +1,523 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"io/fs"
+	"log/slog"
 	"sort"
 	"strings"
+	"time"
 )
 
+// migrationLockKey identifies the Postgres advisory lock held while migrations run
+const migrationLockKey = 72696173
+
+// DefaultMigrationLockTimeout is how long a migrator waits for another instance to finish
+const DefaultMigrationLockTimeout = time.Minute
+
+// lockPollInterval is how often a waiting migrator retries the advisory lock
+const lockPollInterval = time.Second
+
 type Migrator struct {
-	db *DB
+	db          *DB
+	migrations  fs.FS
+	lockTimeout time.Duration
+}
+
+// NewMigrator creates a migrator that reads migration files from the root of the given filesystem
+func NewMigrator(db *DB, migrations fs.FS) *Migrator {
+	return &Migrator{db: db, migrations: migrations, lockTimeout: DefaultMigrationLockTimeout}
+}
+
+// SetLockTimeout sets how long to wait for the migration lock held by another instance
+func (m *Migrator) SetLockTimeout(timeout time.Duration) {
+	m.lockTimeout = timeout
+}
+
+// Migration is a versioned schema change loaded from the migrations filesystem.
+// Files are named <version>.up.sql and <version>.down.sql; a plain <version>.sql
+// is treated as an up migration without a down counterpart.
+type Migration struct {
+	Version  string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describes the state of a single migration version
+type MigrationStatus struct {
+	Version   string
+	Applied   bool
+	AppliedAt *time.Time
+	// Drift is set when the up file changed after the migration was applied
+	Drift bool
+	// Missing is set when an applied version has no file on disk anymore
+	Missing bool
+}
+
+type appliedMigration struct {
+	version   string
+	checksum  sql.NullString
+	appliedAt time.Time
+}
+
+// RunMigrations verifies applied migrations and runs all pending ones
+func (m *Migrator) RunMigrations() error {
+	_, err := m.Up()
+	return err
+}
+
+// Up applies all pending migrations in order and returns their versions.
+// It refuses to run if an already applied migration was modified.
+func (m *Migrator) Up() ([]string, error) {
+	var ran []string
+	err := m.withLock(func(conn *sql.Conn) error {
+		var err error
+		ran, err = m.up(conn)
+		return err
+	})
+	return ran, err
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(steps int) ([]string, error) {
+	var rolledBack []string
+	err := m.withLock(func(conn *sql.Conn) error {
+		var err error
+		rolledBack, err = m.down(conn, steps)
+		return err
+	})
+	return rolledBack, err
+}
+
+// Redo rolls back the most recently applied migration and applies it again
+func (m *Migrator) Redo() (string, error) {
+	var version string
+	err := m.withLock(func(conn *sql.Conn) error {
+		rolledBack, err := m.down(conn, 1)
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			return fmt.Errorf("no applied migrations to redo")
+		}
+		version = rolledBack[0]
+
+		_, err = m.up(conn)
+		return err
+	})
+	return version, err
+}
+
+func (m *Migrator) up(conn *sql.Conn) ([]string, error) {
+	migrations, applied, err := m.prepare(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.verify(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var ran []string
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.applyMigration(conn, migration); err != nil {
+			return ran, fmt.Errorf("failed to apply migration %s: %w", migration.Version, err)
+		}
+		ran = append(ran, migration.Version)
+	}
+
+	return ran, nil
 }
 
-func NewMigrator(db *DB) *Migrator {
-	return &Migrator{db: db}
+func (m *Migrator) down(conn *sql.Conn, steps int) ([]string, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be positive")
+	}
+
+	migrations, applied, err := m.prepare(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.verify(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var rolledBack []string
+	for i := len(migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return rolledBack, fmt.Errorf("migration %s has no down migration", migration.Version)
+		}
+		if err := m.revertMigration(conn, migration); err != nil {
+			return rolledBack, fmt.Errorf("failed to roll back migration %s: %w", migration.Version, err)
+		}
+		rolledBack = append(rolledBack, migration.Version)
+	}
+
+	return rolledBack, nil
 }
 
-// RunMigrations runs all pending migrations
-func (m *Migrator) RunMigrations(migrationsPath string) error {
-	// Create migrations table if it doesn't exist
-	if err := m.createMigrationsTable(); err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+// Status reports every known migration version, applied or not. It only reads
+// the migrations table, so it neither waits for a running migration nor needs
+// DDL rights, and can back health checks.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(m.migrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration files: %w", err)
 	}
 
-	// Get list of migration files
-	migrationFiles, err := m.getMigrationFiles(migrationsPath)
+	applied, err := m.readAppliedMigrations(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get migration files: %w", err)
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 
-	// Get applied migrations
-	appliedMigrations, err := m.getAppliedMigrations()
+	return migrationStatuses(migrations, applied), nil
+}
+
+// readAppliedMigrations loads the applied versions without creating or
+// altering the migrations table. Before the first migration there is no
+// table, and tables created before checksums were tracked have no checksum.
+func (m *Migrator) readAppliedMigrations(ctx context.Context) (map[string]appliedMigration, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'schema_migrations'
+	`)
 	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %w", err)
+		return nil, err
 	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		columns[column] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !columns["version"] {
+		return map[string]appliedMigration{}, nil
+	}
+	checksum := "NULL"
+	if columns["checksum"] {
+		checksum = "checksum"
+	}
+	return queryAppliedMigrations(ctx, m.db, `SELECT version, `+checksum+`, applied_at FROM schema_migrations ORDER BY version`)
+}
+
+// migrationStatuses combines the migration files with the applied versions
+func migrationStatuses(migrations []Migration, applied map[string]appliedMigration) []MigrationStatus {
+	known := make(map[string]bool, len(migrations))
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		status := MigrationStatus{Version: migration.Version}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Drift = record.checksum.Valid && record.checksum.String != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := record.appliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses
+}
 
-	// Apply pending migrations
-	for _, migrationFile := range migrationFiles {
-		if !m.isMigrationApplied(migrationFile, appliedMigrations) {
-			if err := m.applyMigration(migrationFile, migrationsPath); err != nil {
-				return fmt.Errorf("failed to apply migration %s: %w", migrationFile, err)
+// withLock runs fn while holding the migration advisory lock, so that only one
+// instance migrates at a time. It waits up to the lock timeout for other holders.
+// All statements run on the connection that holds the lock.
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.lockTimeout)
+	defer cancel()
+
+	// Advisory locks belong to a session, so pin a single connection for lock and unlock
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, migrationLockKey).Scan(&acquired); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("timed out after %s waiting for migration lock", m.lockTimeout)
 			}
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+
+		slog.Info("Waiting for migration lock", "holder", m.lockHolder(ctx, conn))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for migration lock", m.lockTimeout)
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	defer func() {
+		// Use a fresh context so the lock is released even after a timeout
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			slog.Error("Failed to release migration lock", "error", err)
 		}
+	}()
+
+	return fn(conn)
+}
+
+// lockHolder describes the backend currently holding the migration lock
+func (m *Migrator) lockHolder(ctx context.Context, conn *sql.Conn) string {
+	query := `
+		SELECT a.pid, COALESCE(a.application_name, ''), COALESCE(host(a.client_addr), 'local'), a.backend_start
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+			AND l.classid = 0 AND l.objid = $1 AND l.objsubid = 1
+	`
+
+	var pid int
+	var application, client string
+	var since time.Time
+	if err := conn.QueryRowContext(ctx, query, migrationLockKey).Scan(&pid, &application, &client, &since); err != nil {
+		return "an unknown session"
 	}
 
+	if application == "" {
+		application = "unknown application"
+	}
+	return fmt.Sprintf("pid %d (%s from %s, connected since %s)", pid, application, client, since.Format(time.RFC3339))
+}
+
+// prepare creates the migrations table and loads both the files and the applied versions
+func (m *Migrator) prepare(conn *sql.Conn) ([]Migration, map[string]appliedMigration, error) {
+	if err := m.createMigrationsTable(conn); err != nil {
+		return nil, nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := LoadMigrations(m.migrations)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	applied, err := m.getAppliedMigrations(conn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	if err := m.backfillChecksums(conn, migrations, applied); err != nil {
+		return nil, nil, fmt.Errorf("failed to record migration checksums: %w", err)
+	}
+
+	return migrations, applied, nil
+}
+
+// verify returns an error if any applied migration no longer matches its file
+func (m *Migrator) verify(migrations []Migration, applied map[string]appliedMigration) error {
+	var drifted []string
+	for _, migration := range migrations {
+		record, ok := applied[migration.Version]
+		if ok && record.checksum.Valid && record.checksum.String != migration.Checksum {
+			drifted = append(drifted, migration.Version)
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("applied migrations were modified: %s", strings.Join(drifted, ", "))
+	}
 	return nil
 }
 
-func (m *Migrator) createMigrationsTable() error {
+// backfillChecksums records checksums for migrations applied before checksums were tracked
+func (m *Migrator) backfillChecksums(conn *sql.Conn, migrations []Migration, applied map[string]appliedMigration) error {
+	for _, migration := range migrations {
+		record, ok := applied[migration.Version]
+		if !ok || record.checksum.Valid {
+			continue
+		}
+
+		_, err := conn.ExecContext(context.Background(), `UPDATE schema_migrations SET checksum = $1 WHERE version = $2`, migration.Checksum, migration.Version)
+		if err != nil {
+			return err
+		}
+		slog.Info("Recorded checksum for previously applied migration", "version", migration.Version)
+
+		record.checksum = sql.NullString{String: migration.Checksum, Valid: true}
+		applied[migration.Version] = record
+	}
+	return nil
+}
+
+func (m *Migrator) createMigrationsTable(conn *sql.Conn) error {
 	query := `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version VARCHAR(255) PRIMARY KEY,
+			checksum VARCHAR(64),
 			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		)
+		);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum VARCHAR(64);
 	`
-	_, err := m.db.Exec(query)
+	_, err := conn.ExecContext(context.Background(), query)
 	return err
 }
 
-func (m *Migrator) getMigrationFiles(migrationsPath string) ([]string, error) {
-	files, err := ioutil.ReadDir(migrationsPath)
+// LoadMigrations reads and pairs the up and down files in the root of fsys
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, err
 	}
 
-	var migrationFiles []string
+	byVersion := make(map[string]*Migration)
+	get := func(version string) *Migration {
+		if byVersion[version] == nil {
+			byVersion[version] = &Migration{Version: version}
+		}
+		return byVersion[version]
+	}
+
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".sql") {
-			migrationFiles = append(migrationFiles, file.Name())
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case strings.HasSuffix(name, ".down.sql"):
+			migration := get(strings.TrimSuffix(name, ".down.sql"))
+			migration.Down = string(content)
+		case strings.HasSuffix(name, ".up.sql"):
+			migration := get(strings.TrimSuffix(name, ".up.sql"))
+			if migration.Up != "" {
+				return nil, fmt.Errorf("duplicate up migration for version %s", migration.Version)
+			}
+			migration.Up = string(content)
+		default:
+			migration := get(strings.TrimSuffix(name, ".sql"))
+			if migration.Up != "" {
+				return nil, fmt.Errorf("duplicate up migration for version %s", migration.Version)
+			}
+			migration.Up = string(content)
 		}
 	}
 
-	sort.Strings(migrationFiles)
-	return migrationFiles, nil
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %s has a down file but no up file", migration.Version)
+		}
+		sum := sha256.Sum256([]byte(migration.Up))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
 }
 
-func (m *Migrator) getAppliedMigrations() ([]string, error) {
-	query := `SELECT version FROM schema_migrations ORDER BY version`
-	rows, err := m.db.Query(query)
+func (m *Migrator) getAppliedMigrations(conn *sql.Conn) (map[string]appliedMigration, error) {
+	return queryAppliedMigrations(context.Background(), conn, `SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version`)
+}
+
+// queryAppliedMigrations runs a query returning version, checksum and applied_at
+func queryAppliedMigrations(ctx context.Context, q querier, query string) (map[string]appliedMigration, error) {
+	rows, err := q.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var applied []string
+	applied := make(map[string]appliedMigration)
 	for rows.Next() {
-		var version string
-		if err := rows.Scan(&version); err != nil {
+		var record appliedMigration
+		if err := rows.Scan(&record.version, &record.checksum, &record.appliedAt); err != nil {
 			return nil, err
 		}
-		applied = append(applied, version)
+		applied[record.version] = record
 	}
 
-	return applied, nil
+	return applied, rows.Err()
 }
 
-func (m *Migrator) isMigrationApplied(migrationFile string, appliedMigrations []string) bool {
-	version := strings.TrimSuffix(migrationFile, ".sql")
-	for _, applied := range appliedMigrations {
-		if applied == version {
-			return true
-		}
+func (m *Migrator) applyMigration(conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
 	}
-	return false
-}
+	defer tx.Rollback()
 
-func (m *Migrator) applyMigration(migrationFile, migrationsPath string) error {
-	// Read migration file
-	migrationPath := filepath.Join(migrationsPath, migrationFile)
-	content, err := ioutil.ReadFile(migrationPath)
+	// Execute migration
+	if _, err := tx.Exec(migration.Up); err != nil {
+		return err
+	}
+
+	// Record migration as applied
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)`, migration.Version, migration.Checksum)
 	if err != nil {
 		return err
 	}
 
-	// Execute migration
-	tx, err := m.db.Begin()
+	return tx.Commit()
+}
+
+func (m *Migrator) revertMigration(conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(string(content))
-	if err != nil {
+	// Execute rollback
+	if _, err := tx.Exec(migration.Down); err != nil {
 		return err
 	}
 
-	// Record migration as applied
-	version := strings.TrimSuffix(migrationFile, ".sql")
-	_, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, version)
-	if err != nil {
+	// Forget the migration
+	if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
 		return err
 	}
 
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func migrationFiles(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := migrationFiles(map[string]string{
+		"002_add_index.up.sql":   "CREATE INDEX a ON t(x);",
+		"002_add_index.down.sql": "DROP INDEX a;",
+		"001_legacy.sql":         "CREATE TABLE t (x INT);",
+		"README.md":              "ignored",
+	})
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+
+	if migrations[0].Version != "001_legacy" || migrations[0].Down != "" {
+		t.Errorf("expected legacy up-only migration first, got %+v", migrations[0])
+	}
+	if migrations[1].Version != "002_add_index" || migrations[1].Down != "DROP INDEX a;" {
+		t.Errorf("expected paired migration second, got %+v", migrations[1])
+	}
+	if len(migrations[1].Checksum) != 64 {
+		t.Errorf("expected sha256 checksum, got %q", migrations[1].Checksum)
+	}
+}
+
+func TestLoadMigrations_ChecksumChangesWithContent(t *testing.T) {
+	before, err := LoadMigrations(migrationFiles(map[string]string{"001_a.up.sql": "SELECT 1;"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after, err := LoadMigrations(migrationFiles(map[string]string{"001_a.up.sql": "SELECT 2;"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if before[0].Checksum == after[0].Checksum {
+		t.Errorf("expected checksum to change when the file changes")
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name:  "Down without up",
+			files: map[string]string{"001_a.down.sql": "DROP TABLE t;"},
+		},
+		{
+			name:  "Both plain and up file",
+			files: map[string]string{"001_a.sql": "SELECT 1;", "001_a.up.sql": "SELECT 1;"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadMigrations(migrationFiles(tt.files)); err == nil {
+				t.Errorf("expected error but got none")
+			}
+		})
+	}
+}
+
+func TestMigrationStatuses(t *testing.T) {
+	migrations := []Migration{
+		{Version: "001_a", Checksum: "aaa"},
+		{Version: "002_b", Checksum: "bbb"},
+		{Version: "003_c", Checksum: "ccc"},
+		{Version: "004_d", Checksum: "ddd"},
+	}
+	appliedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	applied := map[string]appliedMigration{
+		"000_gone": {version: "000_gone", appliedAt: appliedAt},
+		"001_a":    {version: "001_a", checksum: sql.NullString{String: "aaa", Valid: true}, appliedAt: appliedAt},
+		"002_b":    {version: "002_b", checksum: sql.NullString{String: "changed", Valid: true}, appliedAt: appliedAt},
+		// Applied before checksums were tracked
+		"003_c": {version: "003_c", appliedAt: appliedAt},
+	}
+
+	statuses := migrationStatuses(migrations, applied)
+
+	expected := []MigrationStatus{
+		{Version: "000_gone", Applied: true, Missing: true},
+		{Version: "001_a", Applied: true},
+		{Version: "002_b", Applied: true, Drift: true},
+		{Version: "003_c", Applied: true},
+		{Version: "004_d"},
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %d statuses, got %+v", len(expected), statuses)
+	}
+	for i, want := range expected {
+		got := statuses[i]
+		if got.Version != want.Version || got.Applied != want.Applied || got.Drift != want.Drift || got.Missing != want.Missing {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+		if got.Applied != (got.AppliedAt != nil) {
+			t.Errorf("%s: expected applied_at only for applied migrations, got %v", got.Version, got.AppliedAt)
+		}
+	}
+}
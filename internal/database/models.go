@@ -4,12 +4,16 @@ import (
 	"time"
 )
 
-// PackSize represents a pack size configuration in the database
+// PackSize represents a pack size configuration in the database. Only active
+// pack sizes are used for calculations; exports carry the flag through the
+// transfer package.
 type PackSize struct {
-	ID        int       `json:"id" db:"id"`
-	Size      int       `json:"size" db:"size"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        int       `json:"id" yaml:"id" db:"id"`
+	TenantID  int       `json:"-" yaml:"-" db:"tenant_id"`
+	Size      int       `json:"size" yaml:"size" db:"size"`
+	Active    bool      `json:"-" yaml:"-" db:"active"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at" db:"updated_at"`
 }
 
 // PackSizeRequest represents a request to create/update a pack size
@@ -24,3 +28,77 @@ type PackSizeResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// Tenant represents a client company whose pack sizes are isolated from other tenants
+type Tenant struct {
+	ID        int       `json:"id" db:"id"`
+	Slug      string    `json:"slug" db:"slug"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// APIKey represents a hashed API key; the plaintext key is never stored
+type APIKey struct {
+	ID         int        `json:"id" db:"id"`
+	TenantID   int        `json:"tenant_id" db:"tenant_id"`
+	TenantSlug string     `json:"tenant_slug" db:"-"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// User is a local web UI account; the password is stored only as a hash
+type User struct {
+	ID           int       `json:"id" db:"id"`
+	TenantID     int       `json:"tenant_id" db:"tenant_id"`
+	TenantSlug   string    `json:"tenant_slug" db:"-"`
+	Username     string    `json:"username" db:"username"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Scopes       []string  `json:"scopes" db:"scopes"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// IdempotencyRecord is the stored outcome of a request made with an Idempotency-Key
+type IdempotencyRecord struct {
+	Scope       string `json:"scope" db:"scope"`
+	Key         string `json:"key" db:"key"`
+	Fingerprint string `json:"fingerprint" db:"fingerprint"`
+	// StatusCode is 0 while the first request is still being processed
+	StatusCode  int       `json:"status_code" db:"status_code"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Body        []byte    `json:"-" db:"body"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// WebhookSubscription sends a tenant's events of the subscribed types to a URL
+type WebhookSubscription struct {
+	ID       int    `json:"id" db:"id"`
+	TenantID int    `json:"tenant_id" db:"tenant_id"`
+	URL      string `json:"url" db:"url"`
+	// Secret signs the deliveries; it is only shown when the subscription is created
+	Secret    string    `json:"-" db:"secret"`
+	Events    []string  `json:"events" db:"events"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery is the delivery of one event to one subscription, as shown in the delivery log
+type WebhookDelivery struct {
+	ID             int64      `json:"id" db:"id"`
+	SubscriptionID int        `json:"subscription_id" db:"subscription_id"`
+	EventID        int64      `json:"event_id" db:"event_id"`
+	EventType      string     `json:"event_type" db:"-"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code" db:"last_status_code"`
+	LastError      string     `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/miloradbozic/packing-service/internal/broadcast"
+)
+
+// packSizeChannel is the NOTIFY channel of pack size changes
+const packSizeChannel = "pack_size_changes"
+
+// listenerPingInterval is how often an idle LISTEN connection is checked
+const listenerPingInterval = time.Minute
+
+// packSizeNotification is the NOTIFY payload of a pack size change
+type packSizeNotification struct {
+	TenantID int             `json:"tenant_id"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// recordPackSizeChange records a pack size event in the outbox and notifies the
+// listeners of every instance. Both take effect when the transaction of q
+// commits, and not at all if it rolls back.
+func recordPackSizeChange(ctx context.Context, q querier, eventType string, ps *PackSize) error {
+	if err := insertPackSizeEvent(ctx, q, eventType, ps); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(newPackSizeEvent(ps))
+	if err != nil {
+		return fmt.Errorf("failed to encode %s notification: %w", eventType, err)
+	}
+	payload, err := json.Marshal(packSizeNotification{TenantID: ps.TenantID, Type: eventType, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s notification: %w", eventType, err)
+	}
+
+	if _, err := q.ExecContext(ctx, `SELECT pg_notify($1, $2)`, packSizeChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify %s: %w", eventType, err)
+	}
+	return nil
+}
+
+// PackSizeListener receives the pack size changes of all instances over a
+// dedicated LISTEN connection and publishes them to a broker. After the
+// connection is lost and re-established, it publishes a resync, since changes
+// made in between were missed. The connection always goes through lib/pq, also
+// with the pgx driver, so the DSN must be one lib/pq understands; see
+// checkListenerDSN.
+type PackSizeListener struct {
+	listener *pq.Listener
+	broker   *broadcast.Broker
+	done     chan struct{}
+}
+
+func NewPackSizeListener(db *DB, broker *broadcast.Broker) *PackSizeListener {
+	listener := pq.NewListener(db.dataSourceName, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			slog.Warn("Lost the pack size change listener connection", "error", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("Failed to connect the pack size change listener", "error", err)
+		case pq.ListenerEventReconnected:
+			slog.Info("Reconnected the pack size change listener")
+		}
+	})
+	return &PackSizeListener{listener: listener, broker: broker}
+}
+
+// checkListenerDSN reports whether lib/pq can parse dsn, so that a DSN written
+// for pgx fails at startup rather than in the listener's reconnect loop
+func checkListenerDSN(dsn string) error {
+	if _, err := pq.NewConnector(dsn); err != nil {
+		return fmt.Errorf("the database DSN must also be valid for lib/pq, which LISTEN uses: %w", err)
+	}
+	return nil
+}
+
+// Start listens for changes until Stop is called
+func (l *PackSizeListener) Start() {
+	if l.done != nil {
+		return
+	}
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+		// Listen blocks until connected, and fails once the listener is closed
+		if err := l.listener.Listen(packSizeChannel); err != nil {
+			slog.Error("Failed to listen for pack size changes", "error", err)
+			return
+		}
+
+		ticker := time.NewTicker(listenerPingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case n, ok := <-l.listener.Notify:
+				if !ok {
+					return
+				}
+				if n == nil {
+					l.broker.Publish(broadcast.Message{Event: broadcast.EventResync})
+					continue
+				}
+				l.publish(n.Extra)
+			case <-ticker.C:
+				// A failed ping makes the listener reconnect
+				go l.listener.Ping()
+			}
+		}
+	}()
+}
+
+// Stop closes the connection and waits for the listener to finish
+func (l *PackSizeListener) Stop() {
+	if l.done == nil {
+		return
+	}
+	l.listener.Close()
+	<-l.done
+	l.done = nil
+}
+
+func (l *PackSizeListener) publish(payload string) {
+	var n packSizeNotification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		slog.Error("Ignoring malformed pack size notification", "error", err)
+		return
+	}
+	l.broker.Publish(broadcast.Message{TenantID: n.TenantID, Event: n.Type, Data: n.Data})
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// OutboxRepository records events for webhook delivery. Changes that come with
+// an event record it in their own transaction instead, see insertEvent.
+type OutboxRepository struct {
+	db *DB
+}
+
+func NewOutboxRepository(db *DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Record stores an event of the tenant from ctx, with data as its JSON payload.
+// Unlike the events of changes, the event is only stored when an active
+// subscription of the tenant wants it, since it is recorded for every request.
+func (r *OutboxRepository) Record(ctx context.Context, eventType string, data interface{}) (err error) {
+	ctx, span := startSpan(ctx, "OutboxRepository.Record")
+	defer func() { endSpan(span, err) }()
+
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	query := `
+		INSERT INTO outbox_events (tenant_id, event_type, payload)
+		SELECT $1, $2, $3
+		WHERE EXISTS (
+			SELECT 1 FROM webhook_subscriptions
+			WHERE tenant_id = $1 AND active AND $2 = ANY(string_to_array(events, ','))
+		)`
+	if _, err := r.db.ExecContext(ctx, query, t.ID, eventType, string(payload)); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// insertEvent adds an event to the outbox. Run on the transaction of a change,
+// the event is committed if and only if the change is.
+func insertEvent(ctx context.Context, q querier, tenantID int, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	query := `INSERT INTO outbox_events (tenant_id, event_type, payload) VALUES ($1, $2, $3)`
+	if _, err := q.ExecContext(ctx, query, tenantID, eventType, string(payload)); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// packSizeEvent is the payload of pack size events, shaped like the pack size
+// resources of the API
+type packSizeEvent struct {
+	ID        int    `json:"id"`
+	Size      int    `json:"size"`
+	Active    bool   `json:"active"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// newPackSizeEvent returns the event payload of ps
+func newPackSizeEvent(ps *PackSize) packSizeEvent {
+	return packSizeEvent{
+		ID:        ps.ID,
+		Size:      ps.Size,
+		Active:    ps.Active,
+		CreatedAt: ps.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: ps.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// insertPackSizeEvent records a pack size event for ps
+func insertPackSizeEvent(ctx context.Context, q querier, eventType string, ps *PackSize) error {
+	return insertEvent(ctx, q, ps.TenantID, eventType, newPackSizeEvent(ps))
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a pagination cursor that is malformed or
+// was issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// packSizeSortColumns are the columns a pack size listing can be sorted by
+var packSizeSortColumns = map[string]string{
+	"size":       "size",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"id":         "id",
+}
+
+// PackSizeSort orders a pack size listing. Ties are broken by ID, in the same direction.
+type PackSizeSort struct {
+	Field      string
+	Descending bool
+}
+
+// ParsePackSizeSort parses a sort parameter such as "size" or "-created_at",
+// where a leading dash sorts in descending order. An empty value sorts by size.
+func ParsePackSizeSort(value string) (PackSizeSort, error) {
+	sort := PackSizeSort{Field: strings.TrimPrefix(value, "-"), Descending: strings.HasPrefix(value, "-")}
+	if sort.Field == "" {
+		sort.Field = "size"
+	}
+	if _, ok := packSizeSortColumns[sort.Field]; !ok {
+		return PackSizeSort{}, fmt.Errorf("unsupported sort field '%s': must be one of size, created_at, updated_at, id", sort.Field)
+	}
+	return sort, nil
+}
+
+func (s PackSizeSort) String() string {
+	if s.Descending {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// PackSizeQuery filters, sorts and pages a pack size listing. Zero values
+// leave a filter unset.
+type PackSizeQuery struct {
+	MinSize      int
+	MaxSize      int
+	CreatedSince time.Time
+	UpdatedSince time.Time
+	Active       *bool
+	Sort         PackSizeSort
+	// Limit is the page size; 0 returns all matching pack sizes
+	Limit int
+	// After continues a listing after the last pack size of a previous page
+	After *PackSizeCursor
+}
+
+// PackSizeCursor marks the position of a pack size in a sorted listing
+type PackSizeCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// PackSizePage is one page of a pack size listing. Next is nil on the last page.
+type PackSizePage struct {
+	PackSizes []PackSize
+	Next      *PackSizeCursor
+}
+
+// Encode returns the cursor as an opaque, URL safe string
+func (c *PackSizeCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePackSizeCursor parses a cursor returned by Encode
+func DecodePackSizeCursor(value string) (*PackSizeCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor PackSizeCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// newPackSizeCursor returns the cursor positioned at ps for the sort order
+func newPackSizeCursor(ps PackSize, sort PackSizeSort) *PackSizeCursor {
+	cursor := &PackSizeCursor{Sort: sort.String(), ID: ps.ID}
+	switch sort.Field {
+	case "size":
+		cursor.Value = strconv.Itoa(ps.Size)
+	case "created_at":
+		cursor.Value = ps.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = ps.UpdatedAt.Format(time.RFC3339Nano)
+	case "id":
+		cursor.Value = strconv.Itoa(ps.ID)
+	}
+	return cursor
+}
+
+// cursorValue converts the cursor value back to the type of the sort column
+func (c *PackSizeCursor) cursorValue(sort PackSizeSort) (interface{}, error) {
+	if c.Sort != sort.String() {
+		return nil, fmt.Errorf("%w: issued for sort '%s'", ErrInvalidCursor, c.Sort)
+	}
+	switch sort.Field {
+	case "created_at", "updated_at":
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	default:
+		n, err := strconv.Atoi(c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return n, nil
+	}
+}
+
+// buildListQuery builds the SQL and arguments for a pack size listing. One row
+// more than the limit is fetched to tell whether another page follows.
+func buildListQuery(query PackSizeQuery, tenantID int) (string, []interface{}, error) {
+	sort := query.Sort
+	if sort.Field == "" {
+		sort.Field = "size"
+	}
+	column, ok := packSizeSortColumns[sort.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported sort field '%s'", sort.Field)
+	}
+
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+	where := func(condition string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			placeholders[i] = len(args)
+		}
+		conditions = append(conditions, fmt.Sprintf(condition, placeholders...))
+	}
+
+	if query.MinSize > 0 {
+		where("size >= $%d", query.MinSize)
+	}
+	if query.MaxSize > 0 {
+		where("size <= $%d", query.MaxSize)
+	}
+	if !query.CreatedSince.IsZero() {
+		where("created_at >= $%d", query.CreatedSince)
+	}
+	if !query.UpdatedSince.IsZero() {
+		where("updated_at >= $%d", query.UpdatedSince)
+	}
+	if query.Active != nil {
+		where("active = $%d", *query.Active)
+	}
+
+	direction, comparison := "ASC", ">"
+	if sort.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if query.After != nil {
+		value, err := query.After.cursorValue(sort)
+		if err != nil {
+			return "", nil, err
+		}
+		where("("+column+", id) "+comparison+" ($%d, $%d)", value, query.After.ID)
+	}
+
+	sql := `SELECT ` + packSizeColumns + ` FROM pack_sizes WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY ` + column + ` ` + direction + `, id ` + direction
+	if query.Limit > 0 {
+		args = append(args, query.Limit+1)
+		sql += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	return sql, args, nil
+}
+
+// List returns one page of the pack sizes matching the query
+func (r *PackSizeRepository) List(ctx context.Context, query PackSizeQuery) (*PackSizePage, error) {
+	if query.Sort.Field == "" {
+		query.Sort.Field = "size"
+	}
+
+	page := &PackSizePage{PackSizes: []PackSize{}}
+	err := r.scoped(ctx, "List", func(ctx context.Context, q querier, tenantID int) error {
+		sql, args, err := buildListQuery(query, tenantID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := q.QueryContext(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query pack sizes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var ps PackSize
+			if err := scanPackSize(rows, &ps); err != nil {
+				return fmt.Errorf("failed to scan pack size: %w", err)
+			}
+			page.PackSizes = append(page.PackSizes, ps)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating pack sizes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Limit > 0 && len(page.PackSizes) > query.Limit {
+		page.PackSizes = page.PackSizes[:query.Limit]
+		page.Next = newPackSizeCursor(page.PackSizes[query.Limit-1], query.Sort)
+	}
+
+	return page, nil
+}
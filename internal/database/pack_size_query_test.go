@@ -0,0 +1,125 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePackSizeSort(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    PackSizeSort
+		expectError bool
+	}{
+		{value: "", expected: PackSizeSort{Field: "size"}},
+		{value: "size", expected: PackSizeSort{Field: "size"}},
+		{value: "-created_at", expected: PackSizeSort{Field: "created_at", Descending: true}},
+		{value: "-", expected: PackSizeSort{Field: "size", Descending: true}},
+		{value: "name", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			sort, err := ParsePackSizeSort(tt.value)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", sort)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sort != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, sort)
+			}
+		})
+	}
+}
+
+func TestBuildListQuery(t *testing.T) {
+	active := true
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		query        PackSizeQuery
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "Defaults",
+			query:        PackSizeQuery{},
+			expectedSQL:  `SELECT ` + packSizeColumns + ` FROM pack_sizes WHERE tenant_id = $1 ORDER BY size ASC, id ASC`,
+			expectedArgs: []interface{}{7},
+		},
+		{
+			name: "Filters and limit",
+			query: PackSizeQuery{
+				MinSize:      100,
+				MaxSize:      1000,
+				UpdatedSince: since,
+				Active:       &active,
+				Sort:         PackSizeSort{Field: "updated_at", Descending: true},
+				Limit:        10,
+			},
+			expectedSQL: `SELECT ` + packSizeColumns + ` FROM pack_sizes WHERE tenant_id = $1 AND size >= $2 AND size <= $3` +
+				` AND updated_at >= $4 AND active = $5 ORDER BY updated_at DESC, id DESC LIMIT $6`,
+			expectedArgs: []interface{}{7, 100, 1000, since, true, 11},
+		},
+		{
+			name: "After cursor",
+			query: PackSizeQuery{
+				Sort:  PackSizeSort{Field: "created_at"},
+				Limit: 2,
+				After: newPackSizeCursor(PackSize{ID: 3, CreatedAt: createdAt}, PackSizeSort{Field: "created_at"}),
+			},
+			expectedSQL: `SELECT ` + packSizeColumns + ` FROM pack_sizes WHERE tenant_id = $1 AND (created_at, id) > ($2, $3)` +
+				` ORDER BY created_at ASC, id ASC LIMIT $4`,
+			expectedArgs: []interface{}{7, createdAt, 3, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := buildListQuery(tt.query, 7)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sql != tt.expectedSQL {
+				t.Errorf("expected SQL\n%s\ngot\n%s", tt.expectedSQL, sql)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("expected args %v, got %v", tt.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestBuildListQuery_CursorForOtherSort(t *testing.T) {
+	cursor := newPackSizeCursor(PackSize{ID: 1, Size: 250}, PackSizeSort{Field: "size"})
+	query := PackSizeQuery{Sort: PackSizeSort{Field: "size", Descending: true}, After: cursor}
+
+	if _, _, err := buildListQuery(query, 1); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestPackSizeCursor_RoundTrip(t *testing.T) {
+	cursor := newPackSizeCursor(PackSize{ID: 4, Size: 500}, PackSizeSort{Field: "size", Descending: true})
+
+	decoded, err := DecodePackSizeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *decoded != *cursor {
+		t.Errorf("expected %+v, got %+v", cursor, decoded)
+	}
+
+	if _, err := DecodePackSizeCursor("%%%"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
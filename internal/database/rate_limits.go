@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/ratelimit"
+)
+
+// rateLimitRetention is how long idle buckets are kept before they are deleted
+const rateLimitRetention = time.Hour
+
+// RateLimitStore keeps rate limit buckets in Postgres so that all replicas share them
+type RateLimitStore struct {
+	db *DB
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+	cleanups    sync.WaitGroup
+}
+
+func NewRateLimitStore(db *DB) *RateLimitStore {
+	return &RateLimitStore{db: db, lastCleanup: time.Now()}
+}
+
+// Take removes cost tokens from the bucket with the given key. The bucket row is
+// locked for the duration of the update, and the database clock is used so that
+// replicas with skewed clocks agree on refills.
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, cost float64) (ratelimit.Result, error) {
+	s.cleanup()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		VALUES ($1, $2, clock_timestamp())
+		ON CONFLICT (key) DO NOTHING
+	`, key, limit.Burst)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to create rate limit bucket: %w", err)
+	}
+
+	var tokens, elapsed float64
+	var now time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT tokens, EXTRACT(EPOCH FROM clock_timestamp() - updated_at)::DOUBLE PRECISION, clock_timestamp()
+		FROM rate_limit_buckets WHERE key = $1
+		FOR UPDATE
+	`, key).Scan(&tokens, &elapsed, &now)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to read rate limit bucket: %w", err)
+	}
+
+	tokens, result := ratelimit.Take(tokens, time.Duration(elapsed*float64(time.Second)), limit, cost)
+
+	_, err = tx.ExecContext(ctx, `UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1`, key, tokens, now)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to commit rate limit bucket: %w", err)
+	}
+
+	return result, nil
+}
+
+// cleanup deletes idle buckets in the background, at most once per retention period
+func (s *RateLimitStore) cleanup() {
+	s.mu.Lock()
+	if time.Since(s.lastCleanup) < rateLimitRetention {
+		s.mu.Unlock()
+		return
+	}
+	s.lastCleanup = time.Now()
+	s.mu.Unlock()
+
+	s.cleanups.Add(1)
+	go func() {
+		defer s.cleanups.Done()
+		query := `DELETE FROM rate_limit_buckets WHERE updated_at < clock_timestamp() - $1 * INTERVAL '1 second'`
+		if _, err := s.db.Exec(query, rateLimitRetention.Seconds()); err != nil {
+			slog.Error("Failed to delete idle rate limit buckets", "error", err)
+		}
+	}()
+}
+
+// Wait blocks until running cleanups have finished, so the database can be closed
+func (s *RateLimitStore) Wait() {
+	s.cleanups.Wait()
+}
@@ -1,10 +1,40 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/tenant"
+	"github.com/miloradbozic/packing-service/internal/tracing"
+	"github.com/miloradbozic/packing-service/internal/webhooks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/miloradbozic/packing-service/internal/database")
+
+// ErrNoTenant is returned when a tenant scoped query runs without a tenant in the context
+var ErrNoTenant = errors.New("no tenant in context")
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// packSizeColumns are selected by every pack size query, in the order scanPackSize expects
+const packSizeColumns = `id, tenant_id, size, active, created_at, updated_at`
+
+func scanPackSize(row rowScanner, ps *PackSize) error {
+	return row.Scan(&ps.ID, &ps.TenantID, &ps.Size, &ps.Active, &ps.CreatedAt, &ps.UpdatedAt)
+}
+
 type PackSizeRepository struct {
 	db *DB
 }
@@ -13,96 +43,306 @@ func NewPackSizeRepository(db *DB) *PackSizeRepository {
 	return &PackSizeRepository{db: db}
 }
 
+// scoped runs fn with the tenant from ctx, in a span named after the repository
+// method. With row-level security enabled fn runs inside a transaction that has
+// app.tenant_id set, otherwise directly on the pool.
+func (r *PackSizeRepository) scoped(ctx context.Context, method string, fn func(ctx context.Context, q querier, tenantID int) error) error {
+	return r.run(ctx, method, r.db.rowLevelSecurity, fn)
+}
+
+// scopedTx is like scoped, but always runs fn in a transaction, so that a change
+// and the outbox event recording it are committed together
+func (r *PackSizeRepository) scopedTx(ctx context.Context, method string, fn func(ctx context.Context, q querier, tenantID int) error) error {
+	return r.run(ctx, method, true, fn)
+}
 
-// GetAll returns all pack sizes
-func (r *PackSizeRepository) GetAll() ([]PackSize, error) {
-	query := `SELECT id, size, created_at, updated_at FROM pack_sizes ORDER BY size ASC`
-	
-	rows, err := r.db.Query(query)
+func (r *PackSizeRepository) run(ctx context.Context, method string, inTx bool, fn func(ctx context.Context, q querier, tenantID int) error) (err error) {
+	ctx, span := startSpan(ctx, "PackSizeRepository."+method)
+	defer func() { endSpan(span, err) }()
+
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
+	if !inTx {
+		return fn(ctx, r.db, t.ID)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query pack sizes: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var packSizes []PackSize
-	for rows.Next() {
-		var ps PackSize
-		if err := rows.Scan(&ps.ID, &ps.Size, &ps.CreatedAt, &ps.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan pack size: %w", err)
+	if r.db.rowLevelSecurity {
+		if err := setTenant(ctx, tx, t.ID); err != nil {
+			return err
 		}
-		packSizes = append(packSizes, ps)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating pack sizes: %w", err)
+	if err := fn(ctx, tx, t.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// startSpan starts the span of a repository method; the SQL statements it runs
+// get child spans from the traced driver
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// endSpan ends span, marking it failed unless err is nil or a missing row
+func endSpan(span trace.Span, err error) {
+	if !errors.Is(err, ErrNotFound) {
+		tracing.RecordError(span, err)
+	}
+	span.End()
+}
+
+// setTenant sets the tenant used by the row-level security policies for the rest of tx
+func setTenant(ctx context.Context, tx *sql.Tx, tenantID int) error {
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, strconv.Itoa(tenantID)); err != nil {
+		return fmt.Errorf("failed to set tenant: %w", err)
+	}
+	return nil
+}
+
+// GetAll returns all pack sizes
+func (r *PackSizeRepository) GetAll(ctx context.Context) ([]PackSize, error) {
+	query := `SELECT ` + packSizeColumns + ` FROM pack_sizes WHERE tenant_id = $1 ORDER BY size ASC`
+
+	var packSizes []PackSize
+	err := r.scoped(ctx, "GetAll", func(ctx context.Context, q querier, tenantID int) error {
+		rows, err := q.QueryContext(ctx, query, tenantID)
+		if err != nil {
+			return fmt.Errorf("failed to query pack sizes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var ps PackSize
+			if err := scanPackSize(rows, &ps); err != nil {
+				return fmt.Errorf("failed to scan pack size: %w", err)
+			}
+			packSizes = append(packSizes, ps)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating pack sizes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return packSizes, nil
 }
 
 // GetByID returns a pack size by ID
-func (r *PackSizeRepository) GetByID(id int) (*PackSize, error) {
-	query := `SELECT id, size, created_at, updated_at FROM pack_sizes WHERE id = $1`
-	
+func (r *PackSizeRepository) GetByID(ctx context.Context, id int) (*PackSize, error) {
+	query := `SELECT ` + packSizeColumns + ` FROM pack_sizes WHERE id = $1 AND tenant_id = $2`
+
 	var ps PackSize
-	err := r.db.QueryRow(query, id).Scan(&ps.ID, &ps.Size, &ps.CreatedAt, &ps.UpdatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("pack size with id %d not found", id)
+	err := r.scoped(ctx, "GetByID", func(ctx context.Context, q querier, tenantID int) error {
+		err := scanPackSize(q.QueryRowContext(ctx, query, id, tenantID), &ps)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return notFound("pack size", id)
+			}
+			return fmt.Errorf("failed to get pack size: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get pack size: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &ps, nil
 }
 
-// Create creates a new pack size
-func (r *PackSizeRepository) Create(size int) (*PackSize, error) {
-	query := `INSERT INTO pack_sizes (size) VALUES ($1) RETURNING id, size, created_at, updated_at`
-	
+// Create creates a new pack size and records a pack_size.created event
+func (r *PackSizeRepository) Create(ctx context.Context, size int, active bool) (*PackSize, error) {
+	query := `INSERT INTO pack_sizes (tenant_id, size, active) VALUES ($1, $2, $3) RETURNING ` + packSizeColumns
+
 	var ps PackSize
-	err := r.db.QueryRow(query, size).Scan(&ps.ID, &ps.Size, &ps.CreatedAt, &ps.UpdatedAt)
+	err := r.scopedTx(ctx, "Create", func(ctx context.Context, q querier, tenantID int) error {
+		err := scanPackSize(q.QueryRowContext(ctx, query, tenantID, size, active), &ps)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: %d", ErrDuplicateSize, size)
+			}
+			return fmt.Errorf("failed to create pack size: %w", err)
+		}
+		return recordPackSizeChange(ctx, q, webhooks.EventPackSizeCreated, &ps)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create pack size: %w", err)
+		return nil, err
 	}
 
 	return &ps, nil
 }
 
-// Update updates an existing pack size
-func (r *PackSizeRepository) Update(id int, size int) (*PackSize, error) {
-	query := `UPDATE pack_sizes SET size = $1 WHERE id = $2 RETURNING id, size, created_at, updated_at`
-	
+// Update updates an existing pack size and records a pack_size.updated event.
+// A nil active leaves the flag unchanged.
+func (r *PackSizeRepository) Update(ctx context.Context, id int, size int, active *bool) (*PackSize, error) {
+	query := `UPDATE pack_sizes SET size = $1, active = COALESCE($2, active) WHERE id = $3 AND tenant_id = $4 RETURNING ` + packSizeColumns
+
 	var ps PackSize
-	err := r.db.QueryRow(query, size, id).Scan(&ps.ID, &ps.Size, &ps.CreatedAt, &ps.UpdatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("pack size with id %d not found", id)
+	err := r.scopedTx(ctx, "Update", func(ctx context.Context, q querier, tenantID int) error {
+		err := scanPackSize(q.QueryRowContext(ctx, query, size, nullBool(active), id, tenantID), &ps)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return notFound("pack size", id)
+			}
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: %d", ErrDuplicateSize, size)
+			}
+			return fmt.Errorf("failed to update pack size: %w", err)
 		}
-		return nil, fmt.Errorf("failed to update pack size: %w", err)
+		return recordPackSizeChange(ctx, q, webhooks.EventPackSizeUpdated, &ps)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &ps, nil
 }
 
-// Delete deletes a pack size (hard delete - removes from database)
-func (r *PackSizeRepository) Delete(id int) error {
-	query := `DELETE FROM pack_sizes WHERE id = $1`
-	
-	result, err := r.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete pack size: %w", err)
+// Delete deletes a pack size (hard delete - removes from database) and records
+// a pack_size.deleted event
+func (r *PackSizeRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM pack_sizes WHERE id = $1 AND tenant_id = $2 RETURNING ` + packSizeColumns
+
+	return r.scopedTx(ctx, "Delete", func(ctx context.Context, q querier, tenantID int) error {
+		var ps PackSize
+		err := scanPackSize(q.QueryRowContext(ctx, query, id, tenantID), &ps)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return notFound("pack size", id)
+			}
+			return fmt.Errorf("failed to delete pack size: %w", err)
+		}
+		return recordPackSizeChange(ctx, q, webhooks.EventPackSizeDeleted, &ps)
+	})
+}
+
+// Import writes a set of pack sizes in a single transaction. New sizes are
+// inserted with their original timestamps; sizes that already exist only take
+// the imported active flag. When replace is true, any pack size not present in
+// the set is deleted. Every inserted, updated and deleted pack size records an
+// event.
+func (r *PackSizeRepository) Import(ctx context.Context, packSizes []PackSize, replace bool) (err error) {
+	ctx, span := startSpan(ctx, "PackSizeRepository.Import")
+	defer func() { endSpan(span, err) }()
+
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return ErrNoTenant
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to begin import: %w", err)
+	}
+	defer tx.Rollback()
+
+	if r.db.rowLevelSecurity {
+		if err := setTenant(ctx, tx, t.ID); err != nil {
+			return err
+		}
+	}
+
+	if replace {
+		query := `DELETE FROM pack_sizes WHERE tenant_id = $1`
+		args := []interface{}{t.ID}
+		returning := ` RETURNING ` + packSizeColumns
+		if len(packSizes) > 0 {
+			placeholders := make([]string, len(packSizes))
+			for i, ps := range packSizes {
+				placeholders[i] = fmt.Sprintf("$%d", i+2)
+				args = append(args, ps.Size)
+			}
+			query += ` AND size NOT IN (` + strings.Join(placeholders, ", ") + `)`
+		}
+		deleted, err := queryPackSizes(ctx, tx, query+returning, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete pack sizes: %w", err)
+		}
+		for i := range deleted {
+			if err := recordPackSizeChange(ctx, tx, webhooks.EventPackSizeDeleted, &deleted[i]); err != nil {
+				return err
+			}
+		}
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("pack size with id %d not found", id)
+	// Existing rows are only updated when their active flag differs, and xmax is
+	// zero for rows the statement inserted
+	query := `
+		INSERT INTO pack_sizes (tenant_id, size, active, created_at, updated_at)
+		VALUES ($1, $2, $3, COALESCE($4, CURRENT_TIMESTAMP), COALESCE($5, CURRENT_TIMESTAMP))
+		ON CONFLICT (tenant_id, size) DO UPDATE SET active = EXCLUDED.active
+		WHERE pack_sizes.active IS DISTINCT FROM EXCLUDED.active
+		RETURNING ` + packSizeColumns + `, xmax = 0`
+	for _, ps := range packSizes {
+		var written PackSize
+		var inserted bool
+		err := tx.QueryRowContext(ctx, query, t.ID, ps.Size, ps.Active, nullTime(ps.CreatedAt), nullTime(ps.UpdatedAt)).Scan(
+			&written.ID, &written.TenantID, &written.Size, &written.Active, &written.CreatedAt, &written.UpdatedAt, &inserted)
+		if err == sql.ErrNoRows {
+			// The size already exists with the same active flag
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import pack size %d: %w", ps.Size, err)
+		}
+		eventType := webhooks.EventPackSizeUpdated
+		if inserted {
+			eventType = webhooks.EventPackSizeCreated
+		}
+		if err := recordPackSizeChange(ctx, tx, eventType, &written); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
 	}
 
 	return nil
 }
 
+// queryPackSizes runs a query that returns pack size rows
+func queryPackSizes(ctx context.Context, q querier, query string, args ...interface{}) ([]PackSize, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var packSizes []PackSize
+	for rows.Next() {
+		var ps PackSize
+		if err := scanPackSize(rows, &ps); err != nil {
+			return nil, err
+		}
+		packSizes = append(packSizes, ps)
+	}
+	return packSizes, rows.Err()
+}
+
+// nullBool maps nil to NULL
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
+
+// nullTime maps the zero time to NULL so the column default applies
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
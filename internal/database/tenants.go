@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrTenantNotFound is returned when no tenant matches the requested slug
+var ErrTenantNotFound = fmt.Errorf("tenant %w", ErrNotFound)
+
+// ErrDuplicateTenant is returned when a tenant with the same slug already exists
+var ErrDuplicateTenant = errors.New("tenant already exists")
+
+type TenantRepository struct {
+	db *DB
+}
+
+func NewTenantRepository(db *DB) *TenantRepository {
+	return &TenantRepository{db: db}
+}
+
+// GetAll returns all tenants
+func (r *TenantRepository) GetAll(ctx context.Context) ([]Tenant, error) {
+	query := `SELECT id, slug, name, created_at FROM tenants ORDER BY slug ASC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []Tenant
+	for rows.Next() {
+		var t Tenant
+		if err := rows.Scan(&t.ID, &t.Slug, &t.Name, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tenants: %w", err)
+	}
+
+	return tenants, nil
+}
+
+// GetBySlug returns a tenant by its slug
+func (r *TenantRepository) GetBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	query := `SELECT id, slug, name, created_at FROM tenants WHERE slug = $1`
+
+	var t Tenant
+	err := r.db.QueryRowContext(ctx, query, slug).Scan(&t.ID, &t.Slug, &t.Name, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("tenant '%s': %w", slug, ErrTenantNotFound)
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	return &t, nil
+}
+
+// Create creates a tenant and seeds it with the given pack sizes in one transaction
+func (r *TenantRepository) Create(ctx context.Context, slug, name string, packSizes []int) (*Tenant, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var t Tenant
+	query := `INSERT INTO tenants (slug, name) VALUES ($1, $2) RETURNING id, slug, name, created_at`
+	if err := tx.QueryRowContext(ctx, query, slug, name).Scan(&t.ID, &t.Slug, &t.Name, &t.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("tenant '%s': %w", slug, ErrDuplicateTenant)
+		}
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	// The row-level security policies only accept rows for the current tenant
+	if r.db.rowLevelSecurity {
+		if err := setTenant(ctx, tx, t.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, size := range packSizes {
+		_, err := tx.ExecContext(ctx, `INSERT INTO pack_sizes (tenant_id, size) VALUES ($1, $2) ON CONFLICT (tenant_id, size) DO NOTHING`, t.ID, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed pack size %d: %w", size, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit tenant: %w", err)
+	}
+
+	return &t, nil
+}
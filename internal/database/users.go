@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ErrUserNotFound is returned when no user matches the username
+var ErrUserNotFound = fmt.Errorf("user %w", ErrNotFound)
+
+const userColumns = `u.id, u.tenant_id, t.slug, u.username, u.password_hash, u.scopes, u.created_at, u.updated_at`
+
+type UserRepository struct {
+	db *DB
+}
+
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var user User
+	var scopes string
+	if err := row.Scan(&user.ID, &user.TenantID, &user.TenantSlug, &user.Username, &user.PasswordHash, &scopes, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	user.Scopes = strings.Split(scopes, ",")
+	return &user, nil
+}
+
+// GetByUsername returns the user with the given username, across all tenants
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users u JOIN tenants t ON t.id = u.tenant_id WHERE u.username = $1`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, username))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user '%s': %w", username, ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// Upsert creates a user or resets the tenant, password and scopes of an existing one
+func (r *UserRepository) Upsert(ctx context.Context, tenantID int, username, passwordHash string, scopes []string) (*User, error) {
+	query := `
+		WITH u AS (
+			INSERT INTO users (tenant_id, username, password_hash, scopes)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (username) DO UPDATE SET
+				tenant_id = EXCLUDED.tenant_id,
+				password_hash = EXCLUDED.password_hash,
+				scopes = EXCLUDED.scopes,
+				updated_at = CURRENT_TIMESTAMP
+			RETURNING *
+		)
+		SELECT ` + userColumns + ` FROM u JOIN tenants t ON t.id = u.tenant_id
+	`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, tenantID, username, passwordHash, strings.Join(scopes, ",")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/webhooks"
+)
+
+// WebhookStore is the webhooks.Store of the dispatcher. It works across
+// tenants, and locks rows with SKIP LOCKED so that replicas share the work.
+type WebhookStore struct {
+	db *DB
+}
+
+func NewWebhookStore(db *DB) *WebhookStore {
+	return &WebhookStore{db: db}
+}
+
+// DispatchEvents creates the deliveries of up to limit undispatched events
+func (s *WebhookStore) DispatchEvents(ctx context.Context, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, tenant_id, event_type FROM outbox_events
+		WHERE dispatched_at IS NULL
+		ORDER BY id LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query outbox events: %w", err)
+	}
+
+	type event struct {
+		id        int64
+		tenantID  int
+		eventType string
+	}
+	var events []event
+	for rows.Next() {
+		var e event
+		if err := rows.Scan(&e.id, &e.tenantID, &e.eventType); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+
+	for _, e := range events {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (subscription_id, event_id)
+			SELECT id, $1 FROM webhook_subscriptions
+			WHERE tenant_id = $2 AND active AND $3 = ANY(string_to_array(events, ','))
+			ON CONFLICT (subscription_id, event_id) DO NOTHING
+		`, e.id, e.tenantID, e.eventType)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create deliveries of event %d: %w", e.id, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE outbox_events SET dispatched_at = CURRENT_TIMESTAMP WHERE id = $1`, e.id); err != nil {
+			return 0, fmt.Errorf("failed to mark event %d dispatched: %w", e.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit dispatched events: %w", err)
+	}
+	return len(events), nil
+}
+
+// ClaimDeliveries returns due deliveries of active subscriptions and postpones
+// their next attempt by lease
+func (s *WebhookStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhooks.Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second', updated_at = CURRENT_TIMESTAMP
+		FROM webhook_subscriptions s, outbox_events e, tenants t
+		WHERE d.id IN (
+			SELECT due.id FROM webhook_deliveries due
+			JOIN webhook_subscriptions active ON active.id = due.subscription_id
+			WHERE due.status = '`+webhooks.StatusPending+`' AND due.next_attempt_at <= CURRENT_TIMESTAMP AND active.active
+			ORDER BY due.next_attempt_at LIMIT $1
+			FOR UPDATE OF due SKIP LOCKED
+		)
+		AND s.id = d.subscription_id AND e.id = d.event_id AND t.id = e.tenant_id
+		RETURNING d.id, d.subscription_id, s.url, s.secret, e.id, e.event_type, t.slug, e.payload, e.created_at, d.attempts
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []webhooks.Delivery
+	for rows.Next() {
+		var d webhooks.Delivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.Secret, &d.EventID, &d.EventType, &d.Tenant, &payload, &d.CreatedAt, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt
+func (s *WebhookStore) RecordAttempt(ctx context.Context, attempt webhooks.Attempt) error {
+	nextAttemptAt := attempt.NextAttemptAt
+	if nextAttemptAt.IsZero() {
+		nextAttemptAt = time.Now()
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5, last_error = $6,
+			delivered_at = CASE WHEN $2 = '`+webhooks.StatusSucceeded+`' THEN CURRENT_TIMESTAMP ELSE delivered_at END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, attempt.DeliveryID, attempt.Status, attempt.Attempts, nextAttemptAt,
+		sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: attempt.StatusCode != 0},
+		sql.NullString{String: attempt.Error, Valid: attempt.Error != ""})
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// Cleanup deletes dispatched events recorded before the given time that have
+// no pending deliveries of active subscriptions left. Their deliveries are
+// deleted with them, including the pending ones of inactive subscriptions,
+// which would otherwise keep their events forever.
+func (s *WebhookStore) Cleanup(ctx context.Context, before time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM outbox_events e
+		WHERE e.dispatched_at IS NOT NULL AND e.created_at < $1
+		AND NOT EXISTS (
+			SELECT 1 FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.event_id = e.id AND d.status = '`+webhooks.StatusPending+`' AND s.active
+		)
+	`, before)
+	if err != nil {
+		return fmt.Errorf("failed to delete old outbox events: %w", err)
+	}
+	return nil
+}
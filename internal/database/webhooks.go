@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/miloradbozic/packing-service/internal/tenant"
+	"github.com/miloradbozic/packing-service/internal/webhooks"
+)
+
+const webhookColumns = `id, tenant_id, url, secret, events, active, created_at, updated_at`
+
+const webhookDeliveryColumns = `d.id, d.subscription_id, d.event_id, e.event_type, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+// WebhookRepository manages the webhook subscriptions of the tenant in the
+// context and their delivery logs
+type WebhookRepository struct {
+	db *DB
+}
+
+func NewWebhookRepository(db *DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func scanWebhook(row rowScanner) (*WebhookSubscription, error) {
+	var w WebhookSubscription
+	var events string
+	if err := row.Scan(&w.ID, &w.TenantID, &w.URL, &w.Secret, &events, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Events = strings.Split(events, ",")
+	return &w, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	var statusCode sql.NullInt64
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+	if err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt, &statusCode, &lastError, &d.CreatedAt, &deliveredAt); err != nil {
+		return nil, err
+	}
+	d.LastStatusCode = int(statusCode.Int64)
+	d.LastError = lastError.String
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+// GetAll returns all webhook subscriptions of the tenant
+func (r *WebhookRepository) GetAll(ctx context.Context) ([]WebhookSubscription, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE tenant_id = $1 ORDER BY id ASC`
+	rows, err := r.db.QueryContext(ctx, query, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []WebhookSubscription
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		subscriptions = append(subscriptions, *w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// GetByID returns a webhook subscription of the tenant
+func (r *WebhookRepository) GetByID(ctx context.Context, id int) (*WebhookSubscription, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`
+	w, err := scanWebhook(r.db.QueryRowContext(ctx, query, id, t.ID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("webhook", id)
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return w, nil
+}
+
+// Create subscribes url to the given event types
+func (r *WebhookRepository) Create(ctx context.Context, url, secret string, events []string, active bool) (*WebhookSubscription, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	query := `INSERT INTO webhook_subscriptions (tenant_id, url, secret, events, active) VALUES ($1, $2, $3, $4, $5) RETURNING ` + webhookColumns
+	w, err := scanWebhook(r.db.QueryRowContext(ctx, query, t.ID, url, secret, strings.Join(events, ","), active))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return w, nil
+}
+
+// Update changes the URL, event types and active flag of a subscription. A nil
+// active leaves the flag unchanged.
+func (r *WebhookRepository) Update(ctx context.Context, id int, url string, events []string, active *bool) (*WebhookSubscription, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	query := `UPDATE webhook_subscriptions SET url = $1, events = $2, active = COALESCE($3, active) WHERE id = $4 AND tenant_id = $5 RETURNING ` + webhookColumns
+	w, err := scanWebhook(r.db.QueryRowContext(ctx, query, url, strings.Join(events, ","), nullBool(active), id, t.ID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("webhook", id)
+		}
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return w, nil
+}
+
+// Delete removes a subscription together with its delivery log
+func (r *WebhookRepository) Delete(ctx context.Context, id int) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`, id, t.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return notFound("webhook", id)
+	}
+	return nil
+}
+
+// Deliveries returns the newest deliveries of a subscription, optionally only
+// those with the given status
+func (r *WebhookRepository) Deliveries(ctx context.Context, subscriptionID int, status string, limit int) ([]WebhookDelivery, error) {
+	if _, err := r.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries d JOIN outbox_events e ON e.id = d.event_id
+		WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.id DESC LIMIT $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery queues a delivery of the subscription to be sent again right
+// away with a fresh set of attempts, typically after it went dead
+func (r *WebhookRepository) RetryDelivery(ctx context.Context, subscriptionID int, deliveryID int64) (*WebhookDelivery, error) {
+	if _, err := r.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH d AS (
+			UPDATE webhook_deliveries
+			SET status = '` + webhooks.StatusPending + `', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND subscription_id = $2
+			RETURNING *
+		)
+		SELECT ` + webhookDeliveryColumns + ` FROM d JOIN outbox_events e ON e.id = d.event_id
+	`
+	d, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, deliveryID, subscriptionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery with id %d: %w", deliveryID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to retry webhook delivery: %w", err)
+	}
+	return d, nil
+}
@@ -1,8 +1,9 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,31 +12,33 @@ import (
 	"github.com/miloradbozic/packing-service/internal/database"
 	"github.com/miloradbozic/packing-service/internal/models"
 	"github.com/miloradbozic/packing-service/internal/service"
+	"github.com/miloradbozic/packing-service/internal/transfer"
 )
 
 type APIHandler struct {
 	service      *service.PackingService
 	packSizeRepo database.PackSizeRepositoryInterface
+	validator    *RequestValidator
 }
 
-func NewAPIHandler(packingService *service.PackingService, packSizeRepo database.PackSizeRepositoryInterface) *APIHandler {
+func NewAPIHandler(packingService *service.PackingService, packSizeRepo database.PackSizeRepositoryInterface, validator *RequestValidator) *APIHandler {
 	return &APIHandler{
 		service:      packingService,
 		packSizeRepo: packSizeRepo,
+		validator:    validator,
 	}
 }
 
 func (h *APIHandler) Calculate(w http.ResponseWriter, r *http.Request) {
 	var req models.CalculateRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !h.validator.Decode(w, r, &req) {
 		return
 	}
 
-	solution, err := h.service.CalculatePacks(req.Items)
+	solution, err := h.service.CalculatePacks(r.Context(), req.Items)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		writeErrorFor(w, r, err, "Failed to calculate packs")
 		return
 	}
 
@@ -62,9 +65,9 @@ func (h *APIHandler) Calculate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *APIHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
-	packSizes, err := h.service.GetPackSizes()
+	packSizes, err := h.service.GetPackSizes(r.Context())
 	if err != nil {
-		h.sendError(w, "Failed to get pack sizes", http.StatusInternalServerError)
+		writeErrorFor(w, r, err, "Failed to get pack sizes")
 		return
 	}
 
@@ -75,149 +78,212 @@ func (h *APIHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *APIHandler) sendError(w http.ResponseWriter, message string, status int) {
-	response := models.ErrorResponse{
-		Error: message,
-	}
-	h.sendJSON(w, response, status)
+	writeError(w, message, status)
 }
 
 // Pack size management endpoints
 
+// ListPackSizes returns a page of pack sizes. See packSizeQuery for the
+// supported filter, sort and pagination parameters.
 func (h *APIHandler) ListPackSizes(w http.ResponseWriter, r *http.Request) {
-	packSizes, err := h.packSizeRepo.GetAll()
+	query, fields := packSizeQuery(r.URL.Query())
+	if !h.validator.Check(w, fields) {
+		return
+	}
+
+	page, err := h.packSizeRepo.List(r.Context(), query)
 	if err != nil {
-		h.sendError(w, "Failed to get pack sizes", http.StatusInternalServerError)
+		writeErrorFor(w, r, err, "Failed to get pack sizes")
 		return
 	}
 
 	response := models.PackSizeListResponse{
-		PackSizes: make([]models.PackSizeResponse, len(packSizes)),
+		PackSizes: make([]models.PackSizeResponse, len(page.PackSizes)),
 	}
-
-	for i, ps := range packSizes {
-		response.PackSizes[i] = models.PackSizeResponse{
-			ID:        ps.ID,
-			Size:      ps.Size,
-			CreatedAt: ps.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: ps.UpdatedAt.Format(time.RFC3339),
-		}
+	for i := range page.PackSizes {
+		response.PackSizes[i] = toPackSizeResponse(&page.PackSizes[i])
+	}
+	if page.Next != nil {
+		response.NextCursor = page.Next.Encode()
 	}
 
 	h.sendJSON(w, response, http.StatusOK)
 }
 
 func (h *APIHandler) GetPackSize(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		h.sendError(w, fmt.Sprintf("Invalid pack size ID '%s': must be a valid integer", idStr), http.StatusBadRequest)
+	id, ok := packSizeID(w, r)
+	if !ok {
 		return
 	}
 
-	packSize, err := h.packSizeRepo.GetByID(id)
+	packSize, err := h.packSizeRepo.GetByID(r.Context(), id)
 	if err != nil {
-		h.sendError(w, "Pack size not found", http.StatusNotFound)
+		writeErrorFor(w, r, err, "Failed to get pack size")
 		return
 	}
 
-	response := models.PackSizeResponse{
-		ID:        packSize.ID,
-		Size:      packSize.Size,
-		CreatedAt: packSize.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: packSize.UpdatedAt.Format(time.RFC3339),
-	}
-
-	h.sendJSON(w, response, http.StatusOK)
+	h.sendJSON(w, toPackSizeResponse(packSize), http.StatusOK)
 }
 
 func (h *APIHandler) CreatePackSize(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatePackSizeRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	if req.Size <= 0 {
-		h.sendError(w, "Pack size must be positive", http.StatusBadRequest)
+	if !h.validator.Decode(w, r, &req) {
 		return
 	}
 
-	packSize, err := h.packSizeRepo.Create(req.Size)
+	active := req.Active == nil || *req.Active
+	packSize, err := h.packSizeRepo.Create(r.Context(), req.Size, active)
 	if err != nil {
-		h.sendError(w, fmt.Sprintf("Failed to create pack size: %v", err), http.StatusBadRequest)
+		writeErrorFor(w, r, err, "Failed to create pack size")
 		return
 	}
 
-	response := models.PackSizeResponse{
-		ID:        packSize.ID,
-		Size:      packSize.Size,
-		CreatedAt: packSize.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: packSize.UpdatedAt.Format(time.RFC3339),
-	}
-
-	h.sendJSON(w, response, http.StatusCreated)
+	h.sendJSON(w, toPackSizeResponse(packSize), http.StatusCreated)
 }
 
 func (h *APIHandler) UpdatePackSize(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
+	id, ok := packSizeID(w, r)
+	if !ok {
+		return
+	}
 
-	id, err := strconv.Atoi(idStr)
+	var req models.UpdatePackSizeRequest
+	if !h.validator.Decode(w, r, &req) {
+		return
+	}
+
+	packSize, err := h.packSizeRepo.Update(r.Context(), id, req.Size, req.Active)
 	if err != nil {
-		h.sendError(w, fmt.Sprintf("Invalid pack size ID '%s': must be a valid integer", idStr), http.StatusBadRequest)
+		writeErrorFor(w, r, err, "Failed to update pack size")
 		return
 	}
 
-	var req models.UpdatePackSizeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	h.sendJSON(w, toPackSizeResponse(packSize), http.StatusOK)
+}
+
+func (h *APIHandler) DeletePackSize(w http.ResponseWriter, r *http.Request) {
+	id, ok := packSizeID(w, r)
+	if !ok {
 		return
 	}
 
-	if req.Size <= 0 {
-		h.sendError(w, "Pack size must be positive", http.StatusBadRequest)
+	if err := h.packSizeRepo.Delete(r.Context(), id); err != nil {
+		writeErrorFor(w, r, err, "Failed to delete pack size")
 		return
 	}
 
-	packSize, err := h.packSizeRepo.Update(id, req.Size)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) ExportPackSizes(w http.ResponseWriter, r *http.Request) {
+	format, err := transfer.ParseFormat(r.URL.Query().Get("format"))
 	if err != nil {
-		h.sendError(w, fmt.Sprintf("Failed to update pack size: %v", err), http.StatusBadRequest)
+		h.sendError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response := models.PackSizeResponse{
-		ID:        packSize.ID,
-		Size:      packSize.Size,
-		CreatedAt: packSize.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: packSize.UpdatedAt.Format(time.RFC3339),
+	packSizes, err := h.service.ExportPackSizes(r.Context())
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to get pack sizes")
+		return
 	}
 
-	h.sendJSON(w, response, http.StatusOK)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"pack-sizes.%s\"", format))
+	w.WriteHeader(http.StatusOK)
+	if err := transfer.Encode(w, format, packSizes); err != nil {
+		// The status is already sent, so the export just ends early
+		slog.ErrorContext(r.Context(), "Failed to encode pack size export", "format", format, "error", err)
+	}
 }
 
-func (h *APIHandler) DeletePackSize(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
+func (h *APIHandler) ImportPackSizes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
 
-	id, err := strconv.Atoi(idStr)
+	format, ok := transfer.FormatFromContentType(r.Header.Get("Content-Type"))
+	if query.Get("format") != "" || !ok {
+		var err error
+		format, err = transfer.ParseFormat(query.Get("format"))
+		if err != nil {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	mode, err := service.ParseImportMode(query.Get("mode"))
 	if err != nil {
-		h.sendError(w, fmt.Sprintf("Invalid pack size ID '%s': must be a valid integer", idStr), http.StatusBadRequest)
+		h.sendError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.packSizeRepo.Delete(id); err != nil {
-		h.sendError(w, fmt.Sprintf("Failed to delete pack size: %v", err), http.StatusBadRequest)
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			h.sendError(w, fmt.Sprintf("Invalid dry_run value '%s': must be a boolean", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	packSizes, err := transfer.Decode(r.Body, format)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeBodyError(w, err)
+			return
+		}
+		h.sendError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	var fields []models.FieldError
+	for i, ps := range packSizes {
+		fields = append(fields, models.ValidatePackSize(fmt.Sprintf("pack_sizes[%d]", i), ps.Size, h.validator.Limits())...)
+	}
+	if !h.validator.Check(w, fields) {
+		return
+	}
+
+	result, err := h.service.ImportPackSizes(r.Context(), packSizes, mode, dryRun)
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to import pack sizes")
+		return
+	}
+
+	response := models.ImportPackSizesResponse{
+		Mode:      string(result.Mode),
+		DryRun:    result.DryRun,
+		Created:   result.Created,
+		Updated:   result.Updated,
+		Deleted:   result.Deleted,
+		Unchanged: result.Unchanged,
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
 }
 
 func (h *APIHandler) sendJSON(w http.ResponseWriter, data interface{}, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	writeJSON(w, data, status)
+}
+
+func toPackSizeResponse(ps *database.PackSize) models.PackSizeResponse {
+	return models.PackSizeResponse{
+		ID:        ps.ID,
+		Size:      ps.Size,
+		Active:    ps.Active,
+		CreatedAt: ps.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: ps.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// packSizeID parses the pack size ID from the route, reporting invalid IDs
+func packSizeID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeErrorFor(w, r, service.NewValidationError("id", "invalid_integer", fmt.Sprintf("Invalid pack size ID '%s': must be a valid integer", idStr)), "Invalid pack size ID")
+		return 0, false
+	}
+	return id, true
 }
@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/config"
 	"github.com/miloradbozic/packing-service/internal/database"
 	"github.com/miloradbozic/packing-service/internal/models"
 	"github.com/miloradbozic/packing-service/internal/service"
@@ -21,24 +25,25 @@ type mockPackSizeRepository struct {
 	nextID    int
 }
 
-func (m *mockPackSizeRepository) GetAll() ([]database.PackSize, error) {
+func (m *mockPackSizeRepository) GetAll(ctx context.Context) ([]database.PackSize, error) {
 	return m.packSizes, nil
 }
 
-func (m *mockPackSizeRepository) GetByID(id int) (*database.PackSize, error) {
+func (m *mockPackSizeRepository) GetByID(ctx context.Context, id int) (*database.PackSize, error) {
 	for _, ps := range m.packSizes {
 		if ps.ID == id {
 			return &ps, nil
 		}
 	}
-	return nil, fmt.Errorf("pack size with id %d not found", id)
+	return nil, fmt.Errorf("pack size with id %d: %w", id, database.ErrNotFound)
 }
 
-func (m *mockPackSizeRepository) Create(size int) (*database.PackSize, error) {
+func (m *mockPackSizeRepository) Create(ctx context.Context, size int, active bool) (*database.PackSize, error) {
 	m.nextID++
 	newPack := database.PackSize{
 		ID:        m.nextID,
 		Size:      size,
+		Active:    active,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -46,39 +51,118 @@ func (m *mockPackSizeRepository) Create(size int) (*database.PackSize, error) {
 	return &newPack, nil
 }
 
-func (m *mockPackSizeRepository) Update(id int, size int) (*database.PackSize, error) {
+func (m *mockPackSizeRepository) Update(ctx context.Context, id int, size int, active *bool) (*database.PackSize, error) {
 	for i, ps := range m.packSizes {
 		if ps.ID == id {
 			m.packSizes[i].Size = size
+			if active != nil {
+				m.packSizes[i].Active = *active
+			}
 			m.packSizes[i].UpdatedAt = time.Now()
 			return &m.packSizes[i], nil
 		}
 	}
-	return nil, fmt.Errorf("pack size with id %d not found", id)
+	return nil, fmt.Errorf("pack size with id %d: %w", id, database.ErrNotFound)
+}
+
+// List filters and sorts by size or ID in memory, paging by position
+func (m *mockPackSizeRepository) List(ctx context.Context, query database.PackSizeQuery) (*database.PackSizePage, error) {
+	key := func(ps database.PackSize) int {
+		if query.Sort.Field == "id" {
+			return ps.ID
+		}
+		return ps.Size
+	}
+	less := func(a, b database.PackSize) bool {
+		if key(a) != key(b) {
+			return (key(a) < key(b)) != query.Sort.Descending
+		}
+		return a.ID != b.ID && (a.ID < b.ID) != query.Sort.Descending
+	}
+
+	var matched []database.PackSize
+	for _, ps := range m.packSizes {
+		if (query.MinSize > 0 && ps.Size < query.MinSize) ||
+			(query.MaxSize > 0 && ps.Size > query.MaxSize) ||
+			(query.Active != nil && ps.Active != *query.Active) ||
+			ps.CreatedAt.Before(query.CreatedSince) ||
+			ps.UpdatedAt.Before(query.UpdatedSince) {
+			continue
+		}
+		if query.After != nil {
+			if query.After.Sort != query.Sort.String() {
+				return nil, database.ErrInvalidCursor
+			}
+			size, _ := strconv.Atoi(query.After.Value)
+			if !less(database.PackSize{ID: query.After.ID, Size: size}, ps) {
+				continue
+			}
+		}
+		matched = append(matched, ps)
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	page := &database.PackSizePage{PackSizes: matched}
+	if query.Limit > 0 && len(matched) > query.Limit {
+		page.PackSizes = matched[:query.Limit]
+		last := page.PackSizes[query.Limit-1]
+		page.Next = &database.PackSizeCursor{Sort: query.Sort.String(), Value: strconv.Itoa(key(last)), ID: last.ID}
+	}
+	return page, nil
 }
 
-func (m *mockPackSizeRepository) Delete(id int) error {
+func (m *mockPackSizeRepository) Delete(ctx context.Context, id int) error {
 	for i, ps := range m.packSizes {
 		if ps.ID == id {
 			m.packSizes = append(m.packSizes[:i], m.packSizes[i+1:]...)
 			return nil
 		}
 	}
-	return fmt.Errorf("pack size with id %d not found", id)
+	return fmt.Errorf("pack size with id %d: %w", id, database.ErrNotFound)
 }
 
+func (m *mockPackSizeRepository) Import(ctx context.Context, packSizes []database.PackSize, replace bool) error {
+	incoming := make(map[int]bool)
+	for _, ps := range packSizes {
+		incoming[ps.Size] = ps.Active
+	}
+
+	existing := make(map[int]bool)
+	kept := m.packSizes[:0]
+	for _, ps := range m.packSizes {
+		active, ok := incoming[ps.Size]
+		if replace && !ok {
+			continue
+		}
+		if ok {
+			ps.Active = active
+		}
+		existing[ps.Size] = true
+		kept = append(kept, ps)
+	}
+	m.packSizes = kept
+
+	for _, ps := range packSizes {
+		if !existing[ps.Size] {
+			m.nextID++
+			ps.ID = m.nextID
+			m.packSizes = append(m.packSizes, ps)
+		}
+	}
+	return nil
+}
 
 func setupTestHandler() *APIHandler {
 	mockRepo := &mockPackSizeRepository{
 		packSizes: []database.PackSize{
-			{ID: 1, Size: 250, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-			{ID: 2, Size: 500, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-			{ID: 3, Size: 1000, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{ID: 1, Size: 250, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{ID: 2, Size: 500, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{ID: 3, Size: 1000, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
 		},
 		nextID: 3,
 	}
 	packingService := service.NewPackingService(mockRepo)
-	return NewAPIHandler(packingService, mockRepo)
+	return NewAPIHandler(packingService, mockRepo, NewRequestValidator(config.ValidationConfig{}))
 }
 
 func setupTestHandlerWithPackSizes(packSizes []database.PackSize) *APIHandler {
@@ -87,7 +171,7 @@ func setupTestHandlerWithPackSizes(packSizes []database.PackSize) *APIHandler {
 		nextID:    len(packSizes),
 	}
 	packingService := service.NewPackingService(mockRepo)
-	return NewAPIHandler(packingService, mockRepo)
+	return NewAPIHandler(packingService, mockRepo, NewRequestValidator(config.ValidationConfig{}))
 }
 
 // Helper function to create a request with mux variables
@@ -233,8 +317,8 @@ func TestAPIHandler_Calculate_EmptyPackSizes(t *testing.T) {
 
 	handler.Calculate(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
 	}
 
 	// Verify error message
@@ -307,6 +391,126 @@ func TestAPIHandler_ListPackSizes(t *testing.T) {
 	}
 }
 
+func TestAPIHandler_ListPackSizes_Query(t *testing.T) {
+	handler := setupTestHandlerWithPackSizes([]database.PackSize{
+		{ID: 1, Size: 250, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 2, Size: 500, Active: false, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 3, Size: 1000, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 4, Size: 2000, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSizes  []int
+		expectedField  string
+	}{
+		{name: "Size range", query: "?min_size=500&max_size=1000", expectedStatus: http.StatusOK, expectedSizes: []int{500, 1000}},
+		{name: "Active only", query: "?active=true", expectedStatus: http.StatusOK, expectedSizes: []int{250, 1000, 2000}},
+		{name: "Inactive only", query: "?active=false", expectedStatus: http.StatusOK, expectedSizes: []int{500}},
+		{name: "Descending sort", query: "?sort=-size", expectedStatus: http.StatusOK, expectedSizes: []int{2000, 1000, 500, 250}},
+		{name: "Invalid limit", query: "?limit=0", expectedStatus: http.StatusBadRequest, expectedField: "limit"},
+		{name: "Limit above maximum", query: "?limit=1001", expectedStatus: http.StatusBadRequest, expectedField: "limit"},
+		{name: "Non-numeric min size", query: "?min_size=abc", expectedStatus: http.StatusBadRequest, expectedField: "min_size"},
+		{name: "Min size above max size", query: "?min_size=1000&max_size=500", expectedStatus: http.StatusBadRequest, expectedField: "min_size"},
+		{name: "Invalid date", query: "?created_since=yesterday", expectedStatus: http.StatusBadRequest, expectedField: "created_since"},
+		{name: "Invalid active flag", query: "?active=maybe", expectedStatus: http.StatusBadRequest, expectedField: "active"},
+		{name: "Unknown sort field", query: "?sort=name", expectedStatus: http.StatusBadRequest, expectedField: "sort"},
+		{name: "Malformed cursor", query: "?cursor=not-a-cursor", expectedStatus: http.StatusBadRequest, expectedField: "cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/pack-sizes"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListPackSizes(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			if tt.expectedField != "" {
+				var problem models.Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				if len(problem.Errors) != 1 || problem.Errors[0].Field != tt.expectedField {
+					t.Errorf("expected a single error on '%s', got %+v", tt.expectedField, problem.Errors)
+				}
+				return
+			}
+
+			var response models.PackSizeListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			sizes := make([]int, len(response.PackSizes))
+			for i, ps := range response.PackSizes {
+				sizes[i] = ps.Size
+			}
+			if fmt.Sprint(sizes) != fmt.Sprint(tt.expectedSizes) {
+				t.Errorf("expected sizes %v, got %v", tt.expectedSizes, sizes)
+			}
+			if response.NextCursor != "" {
+				t.Errorf("expected no next cursor, got '%s'", response.NextCursor)
+			}
+		})
+	}
+}
+
+func TestAPIHandler_ListPackSizes_Pagination(t *testing.T) {
+	handler := setupTestHandler()
+
+	var sizes []int
+	url := "/api/v1/pack-sizes?sort=-size&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not end")
+		}
+
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		handler.ListPackSizes(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response models.PackSizeListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		for _, ps := range response.PackSizes {
+			sizes = append(sizes, ps.Size)
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		url = "/api/v1/pack-sizes?sort=-size&limit=2&cursor=" + response.NextCursor
+	}
+
+	if fmt.Sprint(sizes) != fmt.Sprint([]int{1000, 500, 250}) {
+		t.Errorf("expected sizes [1000 500 250] across pages, got %v", sizes)
+	}
+
+	// A cursor only continues the sort order it was issued for
+	req := httptest.NewRequest("GET", "/api/v1/pack-sizes?sort=-size&limit=1", nil)
+	w := httptest.NewRecorder()
+	handler.ListPackSizes(w, req)
+	var response models.PackSizeListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/pack-sizes?sort=size&cursor="+response.NextCursor, nil)
+	w = httptest.NewRecorder()
+	handler.ListPackSizes(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for a cursor of another sort, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 func TestAPIHandler_GetPackSize(t *testing.T) {
 	handler := setupTestHandler()
 
@@ -435,7 +639,7 @@ func TestAPIHandler_UpdatePackSize(t *testing.T) {
 			name:           "Invalid pack size ID",
 			packID:         "999",
 			requestBody:    models.UpdatePackSizeRequest{Size: 300},
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusNotFound,
 		},
 		{
 			name:           "Invalid pack size - negative",
@@ -495,7 +699,7 @@ func TestAPIHandler_DeletePackSize(t *testing.T) {
 		{
 			name:           "Invalid pack size ID",
 			packID:         "999",
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusNotFound,
 		},
 		{
 			name:           "Non-numeric pack size ID",
@@ -565,3 +769,88 @@ func TestAPIHandler_sendJSON(t *testing.T) {
 		t.Errorf("expected message 'test', got '%s'", response["message"])
 	}
 }
+
+func TestAPIHandler_ExportImportPackSizes(t *testing.T) {
+	source := setupTestHandler()
+
+	req := httptest.NewRequest("GET", "/api/v1/pack-sizes/export?format=yaml", nil)
+	w := httptest.NewRecorder()
+	source.ExportPackSizes(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/yaml" {
+		t.Errorf("expected Content-Type 'application/yaml', got '%s'", w.Header().Get("Content-Type"))
+	}
+
+	target := setupTestHandlerWithPackSizes([]database.PackSize{
+		{ID: 1, Size: 250, Active: false, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 2, Size: 2000, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	})
+
+	tests := []struct {
+		name              string
+		query             string
+		expectedStatus    int
+		expectedCreated   int
+		expectedUpdated   int
+		expectedDeleted   int
+		expectedRemaining int
+	}{
+		{
+			name:              "Dry run replace",
+			query:             "?format=yaml&mode=replace&dry_run=true",
+			expectedStatus:    http.StatusOK,
+			expectedCreated:   2,
+			expectedUpdated:   1,
+			expectedDeleted:   1,
+			expectedRemaining: 1,
+		},
+		{
+			name:           "Invalid mode",
+			query:          "?format=yaml&mode=overwrite",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:              "Replace",
+			query:             "?format=yaml&mode=replace",
+			expectedStatus:    http.StatusOK,
+			expectedCreated:   2,
+			expectedUpdated:   1,
+			expectedDeleted:   1,
+			expectedRemaining: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/pack-sizes/import"+tt.query, bytes.NewBuffer(w.Body.Bytes()))
+			rec := httptest.NewRecorder()
+
+			target.ImportPackSizes(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.ImportPackSizesResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if len(response.Created) != tt.expectedCreated || len(response.Updated) != tt.expectedUpdated || len(response.Deleted) != tt.expectedDeleted {
+				t.Errorf("expected %d created, %d updated and %d deleted, got %v, %v and %v",
+					tt.expectedCreated, tt.expectedUpdated, tt.expectedDeleted, response.Created, response.Updated, response.Deleted)
+			}
+
+			sizes, _ := target.service.GetPackSizes(context.Background())
+			if len(sizes) != tt.expectedRemaining {
+				t.Errorf("expected %d pack sizes after import, got %v", tt.expectedRemaining, sizes)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// Authenticator resolves API keys, bearer tokens and TLS client certificates to
+// principals and enforces scopes on routes
+type Authenticator struct {
+	keys    database.APIKeyRepositoryInterface
+	tokens  *auth.TokenValidator
+	certs   *auth.CertificateMapper
+	enabled bool
+}
+
+// NewAuthenticator creates an authenticator. tokens may be nil, in which case
+// only API keys are accepted.
+func NewAuthenticator(keys database.APIKeyRepositoryInterface, tokens *auth.TokenValidator, cfg config.AuthConfig) *Authenticator {
+	return &Authenticator{
+		keys:    keys,
+		tokens:  tokens,
+		enabled: cfg.Enabled,
+	}
+}
+
+// SetCertificateMapper authenticates requests without an API key or bearer token
+// by their verified TLS client certificate
+func (a *Authenticator) SetCertificateMapper(certs *auth.CertificateMapper) {
+	a.certs = certs
+}
+
+// Middleware authenticates requests that carry an API key or bearer token. The
+// principal and, for API keys, the tenant are stored in the request context;
+// invalid credentials are rejected.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := apiKeyFromRequest(r)
+		if key == "" {
+			a.authenticateToken(w, r, next)
+			return
+		}
+
+		apiKey, err := a.keys.GetByHash(r.Context(), auth.HashKey(key))
+		if err != nil {
+			if errors.Is(err, database.ErrAPIKeyNotFound) {
+				unauthorized(w, "Invalid API key")
+				return
+			}
+			writeError(w, "Failed to authenticate", http.StatusInternalServerError)
+			return
+		}
+
+		if err := a.keys.TouchLastUsed(r.Context(), apiKey.ID); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to record API key usage", "error", err)
+		}
+
+		principal := auth.Principal{
+			Subject:  fmt.Sprintf("api-key:%d", apiKey.ID),
+			TenantID: apiKey.TenantID,
+		}
+		for _, scope := range apiKey.Scopes {
+			principal.Scopes = append(principal.Scopes, auth.Scope(scope))
+		}
+
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		ctx = tenant.WithTenant(ctx, tenant.Tenant{ID: apiKey.TenantID, Slug: apiKey.TenantSlug})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateToken validates a JWT bearer token, if any. The tenant is left to
+// the tenant resolver, which honors a tenant named in the token.
+func (a *Authenticator) authenticateToken(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	token := bearerToken(r)
+	if token == "" || a.tokens == nil {
+		a.authenticateCertificate(w, r, next)
+		return
+	}
+
+	principal, err := a.tokens.Validate(token)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Rejected bearer token", "error", err)
+		unauthorized(w, "Invalid bearer token")
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+}
+
+// authenticateCertificate maps a client certificate verified during the TLS
+// handshake, if any, to a principal. The tenant is left to the tenant resolver.
+func (a *Authenticator) authenticateCertificate(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if a.certs == nil || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	principal := a.certs.Principal(r.TLS.VerifiedChains[0][0])
+	next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+}
+
+// Require only runs next for principals granted the scope. It lets every request
+// through while authentication is disabled.
+func (a *Authenticator) Require(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled {
+			next(w, r)
+			return
+		}
+
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authentication required")
+			return
+		}
+
+		if !principal.Has(scope) {
+			writeError(w, fmt.Sprintf("Missing required scope '%s'", scope), http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// apiKeyFromRequest reads the key from the Authorization bearer token or X-API-Key header
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+
+	if token := bearerToken(r); strings.HasPrefix(token, auth.KeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// bearerToken returns the token from the Authorization header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="packing-service"`)
+	writeError(w, message, http.StatusUnauthorized)
+}
+
+// APIKeyHandler serves the API key management endpoints of the current tenant
+type APIKeyHandler struct {
+	keys      database.APIKeyRepositoryInterface
+	validator *RequestValidator
+}
+
+func NewAPIKeyHandler(keys database.APIKeyRepositoryInterface, validator *RequestValidator) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys, validator: validator}
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.GetAll(r.Context())
+	if err != nil {
+		writeError(w, "Failed to get API keys", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.APIKeyListResponse{
+		APIKeys: make([]models.APIKeyResponse, len(keys)),
+	}
+	for i, key := range keys {
+		response.APIKeys[i] = toAPIKeyResponse(&key, "")
+	}
+
+	writeJSON(w, response, http.StatusOK)
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+
+	if !h.validator.Decode(w, r, &req) {
+		return
+	}
+
+	scopes, err := auth.ParseScopes(req.Scopes)
+	if err != nil {
+		h.validator.Check(w, []models.FieldError{{Field: "scopes", Code: "invalid_scope", Message: err.Error()}})
+		return
+	}
+
+	key, prefix, err := auth.GenerateKey()
+	if err != nil {
+		writeError(w, "Failed to generate API key", http.StatusInternalServerError)
+		return
+	}
+
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+
+	apiKey, err := h.keys.Create(r.Context(), req.Name, prefix, auth.HashKey(key), names)
+	if err != nil {
+		writeError(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toAPIKeyResponse(apiKey, key), http.StatusCreated)
+}
+
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	key, prefix, err := auth.GenerateKey()
+	if err != nil {
+		writeError(w, "Failed to generate API key", http.StatusInternalServerError)
+		return
+	}
+
+	apiKey, err := h.keys.Rotate(r.Context(), id, prefix, auth.HashKey(key))
+	if err != nil {
+		if errors.Is(err, database.ErrAPIKeyNotFound) {
+			writeProblem(w, http.StatusNotFound, codeNotFound, "API key not found", nil)
+			return
+		}
+		writeError(w, "Failed to rotate API key", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toAPIKeyResponse(apiKey, key), http.StatusCreated)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.keys.Revoke(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrAPIKeyNotFound) {
+			writeProblem(w, http.StatusNotFound, codeNotFound, "API key not found", nil)
+			return
+		}
+		writeError(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiKeyID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, fmt.Sprintf("Invalid API key ID '%s': must be a valid integer", idStr), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func toAPIKeyResponse(key *database.APIKey, plaintext string) models.APIKeyResponse {
+	response := models.APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+		Key:       plaintext,
+	}
+	if key.LastUsedAt != nil {
+		response.LastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+	}
+	if key.RevokedAt != nil {
+		response.RevokedAt = key.RevokedAt.Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// Mock API key repository for testing
+type mockAPIKeyRepository struct {
+	keys   map[string]database.APIKey
+	nextID int
+}
+
+func newMockAPIKeyRepository(keys map[string][]string) *mockAPIKeyRepository {
+	m := &mockAPIKeyRepository{keys: make(map[string]database.APIKey)}
+	for key, scopes := range keys {
+		m.nextID++
+		m.keys[auth.HashKey(key)] = database.APIKey{
+			ID: m.nextID, TenantID: 2, TenantSlug: "acme", Name: key, Scopes: scopes, CreatedAt: time.Now(),
+		}
+	}
+	return m
+}
+
+func (m *mockAPIKeyRepository) GetAll(ctx context.Context) ([]database.APIKey, error) {
+	var keys []database.APIKey
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (m *mockAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*database.APIKey, error) {
+	key, ok := m.keys[keyHash]
+	if !ok || key.RevokedAt != nil {
+		return nil, database.ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+func (m *mockAPIKeyRepository) Create(ctx context.Context, name, prefix, keyHash string, scopes []string) (*database.APIKey, error) {
+	m.nextID++
+	key := database.APIKey{ID: m.nextID, Name: name, Prefix: prefix, Scopes: scopes, CreatedAt: time.Now()}
+	m.keys[keyHash] = key
+	return &key, nil
+}
+
+func (m *mockAPIKeyRepository) Rotate(ctx context.Context, id int, prefix, keyHash string) (*database.APIKey, error) {
+	return nil, database.ErrAPIKeyNotFound
+}
+
+func (m *mockAPIKeyRepository) Revoke(ctx context.Context, id int) error {
+	return database.ErrAPIKeyNotFound
+}
+
+func (m *mockAPIKeyRepository) TouchLastUsed(ctx context.Context, id int) error {
+	return nil
+}
+
+func TestAuthenticator(t *testing.T) {
+	keys := newMockAPIKeyRepository(map[string][]string{
+		"psk_reader":     {"read"},
+		"psk_calculator": {"calculate"},
+		"psk_manager":    {"manage"},
+	})
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		header         string
+		value          string
+		scope          auth.Scope
+		expectedStatus int
+	}{
+		{name: "Disabled lets anonymous through", scope: auth.ScopeManage, expectedStatus: http.StatusOK},
+		{name: "Disabled still rejects invalid keys", header: "X-API-Key", value: "psk_unknown", scope: auth.ScopeRead, expectedStatus: http.StatusUnauthorized},
+		{name: "Anonymous", enabled: true, scope: auth.ScopeRead, expectedStatus: http.StatusUnauthorized},
+		{name: "Invalid key", enabled: true, header: "X-API-Key", value: "psk_unknown", scope: auth.ScopeRead, expectedStatus: http.StatusUnauthorized},
+		{name: "Read key can read", enabled: true, header: "X-API-Key", value: "psk_reader", scope: auth.ScopeRead, expectedStatus: http.StatusOK},
+		{name: "Read key cannot manage", enabled: true, header: "X-API-Key", value: "psk_reader", scope: auth.ScopeManage, expectedStatus: http.StatusForbidden},
+		{name: "Calculate key via bearer token", enabled: true, header: "Authorization", value: "Bearer psk_calculator", scope: auth.ScopeCalculate, expectedStatus: http.StatusOK},
+		{name: "Calculate key cannot read", enabled: true, header: "Authorization", value: "Bearer psk_calculator", scope: auth.ScopeRead, expectedStatus: http.StatusForbidden},
+		{name: "Manage key can delete", enabled: true, header: "X-API-Key", value: "psk_manager", scope: auth.ScopeManage, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := NewAuthenticator(keys, nil, config.AuthConfig{Enabled: tt.enabled})
+
+			var resolvedTenant string
+			handler := authenticator.Middleware(authenticator.Require(tt.scope, func(w http.ResponseWriter, r *http.Request) {
+				current, _ := tenant.FromContext(r.Context())
+				resolvedTenant = current.Slug
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/api/v1/pack-sizes", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if w.Code == http.StatusUnauthorized {
+				var response models.ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error == "" {
+					t.Errorf("expected ErrorResponse body, got %q", w.Body.String())
+				}
+			}
+
+			if w.Code == http.StatusOK && tt.value != "" && resolvedTenant != "acme" {
+				t.Errorf("expected the key's tenant 'acme', got '%s'", resolvedTenant)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_ClientCertificate(t *testing.T) {
+	keys := newMockAPIKeyRepository(map[string][]string{"psk_reader": {"read"}})
+	authenticator := NewAuthenticator(keys, nil, config.AuthConfig{Enabled: true})
+	certs, err := auth.NewCertificateMapper(
+		map[string][]string{"inventory-sync": {"manage"}, "reporting": {"read"}},
+		map[string]string{"inventory-sync": "acme", "reporting": "acme"},
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authenticator.SetCertificateMapper(certs)
+
+	tests := []struct {
+		name           string
+		commonName     string
+		verified       bool
+		apiKey         string
+		expectedStatus int
+	}{
+		{name: "Mapped client can manage", commonName: "inventory-sync", verified: true, expectedStatus: http.StatusOK},
+		{name: "Read-only client cannot manage", commonName: "reporting", verified: true, expectedStatus: http.StatusForbidden},
+		{name: "Unmapped client", commonName: "stranger", verified: true, expectedStatus: http.StatusForbidden},
+		{name: "Unverified certificate", commonName: "inventory-sync", expectedStatus: http.StatusUnauthorized},
+		{name: "API key takes precedence", commonName: "inventory-sync", verified: true, apiKey: "psk_reader", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := authenticator.Middleware(authenticator.Require(auth.ScopeManage, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("DELETE", "/api/v1/pack-sizes/1", nil)
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.commonName}}
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			if tt.verified {
+				req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+			}
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHandler_CreateAPIKey(t *testing.T) {
+	keys := newMockAPIKeyRepository(nil)
+	handler := NewAPIKeyHandler(keys, NewRequestValidator(config.ValidationConfig{}))
+
+	req := httptest.NewRequest("POST", "/api/v1/api-keys", bytes.NewBufferString(`{"name": "erp", "scopes": ["calculate", "read"]}`))
+	w := httptest.NewRecorder()
+
+	handler.CreateAPIKey(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var response models.APIKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if response.Key == "" {
+		t.Fatalf("expected the plaintext key in the response")
+	}
+	if _, err := keys.GetByHash(context.Background(), auth.HashKey(response.Key)); err != nil {
+		t.Errorf("expected the key to be stored by its hash: %v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/api-keys", bytes.NewBufferString(`{"name": "erp", "scopes": ["root"]}`))
+	w = httptest.NewRecorder()
+	handler.CreateAPIKey(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown scope, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSnapshot is the configuration the service is running with
+type ConfigSnapshot struct {
+	Config   config.Config
+	LoadedAt time.Time
+	// PendingRestart lists changed settings that cannot be reloaded
+	PendingRestart []string
+}
+
+// ConfigHandler shows operators the effective configuration
+type ConfigHandler struct {
+	current func() ConfigSnapshot
+}
+
+func NewConfigHandler(current func() ConfigSnapshot) *ConfigHandler {
+	return &ConfigHandler{current: current}
+}
+
+// GetConfig returns the effective configuration with secrets redacted
+func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	snapshot := h.current()
+
+	values, err := configValues(snapshot.Config.Redacted())
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to get config")
+		return
+	}
+
+	pending := snapshot.PendingRestart
+	if pending == nil {
+		pending = []string{}
+	}
+	writeJSON(w, models.EffectiveConfigResponse{
+		Config:         values,
+		LoadedAt:       snapshot.LoadedAt.UTC().Format(time.RFC3339),
+		PendingRestart: pending,
+	}, http.StatusOK)
+}
+
+// configValues converts the config to a map keyed like config.yaml
+func configValues(cfg config.Config) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	return values, nil
+}
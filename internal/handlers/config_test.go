@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/models"
+)
+
+func TestConfigHandler_GetConfig(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Server.Port = 8080
+	cfg.Database.Password = "hunter2"
+	cfg.Tenancy.AdminToken = "admin-token"
+	handler := NewConfigHandler(func() ConfigSnapshot {
+		return ConfigSnapshot{Config: cfg, LoadedAt: time.Now()}
+	})
+
+	w := httptest.NewRecorder()
+	handler.GetConfig(w, httptest.NewRequest("GET", "/api/v1/admin/config", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if body := w.Body.String(); strings.Contains(body, "hunter2") || strings.Contains(body, "admin-token") {
+		t.Errorf("expected secrets to be redacted, got %s", body)
+	}
+
+	var response models.EffectiveConfigResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	server, _ := response.Config["server"].(map[string]interface{})
+	if server["port"] != float64(8080) {
+		t.Errorf("expected server.port 8080 keyed like config.yaml, got %v", response.Config["server"])
+	}
+	if response.PendingRestart == nil {
+		t.Error("expected an empty pending_restart list, got null")
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/openapi"
+	"github.com/miloradbozic/packing-service/internal/service"
+)
+
+// newContractRouter serves the JSON API routes the way the app registers them,
+// without authentication
+func newContractRouter(t *testing.T) *mux.Router {
+	packSizes := &mockPackSizeRepository{
+		packSizes: []database.PackSize{
+			{ID: 1, Size: 250, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{ID: 2, Size: 500, Active: true, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		},
+		nextID: 2,
+	}
+	validator := NewRequestValidator(config.ValidationConfig{MaxOrderItems: 1000000})
+	api := NewAPIHandler(service.NewPackingService(packSizes), packSizes, validator)
+	keys := NewAPIKeyHandler(newMockAPIKeyRepository(map[string][]string{"psk_reader": {"read"}}), validator)
+	hooks := NewWebhookHandler(newMockWebhookRepository(), validator)
+	tenants := NewTenantHandler(newMockTenantRepository(), config.TenancyConfig{AdminToken: "secret"}, validator)
+	docs, err := NewDocsHandler(os.DirFS("../../templates"))
+	if err != nil {
+		t.Fatalf("failed to create docs handler: %v", err)
+	}
+
+	health := NewHealthHandler(
+		[]HealthCheck{{Name: "database", Check: func(ctx context.Context) error { return nil }}},
+		[]HealthCheck{{Name: "database", Check: func(ctx context.Context) error { return errors.New("connection refused") }}},
+		time.Second,
+	)
+
+	router := mux.NewRouter()
+	router.Use(validator.LimitBody)
+	router.HandleFunc("/health/live", health.Live).Methods("GET")
+	router.HandleFunc("/health/ready", health.Ready).Methods("GET")
+	router.HandleFunc("/health/startup", health.Startup).Methods("GET")
+	router.HandleFunc("/api/v1/openapi.json", docs.OpenAPI).Methods("GET")
+	router.HandleFunc("/api/v1/docs", docs.Docs).Methods("GET")
+
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(tenants.RequireAdmin)
+	admin.HandleFunc("/tenants", tenants.ListTenants).Methods("GET")
+	admin.HandleFunc("/tenants", tenants.CreateTenant).Methods("POST")
+	admin.HandleFunc("/config", NewConfigHandler(func() ConfigSnapshot {
+		cfg := config.Config{}
+		cfg.Database.Password = "secret"
+		return ConfigSnapshot{Config: cfg, LoadedAt: time.Now(), PendingRestart: []string{"server"}}
+	}).GetConfig).Methods("GET")
+
+	v1 := router.PathPrefix("/api/v1").Subrouter()
+	v1.HandleFunc("/calculate", api.Calculate).Methods("POST")
+	v1.HandleFunc("/config", api.GetConfig).Methods("GET")
+	v1.HandleFunc("/pack-sizes", api.ListPackSizes).Methods("GET")
+	v1.HandleFunc("/pack-sizes", api.CreatePackSize).Methods("POST")
+	v1.HandleFunc("/pack-sizes/export", api.ExportPackSizes).Methods("GET")
+	v1.HandleFunc("/pack-sizes/import", api.ImportPackSizes).Methods("POST")
+	v1.HandleFunc("/pack-sizes/{id}", api.GetPackSize).Methods("GET")
+	v1.HandleFunc("/pack-sizes/{id}", api.UpdatePackSize).Methods("PUT")
+	v1.HandleFunc("/pack-sizes/{id}", api.DeletePackSize).Methods("DELETE")
+	v1.HandleFunc("/api-keys", keys.ListAPIKeys).Methods("GET")
+	v1.HandleFunc("/api-keys", keys.CreateAPIKey).Methods("POST")
+	v1.HandleFunc("/api-keys/{id}/rotate", keys.RotateAPIKey).Methods("POST")
+	v1.HandleFunc("/api-keys/{id}", keys.RevokeAPIKey).Methods("DELETE")
+	v1.HandleFunc("/webhooks", hooks.ListWebhooks).Methods("GET")
+	v1.HandleFunc("/webhooks", hooks.CreateWebhook).Methods("POST")
+	v1.HandleFunc("/webhooks/{id}", hooks.GetWebhook).Methods("GET")
+	v1.HandleFunc("/webhooks/{id}", hooks.UpdateWebhook).Methods("PUT")
+	v1.HandleFunc("/webhooks/{id}", hooks.DeleteWebhook).Methods("DELETE")
+	v1.HandleFunc("/webhooks/{id}/deliveries", hooks.ListDeliveries).Methods("GET")
+	v1.HandleFunc("/webhooks/{id}/deliveries/{delivery_id}/retry", hooks.RetryDelivery).Methods("POST")
+	return router
+}
+
+// TestContract checks real handler responses against the OpenAPI document
+func TestContract(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("failed to load openapi document: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{name: "Calculate", method: "POST", url: "/api/v1/calculate", body: `{"items": 501}`, expectedStatus: http.StatusOK},
+		{name: "Calculate with invalid body", method: "POST", url: "/api/v1/calculate", body: `{"items": "5", "extra": 1}`, expectedStatus: http.StatusBadRequest},
+		{name: "Calculate too large body", method: "POST", url: "/api/v1/calculate", body: `{"items": 1, "pad": "` + strings.Repeat("x", DefaultMaxBodyBytes) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Config", method: "GET", url: "/api/v1/config", expectedStatus: http.StatusOK},
+		{name: "List pack sizes", method: "GET", url: "/api/v1/pack-sizes", expectedStatus: http.StatusOK},
+		{name: "Create pack size", method: "POST", url: "/api/v1/pack-sizes", body: `{"size": 750}`, expectedStatus: http.StatusCreated},
+		{name: "Create invalid pack size", method: "POST", url: "/api/v1/pack-sizes", body: `{"size": -1}`, expectedStatus: http.StatusBadRequest},
+		{name: "Get pack size", method: "GET", url: "/api/v1/pack-sizes/1", expectedStatus: http.StatusOK},
+		{name: "Get missing pack size", method: "GET", url: "/api/v1/pack-sizes/999", expectedStatus: http.StatusNotFound},
+		{name: "Get pack size with invalid ID", method: "GET", url: "/api/v1/pack-sizes/abc", expectedStatus: http.StatusBadRequest},
+		{name: "Update pack size", method: "PUT", url: "/api/v1/pack-sizes/1", body: `{"size": 300}`, expectedStatus: http.StatusOK},
+		{name: "Update missing pack size", method: "PUT", url: "/api/v1/pack-sizes/999", body: `{"size": 300}`, expectedStatus: http.StatusNotFound},
+		{name: "Delete pack size", method: "DELETE", url: "/api/v1/pack-sizes/2", expectedStatus: http.StatusNoContent},
+		{name: "Delete missing pack size", method: "DELETE", url: "/api/v1/pack-sizes/999", expectedStatus: http.StatusNotFound},
+		{name: "Export pack sizes", method: "GET", url: "/api/v1/pack-sizes/export", expectedStatus: http.StatusOK},
+		{name: "Export pack sizes as CSV", method: "GET", url: "/api/v1/pack-sizes/export?format=csv", expectedStatus: http.StatusOK},
+		{name: "Export with unknown format", method: "GET", url: "/api/v1/pack-sizes/export?format=xml", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "Import pack sizes",
+			method:         "POST",
+			url:            "/api/v1/pack-sizes/import?dry_run=true",
+			body:           `{"version": 1, "pack_sizes": [{"size": 250}, {"size": 5000}]}`,
+			headers:        map[string]string{"Content-Type": "application/json"},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "List API keys", method: "GET", url: "/api/v1/api-keys", expectedStatus: http.StatusOK},
+		{name: "Create API key", method: "POST", url: "/api/v1/api-keys", body: `{"name": "ci", "scopes": ["read"]}`, expectedStatus: http.StatusCreated},
+		{name: "Create API key with unknown scope", method: "POST", url: "/api/v1/api-keys", body: `{"name": "ci", "scopes": ["root"]}`, expectedStatus: http.StatusBadRequest},
+		{name: "Rotate missing API key", method: "POST", url: "/api/v1/api-keys/999/rotate", expectedStatus: http.StatusNotFound},
+		{name: "Revoke missing API key", method: "DELETE", url: "/api/v1/api-keys/999", expectedStatus: http.StatusNotFound},
+		{name: "List webhooks", method: "GET", url: "/api/v1/webhooks", expectedStatus: http.StatusOK},
+		{name: "Create webhook", method: "POST", url: "/api/v1/webhooks", body: `{"url": "https://erp.example.com/hooks", "events": ["pack_size.created", "order.calculated"]}`, expectedStatus: http.StatusCreated},
+		{name: "Create webhook with unknown event", method: "POST", url: "/api/v1/webhooks", body: `{"url": "https://erp.example.com/hooks", "events": ["order.shipped"]}`, expectedStatus: http.StatusBadRequest},
+		{name: "Get webhook", method: "GET", url: "/api/v1/webhooks/1", expectedStatus: http.StatusOK},
+		{name: "Get missing webhook", method: "GET", url: "/api/v1/webhooks/999", expectedStatus: http.StatusNotFound},
+		{name: "Update webhook", method: "PUT", url: "/api/v1/webhooks/1", body: `{"url": "https://erp.example.com/v2", "events": ["pack_size.deleted"], "active": false}`, expectedStatus: http.StatusOK},
+		{name: "Delete missing webhook", method: "DELETE", url: "/api/v1/webhooks/999", expectedStatus: http.StatusNotFound},
+		{name: "List webhook deliveries", method: "GET", url: "/api/v1/webhooks/1/deliveries?status=dead", expectedStatus: http.StatusOK},
+		{name: "List webhook deliveries with invalid limit", method: "GET", url: "/api/v1/webhooks/1/deliveries?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "Retry webhook delivery", method: "POST", url: "/api/v1/webhooks/1/deliveries/7/retry", expectedStatus: http.StatusAccepted},
+		{name: "Retry missing webhook delivery", method: "POST", url: "/api/v1/webhooks/1/deliveries/999/retry", expectedStatus: http.StatusNotFound},
+		{name: "Delete webhook", method: "DELETE", url: "/api/v1/webhooks/1", expectedStatus: http.StatusNoContent},
+		{name: "List tenants", method: "GET", url: "/api/v1/admin/tenants", headers: map[string]string{adminTokenHeader: "secret"}, expectedStatus: http.StatusOK},
+		{name: "List tenants without token", method: "GET", url: "/api/v1/admin/tenants", expectedStatus: http.StatusUnauthorized},
+		{
+			name:           "Create tenant",
+			method:         "POST",
+			url:            "/api/v1/admin/tenants",
+			body:           `{"slug": "globex", "pack_sizes": [10, 20]}`,
+			headers:        map[string]string{adminTokenHeader: "secret"},
+			expectedStatus: http.StatusCreated,
+		},
+		{name: "Liveness probe", method: "GET", url: "/health/live", expectedStatus: http.StatusOK},
+		{name: "Readiness probe", method: "GET", url: "/health/ready", expectedStatus: http.StatusOK},
+		{name: "Failing startup probe", method: "GET", url: "/health/startup", expectedStatus: http.StatusServiceUnavailable},
+		{name: "Effective config", method: "GET", url: "/api/v1/admin/config", headers: map[string]string{adminTokenHeader: "secret"}, expectedStatus: http.StatusOK},
+		{name: "OpenAPI document", method: "GET", url: "/api/v1/openapi.json", expectedStatus: http.StatusOK},
+		{name: "Docs page", method: "GET", url: "/api/v1/docs", expectedStatus: http.StatusOK},
+	}
+
+	router := newContractRouter(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			var match mux.RouteMatch
+			if !router.Match(req, &match) {
+				t.Fatalf("no route matches %s %s", tt.method, tt.url)
+			}
+			path, err := match.Route.GetPathTemplate()
+			if err != nil {
+				t.Fatalf("failed to get path template: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if err := doc.ValidateResponse(tt.method, path, w.Code, w.Header().Get("Content-Type"), w.Body.Bytes()); err != nil {
+				t.Errorf("response does not match the openapi document: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+
+	"github.com/miloradbozic/packing-service/internal/openapi"
+)
+
+// DocsHandler serves the OpenAPI document and a page rendering it
+type DocsHandler struct {
+	templates *template.Template
+}
+
+func NewDocsHandler(templates fs.FS) (*DocsHandler, error) {
+	tmpl, err := template.ParseFS(templates, "docs.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse docs template: %w", err)
+	}
+	return &DocsHandler{templates: tmpl}, nil
+}
+
+// OpenAPI serves the OpenAPI 3 document
+func (h *DocsHandler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.Spec())
+}
+
+// Docs serves the API documentation page, which needs no external assets
+func (h *DocsHandler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "docs.html", nil); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/models"
+)
+
+// DefaultHealthCheckTimeout bounds each health check
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// HealthCheck verifies that a dependency of the service is usable
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthHandler serves the liveness, readiness and startup probes
+type HealthHandler struct {
+	readiness []HealthCheck
+	startup   []HealthCheck
+	timeout   time.Duration
+	// started is set once the startup checks have passed
+	started atomic.Bool
+}
+
+func NewHealthHandler(readiness, startup []HealthCheck, timeout time.Duration) *HealthHandler {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	return &HealthHandler{readiness: readiness, startup: startup, timeout: timeout}
+}
+
+// Live reports that the process is running and able to serve requests. It
+// checks no dependencies, so an outage does not get healthy instances restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, models.HealthResponse{Status: healthStatusOK, Checks: []models.HealthCheckResult{}}, http.StatusOK)
+}
+
+// Ready reports whether the instance can serve traffic, answering 503 with the
+// failing checks when it cannot
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, h.run(r.Context(), h.readiness))
+}
+
+// Startup reports whether the instance has finished starting. Once the startup
+// checks have passed they are not run again.
+func (h *HealthHandler) Startup(w http.ResponseWriter, r *http.Request) {
+	if h.started.Load() {
+		writeJSON(w, models.HealthResponse{Status: healthStatusOK, Checks: []models.HealthCheckResult{}}, http.StatusOK)
+		return
+	}
+
+	response := h.run(r.Context(), h.startup)
+	if response.Status == healthStatusOK {
+		h.started.Store(true)
+	}
+	h.respond(w, response)
+}
+
+// run executes the checks concurrently, each with its own timeout
+func (h *HealthHandler) run(ctx context.Context, checks []HealthCheck) models.HealthResponse {
+	response := models.HealthResponse{Status: healthStatusOK, Checks: make([]models.HealthCheckResult, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response.Checks[i] = h.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range response.Checks {
+		if result.Status != healthStatusOK {
+			response.Status = healthStatusFail
+		}
+	}
+	return response
+}
+
+func (h *HealthHandler) runCheck(ctx context.Context, check HealthCheck) models.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+
+	result := models.HealthCheckResult{
+		Name:      check.Name,
+		Status:    healthStatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = healthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (h *HealthHandler) respond(w http.ResponseWriter, response models.HealthResponse) {
+	status := http.StatusOK
+	if response.Status != healthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, response, status)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/models"
+)
+
+func passingCheck(name string) HealthCheck {
+	return HealthCheck{Name: name, Check: func(ctx context.Context) error { return nil }}
+}
+
+func failingCheck(name string) HealthCheck {
+	return HealthCheck{Name: name, Check: func(ctx context.Context) error { return errors.New("connection refused") }}
+}
+
+func decodeHealth(t *testing.T, w *httptest.ResponseRecorder) models.HealthResponse {
+	t.Helper()
+	var response models.HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestHealthHandler_Live(t *testing.T) {
+	handler := NewHealthHandler([]HealthCheck{failingCheck("database")}, nil, 0)
+
+	w := httptest.NewRecorder()
+	handler.Live(w, httptest.NewRequest("GET", "/health/live", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if response := decodeHealth(t, w); response.Status != "ok" {
+		t.Errorf("expected status 'ok', got '%s'", response.Status)
+	}
+}
+
+func TestHealthHandler_Ready(t *testing.T) {
+	slow := HealthCheck{Name: "slow", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	tests := []struct {
+		name           string
+		checks         []HealthCheck
+		expectedStatus int
+		expectedChecks map[string]string
+	}{
+		{
+			name:           "All checks pass",
+			checks:         []HealthCheck{passingCheck("database"), passingCheck("migrations")},
+			expectedStatus: http.StatusOK,
+			expectedChecks: map[string]string{"database": "ok", "migrations": "ok"},
+		},
+		{
+			name:           "A check fails",
+			checks:         []HealthCheck{passingCheck("database"), failingCheck("pack_sizes")},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]string{"database": "ok", "pack_sizes": "fail"},
+		},
+		{
+			name:           "A check times out",
+			checks:         []HealthCheck{slow},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]string{"slow": "fail"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHealthHandler(tt.checks, nil, 10*time.Millisecond)
+
+			w := httptest.NewRecorder()
+			handler.Ready(w, httptest.NewRequest("GET", "/health/ready", nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			response := decodeHealth(t, w)
+			if len(response.Checks) != len(tt.expectedChecks) {
+				t.Fatalf("expected %d checks, got %d", len(tt.expectedChecks), len(response.Checks))
+			}
+			for _, check := range response.Checks {
+				if check.Status != tt.expectedChecks[check.Name] {
+					t.Errorf("expected check '%s' to be '%s', got '%s'", check.Name, tt.expectedChecks[check.Name], check.Status)
+				}
+				if check.Status == "fail" && check.Error == "" {
+					t.Errorf("expected an error for failing check '%s'", check.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthHandler_Startup(t *testing.T) {
+	ready := false
+	calls := 0
+	check := HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+		calls++
+		if !ready {
+			return errors.New("migration 0007 is pending")
+		}
+		return nil
+	}}
+	handler := NewHealthHandler(nil, []HealthCheck{check}, 0)
+
+	probe := func() int {
+		w := httptest.NewRecorder()
+		handler.Startup(w, httptest.NewRequest("GET", "/health/startup", nil))
+		return w.Code
+	}
+
+	if status := probe(); status != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d while starting, got %d", http.StatusServiceUnavailable, status)
+	}
+	ready = true
+	if status := probe(); status != http.StatusOK {
+		t.Errorf("expected status %d once started, got %d", http.StatusOK, status)
+	}
+	ready = false
+	if status := probe(); status != http.StatusOK {
+		t.Errorf("expected the startup probe to stay passing, got %d", status)
+	}
+	if calls != 2 {
+		t.Errorf("expected the checks to run 2 times, got %d", calls)
+	}
+}
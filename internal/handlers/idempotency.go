@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	idempotentReplayed   = "Idempotent-Replayed"
+	maxIdempotencyKeyLen = 255
+	defaultIdempotentTTL = 24 * time.Hour
+	// idempotencyLease is how long a claimed key stays in progress if its
+	// request never finishes, e.g. because the process died. It comfortably
+	// exceeds the server write timeout.
+	idempotencyLease = 5 * time.Minute
+)
+
+// Idempotency replays the stored response of POST requests retried with the same
+// Idempotency-Key header, so that retries after a timeout have no further effect
+type Idempotency struct {
+	records database.IdempotencyRepositoryInterface
+	ttl     atomic.Int64
+}
+
+func NewIdempotency(records database.IdempotencyRepositoryInterface, cfg config.IdempotencyConfig) (*Idempotency, error) {
+	ttl, err := IdempotencyTTL(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Idempotency{records: records}
+	m.SetTTL(ttl)
+	return m, nil
+}
+
+// IdempotencyTTL returns how long keys are kept, 24h unless configured
+func IdempotencyTTL(cfg config.IdempotencyConfig) (time.Duration, error) {
+	if cfg.TTL == "" {
+		return defaultIdempotentTTL, nil
+	}
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid idempotency ttl: %w", err)
+	}
+	return ttl, nil
+}
+
+// SetTTL changes how long keys claimed from now on are kept
+func (m *Idempotency) SetTTL(ttl time.Duration) {
+	m.ttl.Store(int64(ttl))
+}
+
+// Middleware handles POST requests carrying an Idempotency-Key. The first request
+// with a key runs normally and its response is stored; repeats get the stored
+// response. A repeat with a different method, path or body is rejected with 422,
+// and one arriving while the first is still running with 409. Keys are scoped to
+// the tenant and caller. Server errors and panics are not stored, so those can
+// be retried, as can requests that never finished once their lease runs out.
+func (m *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeError(w, fmt.Sprintf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLen), http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(r)
+		record, created, err := m.records.Begin(r.Context(), scope, key, fingerprint(r, body), time.Duration(m.ttl.Load()), idempotencyLease)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to check idempotency key", "error", err)
+			writeError(w, "Failed to check idempotency key", http.StatusInternalServerError)
+			return
+		}
+
+		if !created {
+			replay(w, record, fingerprint(r, body))
+			return
+		}
+
+		// The request context may already be cancelled once the response is written
+		ctx := context.WithoutCancel(r.Context())
+
+		// A panicking handler stored no response, so the key is released for retries
+		defer func() {
+			if p := recover(); p != nil {
+				if err := m.records.Release(ctx, scope, key); err != nil {
+					slog.ErrorContext(ctx, "Failed to release idempotency key", "error", err)
+				}
+				panic(p)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status >= http.StatusInternalServerError {
+			err = m.records.Release(ctx, scope, key)
+		} else {
+			err = m.records.Complete(ctx, scope, key, recorder.status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to store idempotent response", "error", err)
+		}
+	})
+}
+
+// replay answers a repeated request from the stored record
+func replay(w http.ResponseWriter, record *database.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		writeProblem(w, http.StatusUnprocessableEntity, codeIdempotencyKeyReuse, fmt.Sprintf("%s was already used with a different request", idempotencyKeyHeader), nil)
+		return
+	}
+	if record.StatusCode == 0 {
+		writeProblem(w, http.StatusConflict, codeIdempotencyKeyInUse, fmt.Sprintf("A request with this %s is still being processed", idempotencyKeyHeader), nil)
+		return
+	}
+
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set(idempotentReplayed, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// idempotencyScope identifies the caller a key belongs to
+func idempotencyScope(r *http.Request) string {
+	scope := "anonymous"
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		scope = principal.Subject
+	}
+	if t, ok := tenant.FromContext(r.Context()); ok {
+		scope = fmt.Sprintf("tenant:%d|%s", t.ID, scope)
+	}
+	return scope
+}
+
+// fingerprint hashes what makes two requests the same
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
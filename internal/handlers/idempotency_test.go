@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+)
+
+// Mock idempotency repository for testing
+type mockIdempotencyRepository struct {
+	records map[string]*database.IdempotencyRecord
+}
+
+func newMockIdempotencyRepository() *mockIdempotencyRepository {
+	return &mockIdempotencyRepository{records: make(map[string]*database.IdempotencyRecord)}
+}
+
+func (m *mockIdempotencyRepository) Begin(ctx context.Context, scope, key, fingerprint string, ttl, lease time.Duration) (*database.IdempotencyRecord, bool, error) {
+	if record, ok := m.records[scope+"|"+key]; ok {
+		return record, false, nil
+	}
+	record := &database.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint, ExpiresAt: time.Now().Add(ttl)}
+	m.records[scope+"|"+key] = record
+	return record, true, nil
+}
+
+func (m *mockIdempotencyRepository) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	record := m.records[scope+"|"+key]
+	record.StatusCode, record.ContentType, record.Body = statusCode, contentType, body
+	return nil
+}
+
+func (m *mockIdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	delete(m.records, scope+"|"+key)
+	return nil
+}
+
+func TestIdempotency_Middleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		requests       []string
+		keys           []string
+		handlerStatus  int
+		expectedStatus []int
+		expectedCalls  int
+	}{
+		{
+			name:           "Repeat is replayed",
+			requests:       []string{`{"size": 750}`, `{"size": 750}`},
+			keys:           []string{"a", "a"},
+			handlerStatus:  http.StatusCreated,
+			expectedStatus: []int{http.StatusCreated, http.StatusCreated},
+			expectedCalls:  1,
+		},
+		{
+			name:           "Different body with the same key",
+			requests:       []string{`{"size": 750}`, `{"size": 800}`},
+			keys:           []string{"a", "a"},
+			handlerStatus:  http.StatusCreated,
+			expectedStatus: []int{http.StatusCreated, http.StatusUnprocessableEntity},
+			expectedCalls:  1,
+		},
+		{
+			name:           "Different keys",
+			requests:       []string{`{"size": 750}`, `{"size": 750}`},
+			keys:           []string{"a", "b"},
+			handlerStatus:  http.StatusCreated,
+			expectedStatus: []int{http.StatusCreated, http.StatusCreated},
+			expectedCalls:  2,
+		},
+		{
+			name:           "Without a key",
+			requests:       []string{`{"size": 750}`, `{"size": 750}`},
+			keys:           []string{"", ""},
+			handlerStatus:  http.StatusCreated,
+			expectedStatus: []int{http.StatusCreated, http.StatusCreated},
+			expectedCalls:  2,
+		},
+		{
+			name:           "Server errors can be retried",
+			requests:       []string{`{"size": 750}`, `{"size": 750}`},
+			keys:           []string{"a", "a"},
+			handlerStatus:  http.StatusInternalServerError,
+			expectedStatus: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedCalls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idempotency, err := NewIdempotency(newMockIdempotencyRepository(), config.IdempotencyConfig{TTL: "1h"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			calls := 0
+			handler := idempotency.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				writeJSON(w, map[string]interface{}{"call": calls, "body": string(body)}, tt.handlerStatus)
+			}))
+
+			var first string
+			for i, body := range tt.requests {
+				req := httptest.NewRequest("POST", "/api/v1/pack-sizes", bytes.NewBufferString(body))
+				if tt.keys[i] != "" {
+					req.Header.Set(idempotencyKeyHeader, tt.keys[i])
+				}
+				w := httptest.NewRecorder()
+
+				handler.ServeHTTP(w, req)
+
+				if w.Code != tt.expectedStatus[i] {
+					t.Fatalf("request %d: expected status %d, got %d", i+1, tt.expectedStatus[i], w.Code)
+				}
+				if i == 0 {
+					first = w.Body.String()
+				} else if tt.expectedCalls == 1 && w.Code < 300 {
+					if w.Body.String() != first || w.Header().Get(idempotentReplayed) != "true" {
+						t.Errorf("expected replay of %q, got %q", first, w.Body.String())
+					}
+				}
+			}
+
+			if calls != tt.expectedCalls {
+				t.Errorf("expected handler to run %d times, ran %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	records := newMockIdempotencyRepository()
+	idempotency, err := NewIdempotency(records, config.IdempotencyConfig{TTL: "1h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := idempotency.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+
+	req := httptest.NewRequest("POST", "/api/v1/pack-sizes", bytes.NewBufferString(`{"size": 750}`))
+	req.Header.Set(idempotencyKeyHeader, "a")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to be passed on")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	if len(records.records) != 0 {
+		t.Errorf("expected the key to be released, got %v", records.records)
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/database"
+)
+
+const (
+	oidcStateCookieName = "packing_oidc"
+	oidcStateTTL        = 10 * time.Minute
+)
+
+// oidcState ties the provider callback to the login that started it
+type oidcState struct {
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// LoginHandler serves the web UI login with local users and, optionally, an OIDC provider
+type LoginHandler struct {
+	users         database.UserRepositoryInterface
+	tenants       database.TenantRepositoryInterface
+	sessions      *SessionManager
+	oidc          *auth.OIDCClient
+	defaultTenant string
+	templates     *template.Template
+}
+
+// NewLoginHandler creates a login handler. oidc may be nil to offer local users only.
+func NewLoginHandler(users database.UserRepositoryInterface, tenants database.TenantRepositoryInterface, sessions *SessionManager, oidc *auth.OIDCClient, defaultTenant string, templates fs.FS) (*LoginHandler, error) {
+	tmpl, err := template.ParseFS(templates, "*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	if defaultTenant == "" {
+		defaultTenant = defaultTenantSlug
+	}
+
+	return &LoginHandler{
+		users:         users,
+		tenants:       tenants,
+		sessions:      sessions,
+		oidc:          oidc,
+		defaultTenant: defaultTenant,
+		templates:     tmpl,
+	}, nil
+}
+
+type loginPageData struct {
+	CSRFToken   string
+	Username    string
+	Error       string
+	OIDCEnabled bool
+}
+
+// LoginPage renders the login form
+func (h *LoginHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, loginPageData{}, http.StatusOK)
+}
+
+// Login checks the credentials of a local user and starts a session
+func (h *LoginHandler) Login(w http.ResponseWriter, r *http.Request) {
+	username := r.PostFormValue("username")
+	password := r.PostFormValue("password")
+
+	user, err := h.users.GetByUsername(r.Context(), username)
+	if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+		slog.ErrorContext(r.Context(), "Failed to look up user", "error", err)
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || !auth.CheckPassword(password, user.PasswordHash) {
+		h.render(w, r, loginPageData{Username: username, Error: "Invalid username or password"}, http.StatusUnauthorized)
+		return
+	}
+
+	principal := auth.Principal{Subject: fmt.Sprintf("user:%d", user.ID), TenantID: user.TenantID}
+	for _, scope := range user.Scopes {
+		principal.Scopes = append(principal.Scopes, auth.Scope(scope))
+	}
+
+	if err := h.sessions.Login(w, principal, user.Username, user.TenantID, user.TenantSlug); err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Logout ends the session
+func (h *LoginHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	h.sessions.Logout(w)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// OIDCLogin redirects to the provider's authorization endpoint
+func (h *LoginHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := auth.RandomToken()
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := auth.RandomToken()
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	value, err := h.sessions.signer.Encode(oidcState{State: state, Nonce: nonce, ExpiresAt: time.Now().Add(oidcStateTTL)})
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, h.sessions.cookie(oidcStateCookieName, value, int(oidcStateTTL.Seconds())))
+
+	http.Redirect(w, r, h.oidc.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+// OIDCCallback completes the provider login and starts a session
+func (h *LoginHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.SetCookie(w, h.sessions.cookie(oidcStateCookieName, "", -1))
+
+	var state oidcState
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || h.sessions.signer.Decode(cookie.Value, &state) != nil ||
+		time.Now().After(state.ExpiresAt) || r.URL.Query().Get("state") != state.State {
+		h.render(w, r, loginPageData{Error: "Login expired, please try again"}, http.StatusBadRequest)
+		return
+	}
+
+	if providerErr := r.URL.Query().Get("error"); providerErr != "" {
+		h.render(w, r, loginPageData{Error: fmt.Sprintf("Login failed: %s", providerErr)}, http.StatusUnauthorized)
+		return
+	}
+
+	principal, err := h.oidc.Exchange(r.Context(), r.URL.Query().Get("code"), state.Nonce)
+	if err != nil {
+		slog.WarnContext(r.Context(), "OIDC login failed", "error", err)
+		h.render(w, r, loginPageData{Error: "Login failed"}, http.StatusUnauthorized)
+		return
+	}
+
+	slug := principal.TenantSlug
+	if slug == "" {
+		slug = h.defaultTenant
+	}
+	t, err := h.tenants.GetBySlug(r.Context(), slug)
+	if err != nil {
+		if errors.Is(err, database.ErrTenantNotFound) {
+			h.render(w, r, loginPageData{Error: fmt.Sprintf("Unknown tenant '%s'", slug)}, http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to resolve tenant", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.sessions.Login(w, principal, principal.Subject, t.ID, t.Slug); err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *LoginHandler) render(w http.ResponseWriter, r *http.Request, data loginPageData, status int) {
+	session, err := h.sessions.Current(w, r)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	data.CSRFToken = session.CSRFToken
+	data.OIDCEnabled = h.oidc != nil
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "login.html", data); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to render login template", "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+)
+
+const (
+	// DefaultPageSize is the number of pack sizes listed when no limit is given
+	DefaultPageSize = 100
+	// MaxPageSize is the largest limit a listing accepts
+	MaxPageSize = 1000
+)
+
+// packSizeQuery reads the listing parameters of GET /api/v1/pack-sizes:
+//
+//	limit          page size, 1-1000 (default 100)
+//	cursor         next_cursor of the previous page
+//	sort           size, created_at, updated_at or id; a leading dash sorts descending
+//	min_size       smallest pack size to include
+//	max_size       largest pack size to include
+//	created_since  only pack sizes created at or after this RFC 3339 time
+//	updated_since  only pack sizes updated at or after this RFC 3339 time
+//	active         true or false to list only active or inactive pack sizes
+//
+// Every invalid parameter is reported as a field error.
+func packSizeQuery(values url.Values) (database.PackSizeQuery, []models.FieldError) {
+	query := database.PackSizeQuery{Limit: DefaultPageSize}
+	var fields []models.FieldError
+	invalid := func(field, code, message string) {
+		fields = append(fields, models.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	intParam := func(name string, min, max int) int {
+		value := values.Get(name)
+		if value == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			invalid(name, "invalid_integer", fmt.Sprintf("%s must be an integer", name))
+			return 0
+		}
+		if n < min || n > max {
+			invalid(name, "out_of_range", fmt.Sprintf("%s must be between %d and %d", name, min, max))
+			return 0
+		}
+		return n
+	}
+	timeParam := func(name string) time.Time {
+		value := values.Get(name)
+		if value == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			invalid(name, "invalid_format", fmt.Sprintf("%s must be an RFC 3339 date-time", name))
+		}
+		return t
+	}
+
+	if limit := intParam("limit", 1, MaxPageSize); limit > 0 {
+		query.Limit = limit
+	}
+	query.MinSize = intParam("min_size", 1, maxInt)
+	query.MaxSize = intParam("max_size", 1, maxInt)
+	if query.MinSize > 0 && query.MaxSize > 0 && query.MinSize > query.MaxSize {
+		invalid("min_size", "out_of_range", "min_size must not be greater than max_size")
+	}
+	query.CreatedSince = timeParam("created_since")
+	query.UpdatedSince = timeParam("updated_since")
+
+	if value := values.Get("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			invalid("active", "invalid_boolean", "active must be true or false")
+		} else {
+			query.Active = &active
+		}
+	}
+
+	sort, err := database.ParsePackSizeSort(values.Get("sort"))
+	if err != nil {
+		invalid("sort", "invalid_value", err.Error())
+	}
+	query.Sort = sort
+
+	if value := values.Get("cursor"); value != "" {
+		cursor, err := database.DecodePackSizeCursor(value)
+		if err != nil {
+			invalid("cursor", "invalid_format", "cursor must be the next_cursor of a previous page")
+		} else {
+			query.After = cursor
+		}
+	}
+
+	return query, fields
+}
+
+const maxInt = int(^uint(0) >> 1)
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/broadcast"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// DefaultStreamHeartbeat is how often an idle stream sends a comment, so that
+// proxies keep the connection open
+const DefaultStreamHeartbeat = 20 * time.Second
+
+// streamRetry is the reconnection delay suggested to clients, in milliseconds
+const streamRetry = 3000
+
+// PackSizeStream serves the pack size changes of the current tenant as
+// Server-Sent Events
+type PackSizeStream struct {
+	broker    *broadcast.Broker
+	heartbeat time.Duration
+}
+
+func NewPackSizeStream(broker *broadcast.Broker, heartbeat time.Duration) *PackSizeStream {
+	return &PackSizeStream{broker: broker, heartbeat: heartbeat}
+}
+
+// Stream sends an event for every pack size that is created, updated or
+// deleted, with the pack size as data. A resync event means changes may have
+// been missed. The stream ends when the server shuts down or the client falls
+// behind; clients reconnect and should then reload the pack sizes.
+func (h *PackSizeStream) Stream(w http.ResponseWriter, r *http.Request) {
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		writeErrorFor(w, r, database.ErrNoTenant, "Failed to stream pack sizes")
+		return
+	}
+
+	// The stream outlives the server read and write timeouts
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeErrorFor(w, r, err, "Failed to stream pack sizes")
+		return
+	}
+	rc.SetReadDeadline(time.Time{})
+
+	subscription := h.broker.Subscribe(t.ID)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			writeEvent(w, m)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes a message in the event stream format
+func writeEvent(w http.ResponseWriter, m broadcast.Message) {
+	fmt.Fprintf(w, "event: %s\n", m.Event)
+	data := m.Data
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/broadcast"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+func TestPackSizeStream(t *testing.T) {
+	broker := broadcast.NewBroker()
+	stream := NewPackSizeStream(broker, 50*time.Millisecond)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tenant.WithTenant(r.Context(), tenant.Tenant{ID: 1, Slug: "acme"})
+		stream.Stream(w, r.WithContext(ctx))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	// readEvent returns the lines of the next event or comment
+	readEvent := func() []string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if lines := readEvent(); len(lines) != 1 || lines[0] != "retry: 3000" {
+		t.Fatalf("expected the retry delay first, got %q", lines)
+	}
+
+	// Changes of other tenants are not sent
+	broker.Publish(broadcast.Message{TenantID: 2, Event: "pack_size.created", Data: []byte(`{"id":9}`)})
+	broker.Publish(broadcast.Message{TenantID: 1, Event: "pack_size.deleted", Data: []byte(`{"id":5,"size":250}`)})
+
+	expected := []string{"event: pack_size.deleted", `data: {"id":5,"size":250}`}
+	if lines := readEvent(); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if lines := readEvent(); len(lines) != 1 || lines[0] != ": heartbeat" {
+		t.Errorf("expected a heartbeat, got %q", lines)
+	}
+
+	// The stream ends when the broker closes, e.g. on shutdown
+	broker.Close()
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Error("expected the stream to end")
+	}
+}
+
+func TestPackSizeStream_NoTenant(t *testing.T) {
+	stream := NewPackSizeStream(broadcast.NewBroker(), time.Second)
+	w := httptest.NewRecorder()
+	stream.Stream(w, httptest.NewRequest("GET", "/api/v1/pack-sizes/stream", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/ratelimit"
+)
+
+const (
+	anonymousRole                 = "anonymous"
+	defaultRateLimitRate          = 10
+	defaultRateLimitBurst         = 20
+	defaultCalculateItemsPerToken = 100000
+	// maxCostPeekBytes limits how much of a request body is read to work out its cost
+	maxCostPeekBytes = 1 << 20
+)
+
+// CostFunc returns the number of tokens a request costs
+type CostFunc func(r *http.Request) float64
+
+type rateLimitRule struct {
+	name   string
+	method string
+	path   string
+	role   string
+	limit  ratelimit.Limit
+}
+
+// matches reports whether the rule applies to a request for the route by the principal
+func (rule rateLimitRule) matches(method, path string, principal auth.Principal, authenticated bool) bool {
+	if rule.method != "" && rule.method != method {
+		return false
+	}
+	if rule.path != "" && rule.path != path {
+		return false
+	}
+	switch rule.role {
+	case "":
+		return true
+	case anonymousRole:
+		return !authenticated
+	default:
+		return authenticated && principal.Has(auth.Scope(rule.role))
+	}
+}
+
+// RateLimiter applies token bucket limits per client and route
+type RateLimiter struct {
+	store  ratelimit.Store
+	policy atomic.Pointer[RateLimitPolicy]
+
+	mu    sync.RWMutex
+	costs map[string]CostFunc
+}
+
+// RateLimitPolicy holds validated rate limit settings, which can be swapped in
+// at runtime with SetPolicy
+type RateLimitPolicy struct {
+	enabled           bool
+	rules             []rateLimitRule
+	defaultLimit      ratelimit.Limit
+	trustForwardedFor bool
+	trustedProxies    int
+}
+
+func NewRateLimiter(store ratelimit.Store, cfg config.RateLimitConfig) (*RateLimiter, error) {
+	policy, err := NewRateLimitPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := &RateLimiter{
+		store: store,
+		costs: make(map[string]CostFunc),
+	}
+	limiter.SetPolicy(policy)
+	return limiter, nil
+}
+
+// NewRateLimitPolicy validates the limits and rules of cfg. The store is not
+// part of the policy.
+func NewRateLimitPolicy(cfg config.RateLimitConfig) (*RateLimitPolicy, error) {
+	policy := &RateLimitPolicy{
+		enabled:           cfg.Enabled,
+		defaultLimit:      ratelimit.Limit{Rate: cfg.Rate, Burst: cfg.Burst},
+		trustForwardedFor: cfg.TrustForwardedFor,
+		trustedProxies:    cfg.TrustedProxies,
+	}
+	if policy.trustedProxies <= 0 {
+		policy.trustedProxies = 1
+	}
+	if policy.defaultLimit.Rate <= 0 {
+		policy.defaultLimit.Rate = defaultRateLimitRate
+	}
+	if policy.defaultLimit.Burst <= 0 {
+		policy.defaultLimit.Burst = defaultRateLimitBurst
+	}
+
+	for i, rule := range cfg.Rules {
+		if rule.Rate <= 0 || rule.Burst <= 0 {
+			return nil, fmt.Errorf("rate limit rule %d: rate and burst must be positive", i+1)
+		}
+		if rule.Role != "" && rule.Role != anonymousRole {
+			if _, err := auth.ParseScopes([]string{rule.Role}); err != nil {
+				return nil, fmt.Errorf("rate limit rule %d: %w", i+1, err)
+			}
+		}
+
+		parsed := rateLimitRule{
+			name:  fmt.Sprintf("rule%d", i+1),
+			role:  rule.Role,
+			limit: ratelimit.Limit{Rate: rule.Rate, Burst: rule.Burst},
+		}
+		if method, path, ok := strings.Cut(rule.Route, " "); ok {
+			parsed.method, parsed.path = strings.ToUpper(method), strings.TrimSpace(path)
+		} else {
+			parsed.path = rule.Route
+		}
+		policy.rules = append(policy.rules, parsed)
+	}
+
+	return policy, nil
+}
+
+// SetPolicy replaces the limits and rules. Buckets are kept, so clients do not
+// get a fresh burst when the limits change.
+func (l *RateLimiter) SetPolicy(policy *RateLimitPolicy) {
+	l.policy.Store(policy)
+}
+
+// SetCost charges requests to the route (e.g. "POST /api/v1/calculate") by cost
+// instead of one token each
+func (l *RateLimiter) SetCost(route string, cost CostFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.costs[route] = cost
+}
+
+// Middleware takes tokens from the client's bucket for the matched route and
+// rejects the request with 429 once it is empty. It must run after authentication.
+// When the store fails or the policy is disabled the request is let through.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := l.policy.Load()
+		if !policy.enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				path = template
+			}
+		}
+
+		principal, authenticated := auth.FromContext(r.Context())
+		bucket, limit := "default", policy.defaultLimit
+		for _, rule := range policy.rules {
+			if rule.matches(r.Method, path, principal, authenticated) {
+				bucket, limit = rule.name, rule.limit
+				break
+			}
+		}
+
+		cost := 1.0
+		l.mu.RLock()
+		costFunc, ok := l.costs[r.Method+" "+path]
+		l.mu.RUnlock()
+		if ok {
+			cost = costFunc(r)
+		}
+
+		client := "ip:" + policy.clientIP(r)
+		if authenticated {
+			client = principal.Subject
+		}
+
+		result, err := l.store.Take(r.Context(), bucket+"|"+client, limit, cost)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Rate limit store failed, allowing request", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(int(limit.Burst)))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(result.Remaining))))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			writeProblem(w, http.StatusTooManyRequests, codeRateLimited, "Rate limit exceeded", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the address of the client, honoring X-Forwarded-For when
+// trusted. Each proxy appends the address it received the request from, so
+// the client is the entry added by the outermost trusted proxy; entries to the
+// left of it are set by the client and can be spoofed.
+func (p *RateLimitPolicy) clientIP(r *http.Request) string {
+	if p.trustForwardedFor {
+		var entries []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) > 0 {
+			return entries[max(len(entries)-p.trustedProxies, 0)]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// CalculateCost charges a calculation one token plus one per itemsPerToken items
+// ordered, as the solver's work grows with the quantity
+func CalculateCost(itemsPerToken int) CostFunc {
+	if itemsPerToken <= 0 {
+		itemsPerToken = defaultCalculateItemsPerToken
+	}
+
+	return func(r *http.Request) float64 {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxCostPeekBytes))
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		if err != nil {
+			return 1
+		}
+
+		var req struct {
+			Items int `json:"items"`
+		}
+		if json.Unmarshal(body, &req) != nil || req.Items <= 0 {
+			return 1
+		}
+		return 1 + float64(req.Items/itemsPerToken)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/ratelimit"
+)
+
+func newRateLimitedRouter(t *testing.T, cfg config.RateLimitConfig) http.Handler {
+	t.Helper()
+
+	limiter, err := NewRateLimiter(ratelimit.NewMemoryStore(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	limiter.SetCost("POST /api/v1/calculate", CalculateCost(1000))
+
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get("X-Test-Scope"); key != "" {
+				principal := auth.Principal{Subject: "api-key:" + key, Scopes: []auth.Scope{auth.Scope(key)}}
+				r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, limiter.Middleware)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		// The handler must still see the full body after its cost was worked out
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}
+	router.HandleFunc("/api/v1/calculate", ok).Methods("POST")
+	router.HandleFunc("/api/v1/pack-sizes/{id}", ok).Methods("GET")
+	return router
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled: true,
+		Rate:    0.001,
+		Burst:   3,
+		Rules: []config.RateLimitRule{
+			{Route: "/api/v1/pack-sizes/{id}", Role: "admin", Rate: 0.001, Burst: 5},
+			{Route: "GET /api/v1/pack-sizes/{id}", Rate: 0.001, Burst: 1},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		scope    string
+		expected []int
+	}{
+		{name: "Default limit", method: "POST", path: "/api/v1/calculate", body: `{"items": 1}`, expected: []int{200, 200, 200, 429}},
+		{name: "Cost by quantity", method: "POST", path: "/api/v1/calculate", body: `{"items": 2000}`, expected: []int{200, 429}},
+		{name: "Route rule matches the path template", method: "GET", path: "/api/v1/pack-sizes/1", expected: []int{200, 429}},
+		{name: "Role rule", method: "GET", path: "/api/v1/pack-sizes/2", scope: "admin", expected: []int{200, 200, 200, 200, 200, 429}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRateLimitedRouter(t, cfg)
+
+			for i, expected := range tt.expected {
+				req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+				if tt.scope != "" {
+					req.Header.Set("X-Test-Scope", tt.scope)
+				}
+				w := httptest.NewRecorder()
+
+				router.ServeHTTP(w, req)
+
+				if w.Code != expected {
+					t.Fatalf("request %d: expected status %d, got %d", i+1, expected, w.Code)
+				}
+				if w.Header().Get("RateLimit-Limit") == "" || w.Header().Get("RateLimit-Remaining") == "" || w.Header().Get("RateLimit-Reset") == "" {
+					t.Errorf("request %d: expected RateLimit headers, got %v", i+1, w.Header())
+				}
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: expected Retry-After header", i+1)
+				}
+				if w.Code == http.StatusOK && w.Body.String() != tt.body {
+					t.Errorf("request %d: expected body %q to reach the handler, got %q", i+1, tt.body, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestNewRateLimiter_InvalidRule(t *testing.T) {
+	_, err := NewRateLimiter(ratelimit.NewMemoryStore(), config.RateLimitConfig{
+		Rules: []config.RateLimitRule{{Role: "superuser", Rate: 1, Burst: 1}},
+	})
+	if err == nil {
+		t.Errorf("expected error for unknown role")
+	}
+}
+
+func TestRateLimiter_SetPolicy(t *testing.T) {
+	limiter, err := NewRateLimiter(ratelimit.NewMemoryStore(), config.RateLimitConfig{Enabled: true, Rate: 0.001, Burst: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func() int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/config", nil))
+		return w.Code
+	}
+
+	if status := request(); status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+	if status := request(); status != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d once the burst is used, got %d", http.StatusTooManyRequests, status)
+	}
+
+	policy, err := NewRateLimitPolicy(config.RateLimitConfig{Enabled: false})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	limiter.SetPolicy(policy)
+	if status := request(); status != http.StatusOK {
+		t.Errorf("expected a disabled policy to let requests through, got %d", status)
+	}
+}
+
+func TestRateLimitPolicy_ClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            config.RateLimitConfig
+		forwardedFor   []string
+		expectedClient string
+	}{
+		{
+			name:           "Forwarded for ignored when not trusted",
+			forwardedFor:   []string{"203.0.113.7"},
+			expectedClient: "192.0.2.1",
+		},
+		{
+			name:           "Single proxy",
+			cfg:            config.RateLimitConfig{TrustForwardedFor: true},
+			forwardedFor:   []string{"203.0.113.7"},
+			expectedClient: "203.0.113.7",
+		},
+		{
+			name:           "Spoofed entries before the proxy",
+			cfg:            config.RateLimitConfig{TrustForwardedFor: true},
+			forwardedFor:   []string{"10.0.0.1, 198.51.100.9, 203.0.113.7"},
+			expectedClient: "203.0.113.7",
+		},
+		{
+			name:           "Two trusted proxies",
+			cfg:            config.RateLimitConfig{TrustForwardedFor: true, TrustedProxies: 2},
+			forwardedFor:   []string{"10.0.0.1, 203.0.113.7", "172.16.0.4"},
+			expectedClient: "203.0.113.7",
+		},
+		{
+			name:           "Fewer entries than trusted proxies",
+			cfg:            config.RateLimitConfig{TrustForwardedFor: true, TrustedProxies: 3},
+			forwardedFor:   []string{"203.0.113.7, 172.16.0.4"},
+			expectedClient: "203.0.113.7",
+		},
+		{
+			name:           "No forwarded for header",
+			cfg:            config.RateLimitConfig{TrustForwardedFor: true},
+			expectedClient: "192.0.2.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewRateLimitPolicy(tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/api/v1/config", nil)
+			req.RemoteAddr = "192.0.2.1:4321"
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			if client := policy.clientIP(req); client != tt.expectedClient {
+				t.Errorf("expected client %s, got %s", tt.expectedClient, client)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_SpoofedForwardedFor(t *testing.T) {
+	handler := newRateLimitedRouter(t, config.RateLimitConfig{Enabled: true, Rate: 0.001, Burst: 1, TrustForwardedFor: true})
+
+	// A client rotating the left-most entry still gets a single bucket
+	for i, spoofed := range []string{"10.0.0.1", "10.0.0.2"} {
+		req := httptest.NewRequest("GET", "/api/v1/pack-sizes/1", nil)
+		req.Header.Set("X-Forwarded-For", spoofed+", 203.0.113.7")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		expected := http.StatusOK
+		if i > 0 {
+			expected = http.StatusTooManyRequests
+		}
+		if w.Code != expected {
+			t.Errorf("request %d: expected status %d, got %d", i+1, expected, w.Code)
+		}
+	}
+}
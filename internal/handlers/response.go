@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/service"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	// problemTypePrefix starts the type URI of every problem, followed by its code
+	problemTypePrefix = "urn:packing-service:problem:"
+)
+
+// Error codes that clients can rely on in addition to the ones derived from the status
+const (
+	codeInvalidBody         = "invalid_body"
+	codeBodyTooLarge        = "body_too_large"
+	codeValidationFailed    = "validation_failed"
+	codeNotFound            = "not_found"
+	codeInvalidCursor       = "invalid_cursor"
+	codeDuplicateSize       = "duplicate_size"
+	codeDuplicateTenant     = "duplicate_tenant"
+	codeUnknownTenant       = "unknown_tenant"
+	codeNoPackSizes         = "no_pack_sizes"
+	codeUnreachableQuantity = "unreachable_quantity"
+	codeTenantRequired      = "tenant_required"
+	codeCSRFTokenInvalid    = "csrf_token_invalid"
+	codeRateLimited         = "rate_limited"
+	codeIdempotencyKeyReuse = "idempotency_key_reused"
+	codeIdempotencyKeyInUse = "idempotency_key_in_use"
+	codeInternalError       = "internal_error"
+)
+
+// errorMappings maps typed service and database errors to problems. The first
+// mapping the error matches with errors.Is applies.
+var errorMappings = []struct {
+	target error
+	status int
+	code   string
+}{
+	{database.ErrDuplicateSize, http.StatusConflict, codeDuplicateSize},
+	{database.ErrDuplicateTenant, http.StatusConflict, codeDuplicateTenant},
+	{database.ErrNotFound, http.StatusNotFound, codeNotFound},
+	{database.ErrInvalidCursor, http.StatusBadRequest, codeInvalidCursor},
+	{database.ErrNoTenant, http.StatusBadRequest, codeTenantRequired},
+	{service.ErrNoPackSizes, http.StatusUnprocessableEntity, codeNoPackSizes},
+	{service.ErrUnreachable, http.StatusUnprocessableEntity, codeUnreachableQuantity},
+}
+
+// writeJSON encodes data as the JSON response body
+func writeJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// writeProblem sends an application/problem+json response
+func writeProblem(w http.ResponseWriter, status int, code, detail string, fields []models.FieldError) {
+	problem := models.Problem{
+		Type:   problemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Error:  detail,
+		Errors: fields,
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// writeError sends a problem with the given message and a code derived from the status
+func writeError(w http.ResponseWriter, message string, status int) {
+	writeProblem(w, status, statusCode(status), message, nil)
+}
+
+// writeErrorFor maps err to a problem. Errors without a mapping are logged and
+// reported as internal errors with the fallback message, so that driver
+// messages never reach clients.
+func writeErrorFor(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		fields := make([]models.FieldError, len(validationErr.Fields))
+		for i, field := range validationErr.Fields {
+			fields[i] = models.FieldError{Field: field.Field, Code: field.Code, Message: field.Message}
+		}
+		writeProblem(w, http.StatusBadRequest, codeValidationFailed, validationErr.Error(), fields)
+		return
+	}
+
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.target) {
+			writeProblem(w, mapping.status, mapping.code, err.Error(), nil)
+			return
+		}
+	}
+
+	slog.ErrorContext(r.Context(), fallback, "error", err)
+	writeProblem(w, http.StatusInternalServerError, codeInternalError, fallback, nil)
+}
+
+// statusCode derives an error code from an HTTP status, e.g. "too_many_requests"
+func statusCode(status int) string {
+	if status == http.StatusInternalServerError {
+		return codeInternalError
+	}
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
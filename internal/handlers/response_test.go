@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/service"
+)
+
+func TestWriteErrorFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedDetail string
+		expectedFields int
+	}{
+		{
+			name:           "Duplicate size",
+			err:            fmt.Errorf("%w: %d", database.ErrDuplicateSize, 250),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   codeDuplicateSize,
+			expectedDetail: "pack size already exists: 250",
+		},
+		{
+			name:           "Not found",
+			err:            fmt.Errorf("pack size with id 7: %w", database.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codeNotFound,
+			expectedDetail: "pack size with id 7: not found",
+		},
+		{
+			name:           "Tenant not found",
+			err:            database.ErrTenantNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codeNotFound,
+			expectedDetail: "tenant not found",
+		},
+		{
+			name:           "No pack sizes",
+			err:            service.ErrNoPackSizes,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codeNoPackSizes,
+			expectedDetail: "no pack sizes configured",
+		},
+		{
+			name:           "Unreachable",
+			err:            service.ErrUnreachable,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codeUnreachableQuantity,
+			expectedDetail: "unable to fulfill order with current pack sizes",
+		},
+		{
+			name: "Validation error",
+			err: &service.ValidationError{Fields: []service.FieldError{
+				{Field: "size", Code: "must_be_positive", Message: "Pack size must be positive"},
+				{Field: "id", Code: "invalid_integer", Message: "Invalid ID"},
+			}},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeValidationFailed,
+			expectedDetail: "Pack size must be positive; Invalid ID",
+			expectedFields: 2,
+		},
+		{
+			name:           "Unknown error is not leaked",
+			err:            errors.New("pq: connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   codeInternalError,
+			expectedDetail: "Fallback message",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			writeErrorFor(w, httptest.NewRequest("GET", "/", nil), tt.err, "Fallback message")
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != problemContentType {
+				t.Errorf("expected content type %s, got %s", problemContentType, contentType)
+			}
+
+			var problem models.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("failed to unmarshal problem: %v", err)
+			}
+			if problem.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, problem.Code)
+			}
+			if problem.Type != problemTypePrefix+tt.expectedCode {
+				t.Errorf("expected type %s, got %s", problemTypePrefix+tt.expectedCode, problem.Type)
+			}
+			if problem.Status != tt.expectedStatus {
+				t.Errorf("expected status field %d, got %d", tt.expectedStatus, problem.Status)
+			}
+			if problem.Detail != tt.expectedDetail || problem.Error != tt.expectedDetail {
+				t.Errorf("expected detail %q, got detail %q and error %q", tt.expectedDetail, problem.Detail, problem.Error)
+			}
+			if len(problem.Errors) != tt.expectedFields {
+				t.Errorf("expected %d field errors, got %d", tt.expectedFields, len(problem.Errors))
+			}
+		})
+	}
+}
+
+func TestWriteError_DerivesCode(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	writeError(w, "Missing credentials", http.StatusUnauthorized)
+
+	var problem models.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("failed to unmarshal problem: %v", err)
+	}
+	if problem.Code != "unauthorized" {
+		t.Errorf("expected code unauthorized, got %s", problem.Code)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+const (
+	sessionCookieName = "packing_session"
+	csrfHeader        = "X-CSRF-Token"
+	csrfFormField     = "csrf_token"
+	defaultSessionTTL = 12 * time.Hour
+)
+
+// SessionManager keeps web UI sessions in signed cookies and enforces CSRF tokens
+// on state-changing requests made with them
+type SessionManager struct {
+	signer *auth.Signer
+	ttl    time.Duration
+	secure bool
+}
+
+func NewSessionManager(cfg config.SessionConfig) (*SessionManager, error) {
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		slog.Warn("auth.session.secret is not set; sessions will not survive a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate session secret: %w", err)
+		}
+	}
+
+	ttl := defaultSessionTTL
+	if cfg.TTL != "" {
+		parsed, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session ttl: %w", err)
+		}
+		ttl = parsed
+	}
+
+	return &SessionManager{
+		signer: auth.NewSigner(secret),
+		ttl:    ttl,
+		secure: cfg.SecureCookie,
+	}, nil
+}
+
+// Middleware loads the session cookie into the request context. State-changing
+// requests that carry the cookie must echo its CSRF token in the X-CSRF-Token
+// header or the csrf_token form field. A logged in session also supplies the
+// principal and tenant. Requests authenticated otherwise (API keys, bearer
+// tokens) are left alone, as they carry no ambient credentials.
+func (m *SessionManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.FromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session, ok := m.load(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !isSafeMethod(r.Method) && !validCSRFToken(r, session.CSRFToken) {
+			writeProblem(w, http.StatusForbidden, codeCSRFTokenInvalid, "Invalid or missing CSRF token", nil)
+			return
+		}
+
+		ctx := auth.WithSession(r.Context(), session)
+		if session.Authenticated() {
+			ctx = auth.WithPrincipal(ctx, session.Principal())
+			ctx = tenant.WithTenant(ctx, tenant.Tenant{ID: session.TenantID, Slug: session.TenantSlug})
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireCSRF rejects state-changing requests that did not pass the CSRF check
+// of Middleware, i.e. were made without a session. It guards the web forms.
+func (m *SessionManager) RequireCSRF(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.SessionFromContext(r.Context()); !ok && !isSafeMethod(r.Method) {
+			writeProblem(w, http.StatusForbidden, codeCSRFTokenInvalid, "Invalid or missing CSRF token", nil)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Current returns the session of the request, starting an anonymous one if needed
+func (m *SessionManager) Current(w http.ResponseWriter, r *http.Request) (auth.Session, error) {
+	if session, ok := auth.SessionFromContext(r.Context()); ok {
+		return session, nil
+	}
+	if session, ok := m.load(r); ok {
+		return session, nil
+	}
+
+	session, err := auth.NewSession(m.ttl)
+	if err != nil {
+		return auth.Session{}, err
+	}
+	return session, m.save(w, session)
+}
+
+// Login replaces the session with a logged in one. The CSRF token is rotated.
+func (m *SessionManager) Login(w http.ResponseWriter, principal auth.Principal, name string, tenantID int, tenantSlug string) error {
+	session, err := auth.NewSession(m.ttl)
+	if err != nil {
+		return err
+	}
+	session.Subject = principal.Subject
+	session.Name = name
+	session.Scopes = principal.Scopes
+	session.TenantID = tenantID
+	session.TenantSlug = tenantSlug
+
+	return m.save(w, session)
+}
+
+// Logout clears the session cookie
+func (m *SessionManager) Logout(w http.ResponseWriter) {
+	http.SetCookie(w, m.cookie(sessionCookieName, "", -1))
+}
+
+// load returns the valid session carried by the request cookie, if any
+func (m *SessionManager) load(r *http.Request) (auth.Session, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return auth.Session{}, false
+	}
+	session, err := m.signer.DecodeSession(cookie.Value)
+	if err != nil {
+		return auth.Session{}, false
+	}
+	return session, true
+}
+
+func (m *SessionManager) save(w http.ResponseWriter, session auth.Session) error {
+	value, err := m.signer.Encode(session)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, m.cookie(sessionCookieName, value, int(m.ttl.Seconds())))
+	return nil
+}
+
+func (m *SessionManager) cookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// validCSRFToken compares the token sent with the request to the session's
+func validCSRFToken(r *http.Request, expected string) bool {
+	token := r.Header.Get(csrfHeader)
+	if token == "" {
+		token = r.PostFormValue(csrfFormField)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
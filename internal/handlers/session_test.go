@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// Mock user repository for testing
+type mockUserRepository struct {
+	users map[string]database.User
+}
+
+func (m *mockUserRepository) GetByUsername(ctx context.Context, username string) (*database.User, error) {
+	user, ok := m.users[username]
+	if !ok {
+		return nil, fmt.Errorf("user '%s': %w", username, database.ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+func (m *mockUserRepository) Upsert(ctx context.Context, tenantID int, username, passwordHash string, scopes []string) (*database.User, error) {
+	user := database.User{ID: len(m.users) + 1, TenantID: tenantID, Username: username, PasswordHash: passwordHash, Scopes: scopes}
+	m.users[username] = user
+	return &user, nil
+}
+
+// sessionCookie returns the session cookie set on the response, if any
+func sessionCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestSessionManager_CSRF(t *testing.T) {
+	sessions, err := NewSessionManager(config.SessionConfig{Secret: "test-secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Start an anonymous session to obtain a cookie and its CSRF token
+	w := httptest.NewRecorder()
+	session, err := sessions.Current(w, httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cookie := sessionCookie(w)
+	if cookie == nil {
+		t.Fatalf("expected a session cookie")
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		cookie         bool
+		header         string
+		form           string
+		expectedStatus int
+	}{
+		{name: "GET without token", method: "GET", cookie: true, expectedStatus: http.StatusOK},
+		{name: "POST without session", method: "POST", expectedStatus: http.StatusForbidden},
+		{name: "POST without token", method: "POST", cookie: true, expectedStatus: http.StatusForbidden},
+		{name: "POST with wrong token", method: "POST", cookie: true, header: "forged", expectedStatus: http.StatusForbidden},
+		{name: "POST with header token", method: "POST", cookie: true, header: session.CSRFToken, expectedStatus: http.StatusOK},
+		{name: "POST with form token", method: "POST", cookie: true, form: session.CSRFToken, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := sessions.Middleware(sessions.RequireCSRF(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			var req *http.Request
+			if tt.form != "" {
+				req = httptest.NewRequest(tt.method, "/", strings.NewReader(url.Values{csrfFormField: {tt.form}}.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(tt.method, "/", nil)
+			}
+			if tt.cookie {
+				req.AddCookie(cookie)
+			}
+			if tt.header != "" {
+				req.Header.Set(csrfHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestLoginHandler_Login(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	users := &mockUserRepository{users: map[string]database.User{
+		"alice": {ID: 7, TenantID: 2, TenantSlug: "acme", Username: "alice", PasswordHash: hash, Scopes: []string{"manage"}},
+	}}
+
+	sessions, err := NewSessionManager(config.SessionConfig{Secret: "test-secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	templates := fstest.MapFS{"login.html": {Data: []byte(`{{.Error}}`)}}
+	handler, err := NewLoginHandler(users, newMockTenantRepository(), sessions, nil, "", templates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		username       string
+		password       string
+		expectedStatus int
+	}{
+		{name: "Valid credentials", username: "alice", password: "correct horse", expectedStatus: http.StatusSeeOther},
+		{name: "Wrong password", username: "alice", password: "wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "Unknown user", username: "bob", password: "correct horse", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"username": {tt.username}, "password": {tt.password}}
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			handler.Login(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Code != http.StatusSeeOther {
+				return
+			}
+
+			// The new session authenticates follow-up requests as the user
+			cookie := sessionCookie(w)
+			if cookie == nil {
+				t.Fatalf("expected a session cookie")
+			}
+			var principal auth.Principal
+			var current tenant.Tenant
+			check := sessions.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = auth.FromContext(r.Context())
+				current, _ = tenant.FromContext(r.Context())
+			}))
+			followUp := httptest.NewRequest("GET", "/", nil)
+			followUp.AddCookie(cookie)
+			check.ServeHTTP(httptest.NewRecorder(), followUp)
+
+			if principal.Subject != "user:7" || !principal.Has(auth.ScopeManage) || current.Slug != "acme" {
+				t.Errorf("unexpected principal %+v in tenant %+v", principal, current)
+			}
+		})
+	}
+}
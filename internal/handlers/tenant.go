@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+const (
+	defaultTenantHeader = "X-Tenant-ID"
+	defaultTenantSlug   = "default"
+	adminTokenHeader    = "X-Admin-Token"
+)
+
+// DefaultTenantPackSizes seed new tenants when no pack sizes are configured or requested
+var DefaultTenantPackSizes = []int{250, 500, 1000, 2000, 5000}
+
+// TenantResolver maps each request to a tenant and stores it in the request context
+type TenantResolver struct {
+	tenants       database.TenantRepositoryInterface
+	header        string
+	defaultTenant string
+	required      bool
+}
+
+func NewTenantResolver(tenants database.TenantRepositoryInterface, cfg config.TenancyConfig) *TenantResolver {
+	resolver := &TenantResolver{
+		tenants:       tenants,
+		header:        cfg.Header,
+		defaultTenant: cfg.DefaultTenant,
+		required:      cfg.RequireTenant,
+	}
+	if resolver.header == "" {
+		resolver.header = defaultTenantHeader
+	}
+	if resolver.defaultTenant == "" {
+		resolver.defaultTenant = defaultTenantSlug
+	}
+	return resolver
+}
+
+// Middleware resolves the tenant from the tenant header, falling back to the
+// default tenant. A tenant already in the context (e.g. set by authentication)
+// or named by the caller's bearer token takes precedence, and a header naming
+// another tenant is rejected.
+func (tr *TenantResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(tr.header)
+		if current, ok := tenant.FromContext(r.Context()); ok {
+			if header != "" && header != current.Slug {
+				writeError(w, fmt.Sprintf("Not allowed to access tenant '%s'", header), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		slug := header
+		if principal, ok := auth.FromContext(r.Context()); ok && principal.TenantSlug != "" {
+			if header != "" && header != principal.TenantSlug {
+				writeError(w, fmt.Sprintf("Not allowed to access tenant '%s'", header), http.StatusForbidden)
+				return
+			}
+			slug = principal.TenantSlug
+		}
+		if slug == "" {
+			if tr.required {
+				writeProblem(w, http.StatusBadRequest, codeTenantRequired, fmt.Sprintf("Missing %s header", tr.header), nil)
+				return
+			}
+			slug = tr.defaultTenant
+		}
+
+		t, err := tr.tenants.GetBySlug(r.Context(), slug)
+		if err != nil {
+			// An unknown tenant is a bad request rather than a missing resource
+			if errors.Is(err, database.ErrTenantNotFound) {
+				writeProblem(w, http.StatusBadRequest, codeUnknownTenant, fmt.Sprintf("Unknown tenant '%s'", slug), nil)
+				return
+			}
+			writeErrorFor(w, r, err, "Failed to resolve tenant")
+			return
+		}
+
+		ctx := tenant.WithTenant(r.Context(), tenant.Tenant{ID: t.ID, Slug: t.Slug})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TenantHandler serves the admin API for provisioning tenants
+type TenantHandler struct {
+	tenants          database.TenantRepositoryInterface
+	adminToken       string
+	defaultPackSizes []int
+	validator        *RequestValidator
+}
+
+func NewTenantHandler(tenants database.TenantRepositoryInterface, cfg config.TenancyConfig, validator *RequestValidator) *TenantHandler {
+	packSizes := cfg.DefaultPackSizes
+	if len(packSizes) == 0 {
+		packSizes = DefaultTenantPackSizes
+	}
+	return &TenantHandler{
+		tenants:          tenants,
+		adminToken:       cfg.AdminToken,
+		defaultPackSizes: packSizes,
+		validator:        validator,
+	}
+}
+
+// RequireAdmin only lets requests through that carry the configured admin token
+func (h *TenantHandler) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.adminToken == "" {
+			writeError(w, "Admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		token := r.Header.Get(adminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+			writeError(w, "Invalid admin token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.tenants.GetAll(r.Context())
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to get tenants")
+		return
+	}
+
+	response := models.TenantListResponse{
+		Tenants: make([]models.TenantResponse, len(tenants)),
+	}
+	for i, t := range tenants {
+		response.Tenants[i] = toTenantResponse(&t)
+	}
+
+	writeJSON(w, response, http.StatusOK)
+}
+
+func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTenantRequest
+
+	if !h.validator.Decode(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		req.Name = req.Slug
+	}
+
+	packSizes := req.PackSizes
+	if packSizes == nil {
+		packSizes = h.defaultPackSizes
+	}
+
+	t, err := h.tenants.Create(r.Context(), req.Slug, req.Name, packSizes)
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to create tenant")
+		return
+	}
+
+	writeJSON(w, toTenantResponse(t), http.StatusCreated)
+}
+
+func toTenantResponse(t *database.Tenant) models.TenantResponse {
+	return models.TenantResponse{
+		ID:        t.ID,
+		Slug:      t.Slug,
+		Name:      t.Name,
+		CreatedAt: t.CreatedAt.Format(time.RFC3339),
+	}
+}
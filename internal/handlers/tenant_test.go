@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/auth"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/tenant"
+)
+
+// Mock tenant repository for testing
+type mockTenantRepository struct {
+	tenants   []database.Tenant
+	packSizes map[int][]int
+}
+
+func newMockTenantRepository() *mockTenantRepository {
+	return &mockTenantRepository{
+		tenants: []database.Tenant{
+			{ID: 1, Slug: "default", Name: "Default", CreatedAt: time.Now()},
+			{ID: 2, Slug: "acme", Name: "Acme", CreatedAt: time.Now()},
+		},
+		packSizes: make(map[int][]int),
+	}
+}
+
+func (m *mockTenantRepository) GetAll(ctx context.Context) ([]database.Tenant, error) {
+	return m.tenants, nil
+}
+
+func (m *mockTenantRepository) GetBySlug(ctx context.Context, slug string) (*database.Tenant, error) {
+	for _, t := range m.tenants {
+		if t.Slug == slug {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("tenant '%s': %w", slug, database.ErrTenantNotFound)
+}
+
+func (m *mockTenantRepository) Create(ctx context.Context, slug, name string, packSizes []int) (*database.Tenant, error) {
+	for _, t := range m.tenants {
+		if t.Slug == slug {
+			return nil, fmt.Errorf("tenant '%s': %w", slug, database.ErrDuplicateTenant)
+		}
+	}
+	t := database.Tenant{ID: len(m.tenants) + 1, Slug: slug, Name: name, CreatedAt: time.Now()}
+	m.tenants = append(m.tenants, t)
+	m.packSizes[t.ID] = packSizes
+	return &t, nil
+}
+
+func TestTenantResolver_Middleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            config.TenancyConfig
+		principal      *auth.Principal
+		current        *tenant.Tenant
+		header         string
+		expectedStatus int
+		expectedTenant string
+	}{
+		{
+			name:           "Tenant from header",
+			header:         "acme",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "acme",
+		},
+		{
+			name:           "Default tenant without header",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "default",
+		},
+		{
+			name:           "Missing header when tenant is required",
+			cfg:            config.TenancyConfig{RequireTenant: true},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown tenant",
+			header:         "globex",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Tenant from token",
+			principal:      &auth.Principal{Subject: "jwt:alice", TenantSlug: "acme"},
+			expectedStatus: http.StatusOK,
+			expectedTenant: "acme",
+		},
+		{
+			name:           "Token with matching header",
+			principal:      &auth.Principal{Subject: "jwt:alice", TenantSlug: "acme"},
+			header:         "acme",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "acme",
+		},
+		{
+			name:           "Token for another tenant",
+			principal:      &auth.Principal{Subject: "jwt:alice", TenantSlug: "acme"},
+			header:         "default",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "API key for another tenant",
+			current:        &tenant.Tenant{ID: 2, Slug: "acme"},
+			header:         "default",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewTenantResolver(newMockTenantRepository(), tt.cfg)
+
+			var resolved string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				current, _ := tenant.FromContext(r.Context())
+				resolved = current.Slug
+			})
+
+			req := httptest.NewRequest("GET", "/api/v1/pack-sizes", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), *tt.principal))
+			}
+			if tt.current != nil {
+				req = req.WithContext(tenant.WithTenant(req.Context(), *tt.current))
+			}
+			w := httptest.NewRecorder()
+
+			resolver.Middleware(next).ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if resolved != tt.expectedTenant {
+				t.Errorf("expected tenant '%s', got '%s'", tt.expectedTenant, resolved)
+			}
+		})
+	}
+}
+
+func TestTenantHandler_CreateTenant(t *testing.T) {
+	tests := []struct {
+		name              string
+		adminToken        string
+		requestToken      string
+		requestBody       string
+		expectedStatus    int
+		expectedCode      string
+		expectedPackSizes []int
+	}{
+		{
+			name:              "Seeds default pack sizes",
+			adminToken:        "secret",
+			requestToken:      "secret",
+			requestBody:       `{"slug": "globex", "name": "Globex"}`,
+			expectedStatus:    http.StatusCreated,
+			expectedPackSizes: DefaultTenantPackSizes,
+		},
+		{
+			name:              "Custom pack sizes",
+			adminToken:        "secret",
+			requestToken:      "secret",
+			requestBody:       `{"slug": "initech", "pack_sizes": [10, 20]}`,
+			expectedStatus:    http.StatusCreated,
+			expectedPackSizes: []int{10, 20},
+		},
+		{
+			name:           "Invalid slug",
+			adminToken:     "secret",
+			requestToken:   "secret",
+			requestBody:    `{"slug": "Not Valid"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Duplicate slug",
+			adminToken:     "secret",
+			requestToken:   "secret",
+			requestBody:    `{"slug": "acme"}`,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   codeDuplicateTenant,
+		},
+		{
+			name:           "Wrong admin token",
+			adminToken:     "secret",
+			requestToken:   "guess",
+			requestBody:    `{"slug": "globex"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Admin API disabled",
+			requestBody:    `{"slug": "globex"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockTenantRepository()
+			handler := NewTenantHandler(repo, config.TenancyConfig{AdminToken: tt.adminToken}, NewRequestValidator(config.ValidationConfig{}))
+
+			req := httptest.NewRequest("POST", "/api/v1/admin/tenants", bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("X-Admin-Token", tt.requestToken)
+			w := httptest.NewRecorder()
+
+			handler.RequireAdmin(http.HandlerFunc(handler.CreateTenant)).ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				if tt.expectedCode != "" {
+					var problem models.Problem
+					if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+						t.Fatalf("failed to unmarshal problem: %v", err)
+					}
+					if problem.Code != tt.expectedCode {
+						t.Errorf("expected code %q, got %q", tt.expectedCode, problem.Code)
+					}
+				}
+				return
+			}
+
+			var response models.TenantResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			seeded := repo.packSizes[response.ID]
+			if fmt.Sprint(seeded) != fmt.Sprint(tt.expectedPackSizes) {
+				t.Errorf("expected seeded pack sizes %v, got %v", tt.expectedPackSizes, seeded)
+			}
+		})
+	}
+}
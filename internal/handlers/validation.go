@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/models"
+)
+
+const (
+	// DefaultMaxBodyBytes caps request bodies when no limit is configured
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultMaxOrderItems bounds calculations, whose memory use grows with the order size
+	DefaultMaxOrderItems = 10_000_000
+	// DefaultMaxPackSize bounds the pack sizes that can be created
+	DefaultMaxPackSize = 1_000_000
+)
+
+// RequestValidator decodes JSON request bodies strictly and validates them
+// against the configured limits, which can be replaced at runtime
+type RequestValidator struct {
+	settings atomic.Pointer[validatorSettings]
+}
+
+type validatorSettings struct {
+	maxBodyBytes int64
+	limits       models.Limits
+}
+
+func NewRequestValidator(cfg config.ValidationConfig) *RequestValidator {
+	v := &RequestValidator{}
+	v.Configure(cfg)
+	return v
+}
+
+// Configure replaces the limits, applying defaults to unset ones. Requests
+// already being validated keep the previous limits.
+func (v *RequestValidator) Configure(cfg config.ValidationConfig) {
+	settings := &validatorSettings{
+		maxBodyBytes: cfg.MaxBodyBytes,
+		limits: models.Limits{
+			MaxOrderItems: cfg.MaxOrderItems,
+			MaxPackSize:   cfg.MaxPackSize,
+		},
+	}
+	if settings.maxBodyBytes <= 0 {
+		settings.maxBodyBytes = DefaultMaxBodyBytes
+	}
+	if settings.limits.MaxOrderItems <= 0 {
+		settings.limits.MaxOrderItems = DefaultMaxOrderItems
+	}
+	if settings.limits.MaxPackSize <= 0 {
+		settings.limits.MaxPackSize = DefaultMaxPackSize
+	}
+	v.settings.Store(settings)
+}
+
+// Limits returns the limits requests are validated against
+func (v *RequestValidator) Limits() models.Limits {
+	return v.settings.Load().limits
+}
+
+// LimitBody caps the size of every request body, so that middleware reading
+// the body before the handler is bounded as well
+func (v *RequestValidator) LimitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, v.settings.Load().maxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Decode reads a JSON object into dst and validates it. Unknown fields,
+// values of the wrong type and rule violations are all collected into a
+// single validation problem. It writes the error response and returns false
+// when the request is rejected.
+func (v *RequestValidator) Decode(w http.ResponseWriter, r *http.Request, dst models.Validator) bool {
+	settings := v.settings.Load()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, settings.maxBodyBytes))
+	if err != nil {
+		writeBodyError(w, err)
+		return false
+	}
+
+	fields, err := decodeFields(body, dst)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, codeInvalidBody, "Invalid request body: "+err.Error(), nil)
+		return false
+	}
+
+	// Rules only apply to fields that decoded, so a wrongly typed value is
+	// not reported twice
+	for _, fieldErr := range dst.Validate(settings.limits) {
+		if !hasField(fields, fieldErr.Field) {
+			fields = append(fields, fieldErr)
+		}
+	}
+	return v.Check(w, fields)
+}
+
+// Check writes a validation problem and returns false when there are field errors
+func (v *RequestValidator) Check(w http.ResponseWriter, fields []models.FieldError) bool {
+	if len(fields) == 0 {
+		return true
+	}
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	writeProblem(w, http.StatusBadRequest, codeValidationFailed, strings.Join(messages, "; "), fields)
+	return false
+}
+
+// writeBodyError reports a failure to read the request body
+func writeBodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeProblem(w, http.StatusRequestEntityTooLarge, codeBodyTooLarge, fmt.Sprintf("Request body must be at most %d bytes", maxBytesErr.Limit), nil)
+		return
+	}
+	writeProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to read request body", nil)
+}
+
+// decodeFields decodes a JSON object into the struct dst points to, one field
+// at a time, so that every unknown or mistyped field is reported. Malformed
+// JSON is returned as an error.
+func decodeFields(body []byte, dst interface{}) ([]models.FieldError, error) {
+	var raw map[string]json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body is empty")
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, errors.New("body must be a JSON object")
+		}
+		return nil, err
+	}
+	if raw == nil {
+		return nil, errors.New("body must be a JSON object")
+	}
+	if decoder.More() {
+		return nil, errors.New("body must contain a single JSON object")
+	}
+
+	target := reflect.ValueOf(dst).Elem()
+	known := make(map[string]bool)
+	var fields []models.FieldError
+	for i := 0; i < target.NumField(); i++ {
+		name := jsonName(target.Type().Field(i))
+		if name == "" {
+			continue
+		}
+		known[name] = true
+
+		value, ok := raw[name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(value, target.Field(i).Addr().Interface()); err != nil {
+			fields = append(fields, models.FieldError{
+				Field:   name,
+				Code:    "invalid_type",
+				Message: fmt.Sprintf("%s must be %s", name, describeType(target.Field(i).Type())),
+			})
+		}
+	}
+
+	unknown := make([]string, 0)
+	for name := range raw {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		fields = append(fields, models.FieldError{Field: name, Code: "unknown_field", Message: fmt.Sprintf("unknown field '%s'", name)})
+	}
+
+	return fields, nil
+}
+
+// jsonName returns the JSON name of a struct field, or "" for ignored fields
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// describeType names a Go type the way API clients see it
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return fmt.Sprintf("a list of %ss", strings.TrimPrefix(strings.TrimPrefix(describeType(t.Elem()), "an "), "a "))
+	default:
+		return "an object"
+	}
+}
+
+// hasField reports whether name, or the list element name refers to, already has an error
+func hasField(fields []models.FieldError, name string) bool {
+	for _, field := range fields {
+		if field.Field == name || strings.HasPrefix(name, field.Field+"[") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/models"
+)
+
+func TestRequestValidator_Decode(t *testing.T) {
+	validator := NewRequestValidator(config.ValidationConfig{MaxBodyBytes: 128, MaxOrderItems: 1000, MaxPackSize: 500})
+
+	tests := []struct {
+		name           string
+		body           string
+		dst            models.Validator
+		expectedStatus int
+		expectedCode   string
+		expectedFields []string
+	}{
+		{
+			name:           "Valid request",
+			body:           `{"items": 250}`,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unknown field",
+			body:           `{"items": 250, "itemz": 1}`,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeValidationFailed,
+			expectedFields: []string{"itemz"},
+		},
+		{
+			name:           "Wrong type",
+			body:           `{"items": "5"}`,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeValidationFailed,
+			expectedFields: []string{"items"},
+		},
+		{
+			name:           "Order too large",
+			body:           `{"items": 1001}`,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeValidationFailed,
+			expectedFields: []string{"items"},
+		},
+		{
+			name:           "Pack size too large",
+			body:           `{"size": 501}`,
+			dst:            &models.CreatePackSizeRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeValidationFailed,
+			expectedFields: []string{"size"},
+		},
+		{
+			name:           "All field errors are collected",
+			body:           `{"slug": "Not Valid", "pack_sizes": [250, -1, 600], "extra": true}`,
+			dst:            &models.CreateTenantRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeValidationFailed,
+			expectedFields: []string{"extra", "slug", "pack_sizes[1]", "pack_sizes[2]"},
+		},
+		{
+			name:           "Mistyped list is reported once",
+			body:           `{"slug": "acme", "pack_sizes": [250, "x"]}`,
+			dst:            &models.CreateTenantRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeValidationFailed,
+			expectedFields: []string{"pack_sizes"},
+		},
+		{
+			name:           "Malformed JSON",
+			body:           `{"items": `,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidBody,
+		},
+		{
+			name:           "Not an object",
+			body:           `[1, 2]`,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidBody,
+		},
+		{
+			name:           "Trailing data",
+			body:           `{"items": 1} {"items": 2}`,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidBody,
+		},
+		{
+			name:           "Body too large",
+			body:           `{"items": 1, "padding": "` + strings.Repeat("x", 128) + `"}`,
+			dst:            &models.CalculateRequest{},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   codeBodyTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			ok := validator.Decode(w, req, tt.dst)
+
+			if tt.expectedStatus == http.StatusOK {
+				if !ok {
+					t.Fatalf("expected request to be accepted, got %d: %s", w.Code, w.Body.String())
+				}
+				return
+			}
+			if ok {
+				t.Fatal("expected request to be rejected")
+			}
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			var problem models.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("failed to unmarshal problem: %v", err)
+			}
+			if problem.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, problem.Code)
+			}
+			if len(problem.Errors) != len(tt.expectedFields) {
+				t.Fatalf("expected field errors %v, got %+v", tt.expectedFields, problem.Errors)
+			}
+			for i, field := range tt.expectedFields {
+				if problem.Errors[i].Field != field {
+					t.Errorf("expected field error %d for %s, got %s", i, field, problem.Errors[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRequestValidator_Defaults(t *testing.T) {
+	validator := NewRequestValidator(config.ValidationConfig{})
+
+	limits := validator.Limits()
+	if limits.MaxOrderItems != DefaultMaxOrderItems {
+		t.Errorf("expected max order items %d, got %d", DefaultMaxOrderItems, limits.MaxOrderItems)
+	}
+	if limits.MaxPackSize != DefaultMaxPackSize {
+		t.Errorf("expected max pack size %d, got %d", DefaultMaxPackSize, limits.MaxPackSize)
+	}
+}
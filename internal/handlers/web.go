@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"sort"
 	"strconv"
 
+	"github.com/miloradbozic/packing-service/internal/auth"
 	"github.com/miloradbozic/packing-service/internal/database"
 	"github.com/miloradbozic/packing-service/internal/models"
 	"github.com/miloradbozic/packing-service/internal/service"
@@ -16,10 +20,26 @@ type WebHandler struct {
 	service      *service.PackingService
 	packSizeRepo *database.PackSizeRepository
 	templates    *template.Template
+	sessions     *SessionManager
+	authEnabled  bool
+	validator    *RequestValidator
 }
 
-func NewWebHandler(packingService *service.PackingService, packSizeRepo *database.PackSizeRepository) (*WebHandler, error) {
-	tmpl, err := template.ParseGlob("templates/*.html")
+// homePageData is rendered by index.html
+type homePageData struct {
+	PackSizes []int
+	Results   *models.CalculateResponse
+	Error     string
+	Items     string
+	CSRFToken string
+	// CanManage shows the pack size management controls
+	CanManage bool
+	// User names the logged in user; empty for anonymous visitors
+	User string
+}
+
+func NewWebHandler(packingService *service.PackingService, packSizeRepo *database.PackSizeRepository, templates fs.FS, sessions *SessionManager, authEnabled bool, validator *RequestValidator) (*WebHandler, error) {
+	tmpl, err := template.ParseFS(templates, "*.html")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
@@ -28,29 +48,36 @@ func NewWebHandler(packingService *service.PackingService, packSizeRepo *databas
 		service:      packingService,
 		packSizeRepo: packSizeRepo,
 		templates:    tmpl,
+		sessions:     sessions,
+		authEnabled:  authEnabled,
+		validator:    validator,
 	}, nil
 }
 
 func (h *WebHandler) HomePage(w http.ResponseWriter, r *http.Request) {
+	principal, loggedIn := auth.FromContext(r.Context())
+	if h.authEnabled && !loggedIn {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
 	if r.Method == "POST" {
 		h.handleCalculate(w, r)
 		return
 	}
 
-	packSizes, err := h.service.GetPackSizes()
+	packSizes, err := h.service.GetPackSizes(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to get pack sizes", http.StatusInternalServerError)
 		return
 	}
 
-	data := struct {
-		PackSizes []int
-		Results   *models.CalculateResponse
-		Error     string
-		Items     string
-	}{
-		PackSizes: packSizes,
+	data, err := h.pageData(w, r, principal, loggedIn)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
 	}
+	data.PackSizes = packSizes
 
 	if err := h.templates.ExecuteTemplate(w, "index.html", data); err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
@@ -63,31 +90,36 @@ func (h *WebHandler) handleCalculate(w http.ResponseWriter, r *http.Request) {
 	itemsStr := r.FormValue("items")
 	items, err := strconv.Atoi(itemsStr)
 
-	packSizes, packErr := h.service.GetPackSizes()
+	packSizes, packErr := h.service.GetPackSizes(r.Context())
 	if packErr != nil {
 		http.Error(w, "Failed to get pack sizes", http.StatusInternalServerError)
 		return
 	}
 
-	data := struct {
-		PackSizes []int
-		Results   *models.CalculateResponse
-		Error     string
-		Items     string
-	}{
-		PackSizes: packSizes,
-		Items:     itemsStr,
+	principal, loggedIn := auth.FromContext(r.Context())
+	data, sessionErr := h.pageData(w, r, principal, loggedIn)
+	if sessionErr != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
 	}
+	data.PackSizes = packSizes
+	data.Items = itemsStr
 
 	if err != nil || items <= 0 {
 		data.Error = "Please enter a valid positive number"
 		h.templates.ExecuteTemplate(w, "index.html", data)
 		return
 	}
+	req := models.CalculateRequest{Items: items}
+	if fieldErrs := req.Validate(h.validator.Limits()); len(fieldErrs) > 0 {
+		data.Error = fieldErrs[0].Message
+		h.templates.ExecuteTemplate(w, "index.html", data)
+		return
+	}
 
-	solution, err := h.service.CalculatePacks(items)
+	solution, err := h.service.CalculatePacks(r.Context(), items)
 	if err != nil {
-		data.Error = err.Error()
+		data.Error = calculationErrorMessage(r, err)
 		h.templates.ExecuteTemplate(w, "index.html", data)
 		return
 	}
@@ -120,3 +152,29 @@ func (h *WebHandler) handleCalculate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 	}
 }
+
+// calculationErrorMessage returns the message shown for a failed calculation.
+// Only the errors meant for users are shown as they are; others are logged.
+func calculationErrorMessage(r *http.Request, err error) string {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) || errors.Is(err, service.ErrNoPackSizes) || errors.Is(err, service.ErrUnreachable) {
+		return err.Error()
+	}
+	slog.ErrorContext(r.Context(), "Failed to calculate packs", "error", err)
+	return "Failed to calculate packs"
+}
+
+// pageData fills in the session dependent parts of the page. Management controls
+// are shown to everyone while authentication is disabled.
+func (h *WebHandler) pageData(w http.ResponseWriter, r *http.Request, principal auth.Principal, loggedIn bool) (homePageData, error) {
+	session, err := h.sessions.Current(w, r)
+	if err != nil {
+		return homePageData{}, err
+	}
+
+	return homePageData{
+		CSRFToken: session.CSRFToken,
+		CanManage: !h.authEnabled || (loggedIn && principal.Has(auth.ScopeManage)),
+		User:      session.Name,
+	}, nil
+}
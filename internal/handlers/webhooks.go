@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/service"
+	"github.com/miloradbozic/packing-service/internal/webhooks"
+)
+
+const (
+	// DefaultDeliveryPageSize is the number of deliveries listed when no limit is given
+	DefaultDeliveryPageSize = 50
+	// MaxDeliveryPageSize is the largest delivery limit accepted
+	MaxDeliveryPageSize = 500
+)
+
+// WebhookHandler serves the webhook subscription endpoints of the current tenant
+type WebhookHandler struct {
+	webhooks  database.WebhookRepositoryInterface
+	validator *RequestValidator
+}
+
+func NewWebhookHandler(webhookRepo database.WebhookRepositoryInterface, validator *RequestValidator) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhookRepo, validator: validator}
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.webhooks.GetAll(r.Context())
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to get webhooks")
+		return
+	}
+
+	response := models.WebhookListResponse{
+		Webhooks: make([]models.WebhookResponse, len(subscriptions)),
+	}
+	for i := range subscriptions {
+		response.Webhooks[i] = toWebhookResponse(&subscriptions[i], false)
+	}
+
+	writeJSON(w, response, http.StatusOK)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhooks.GetByID(r.Context(), id)
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to get webhook")
+		return
+	}
+
+	writeJSON(w, toWebhookResponse(subscription, false), http.StatusOK)
+}
+
+// CreateWebhook subscribes a URL to events. The response carries the signing
+// secret, which is not shown again.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+
+	if !h.validator.Decode(w, r, &req) {
+		return
+	}
+
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to generate webhook secret")
+		return
+	}
+
+	active := req.Active == nil || *req.Active
+	subscription, err := h.webhooks.Create(r.Context(), req.URL, secret, req.Events, active)
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to create webhook")
+		return
+	}
+
+	writeJSON(w, toWebhookResponse(subscription, true), http.StatusCreated)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if !h.validator.Decode(w, r, &req) {
+		return
+	}
+
+	subscription, err := h.webhooks.Update(r.Context(), id, req.URL, req.Events, req.Active)
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to update webhook")
+		return
+	}
+
+	writeJSON(w, toWebhookResponse(subscription, false), http.StatusOK)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.Delete(r.Context(), id); err != nil {
+		writeErrorFor(w, r, err, "Failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first. The
+// status parameter selects pending, succeeded or dead deliveries and limit
+// the number returned (1-500, default 50).
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	status, limit, fields := deliveryQuery(r.URL.Query())
+	if !h.validator.Check(w, fields) {
+		return
+	}
+
+	deliveries, err := h.webhooks.Deliveries(r.Context(), id, status, limit)
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to get webhook deliveries")
+		return
+	}
+
+	response := models.WebhookDeliveryListResponse{
+		Deliveries: make([]models.WebhookDeliveryResponse, len(deliveries)),
+	}
+	for i := range deliveries {
+		response.Deliveries[i] = toWebhookDeliveryResponse(&deliveries[i])
+	}
+
+	writeJSON(w, response, http.StatusOK)
+}
+
+// RetryDelivery sends a delivery again, with a fresh set of attempts. It is
+// meant for dead deliveries, once the receiving end has been fixed.
+func (h *WebhookHandler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	deliveryIDStr := mux.Vars(r)["delivery_id"]
+	deliveryID, err := strconv.ParseInt(deliveryIDStr, 10, 64)
+	if err != nil {
+		writeErrorFor(w, r, service.NewValidationError("delivery_id", "invalid_integer", fmt.Sprintf("Invalid delivery ID '%s': must be a valid integer", deliveryIDStr)), "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.webhooks.RetryDelivery(r.Context(), id, deliveryID)
+	if err != nil {
+		writeErrorFor(w, r, err, "Failed to retry webhook delivery")
+		return
+	}
+
+	writeJSON(w, toWebhookDeliveryResponse(delivery), http.StatusAccepted)
+}
+
+// deliveryQuery reads the status and limit parameters of a delivery log
+// listing, reporting every invalid parameter as a field error
+func deliveryQuery(values url.Values) (string, int, []models.FieldError) {
+	var fields []models.FieldError
+
+	status := values.Get("status")
+	switch status {
+	case "", webhooks.StatusPending, webhooks.StatusSucceeded, webhooks.StatusDead:
+	default:
+		fields = append(fields, models.FieldError{Field: "status", Code: "invalid_value", Message: "status must be pending, succeeded or dead"})
+	}
+
+	limit := DefaultDeliveryPageSize
+	if value := values.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			fields = append(fields, models.FieldError{Field: "limit", Code: "invalid_integer", Message: "limit must be an integer"})
+		case n < 1 || n > MaxDeliveryPageSize:
+			fields = append(fields, models.FieldError{Field: "limit", Code: "out_of_range", Message: fmt.Sprintf("limit must be between 1 and %d", MaxDeliveryPageSize)})
+		default:
+			limit = n
+		}
+	}
+
+	return status, limit, fields
+}
+
+// webhookID parses the webhook ID from the route, reporting invalid IDs
+func webhookID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeErrorFor(w, r, service.NewValidationError("id", "invalid_integer", fmt.Sprintf("Invalid webhook ID '%s': must be a valid integer", idStr)), "Invalid webhook ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func toWebhookResponse(subscription *database.WebhookSubscription, withSecret bool) models.WebhookResponse {
+	response := models.WebhookResponse{
+		ID:        subscription.ID,
+		URL:       subscription.URL,
+		Events:    subscription.Events,
+		Active:    subscription.Active,
+		CreatedAt: subscription.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: subscription.UpdatedAt.Format(time.RFC3339),
+	}
+	if withSecret {
+		response.Secret = subscription.Secret
+	}
+	return response
+}
+
+func toWebhookDeliveryResponse(delivery *database.WebhookDelivery) models.WebhookDeliveryResponse {
+	response := models.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt.Format(time.RFC3339),
+	}
+	if delivery.Status == webhooks.StatusPending {
+		response.NextAttemptAt = delivery.NextAttemptAt.Format(time.RFC3339)
+	}
+	if delivery.DeliveredAt != nil {
+		response.DeliveredAt = delivery.DeliveredAt.Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/models"
+	"github.com/miloradbozic/packing-service/internal/webhooks"
+)
+
+// mockWebhookRepository implements WebhookRepositoryInterface for testing
+type mockWebhookRepository struct {
+	subscriptions []database.WebhookSubscription
+	deliveries    []database.WebhookDelivery
+	nextID        int
+	// lastStatus and lastLimit record the filter of the last Deliveries call
+	lastStatus string
+	lastLimit  int
+}
+
+func newMockWebhookRepository() *mockWebhookRepository {
+	now := time.Now()
+	return &mockWebhookRepository{
+		subscriptions: []database.WebhookSubscription{
+			{ID: 1, URL: "https://erp.example.com/hooks", Secret: "whsec_test", Events: []string{webhooks.EventPackSizeCreated}, Active: true, CreatedAt: now, UpdatedAt: now},
+		},
+		deliveries: []database.WebhookDelivery{
+			{ID: 7, SubscriptionID: 1, EventID: 3, EventType: webhooks.EventPackSizeCreated, Status: webhooks.StatusDead, Attempts: 8, NextAttemptAt: now, LastStatusCode: 500, LastError: "unexpected response status 500", CreatedAt: now},
+		},
+		nextID: 1,
+	}
+}
+
+func (m *mockWebhookRepository) GetAll(ctx context.Context) ([]database.WebhookSubscription, error) {
+	return m.subscriptions, nil
+}
+
+func (m *mockWebhookRepository) GetByID(ctx context.Context, id int) (*database.WebhookSubscription, error) {
+	for i := range m.subscriptions {
+		if m.subscriptions[i].ID == id {
+			return &m.subscriptions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("webhook with id %d: %w", id, database.ErrNotFound)
+}
+
+func (m *mockWebhookRepository) Create(ctx context.Context, url, secret string, events []string, active bool) (*database.WebhookSubscription, error) {
+	m.nextID++
+	subscription := database.WebhookSubscription{ID: m.nextID, URL: url, Secret: secret, Events: events, Active: active, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	m.subscriptions = append(m.subscriptions, subscription)
+	return &subscription, nil
+}
+
+func (m *mockWebhookRepository) Update(ctx context.Context, id int, url string, events []string, active *bool) (*database.WebhookSubscription, error) {
+	subscription, err := m.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.URL = url
+	subscription.Events = events
+	if active != nil {
+		subscription.Active = *active
+	}
+	return subscription, nil
+}
+
+func (m *mockWebhookRepository) Delete(ctx context.Context, id int) error {
+	for i := range m.subscriptions {
+		if m.subscriptions[i].ID == id {
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook with id %d: %w", id, database.ErrNotFound)
+}
+
+func (m *mockWebhookRepository) Deliveries(ctx context.Context, subscriptionID int, status string, limit int) ([]database.WebhookDelivery, error) {
+	if _, err := m.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	m.lastStatus, m.lastLimit = status, limit
+	var deliveries []database.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionID && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *mockWebhookRepository) RetryDelivery(ctx context.Context, subscriptionID int, deliveryID int64) (*database.WebhookDelivery, error) {
+	for i := range m.deliveries {
+		if m.deliveries[i].ID == deliveryID && m.deliveries[i].SubscriptionID == subscriptionID {
+			m.deliveries[i].Status = webhooks.StatusPending
+			m.deliveries[i].Attempts = 0
+			return &m.deliveries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("webhook delivery with id %d: %w", deliveryID, database.ErrNotFound)
+}
+
+func newWebhookRouter(repo *mockWebhookRepository) *mux.Router {
+	handler := NewWebhookHandler(repo, NewRequestValidator(config.ValidationConfig{}))
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/webhooks", handler.ListWebhooks).Methods("GET")
+	router.HandleFunc("/api/v1/webhooks", handler.CreateWebhook).Methods("POST")
+	router.HandleFunc("/api/v1/webhooks/{id}", handler.GetWebhook).Methods("GET")
+	router.HandleFunc("/api/v1/webhooks/{id}", handler.UpdateWebhook).Methods("PUT")
+	router.HandleFunc("/api/v1/webhooks/{id}", handler.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc("/api/v1/webhooks/{id}/deliveries", handler.ListDeliveries).Methods("GET")
+	router.HandleFunc("/api/v1/webhooks/{id}/deliveries/{delivery_id}/retry", handler.RetryDelivery).Methods("POST")
+	return router
+}
+
+func TestWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "List", method: "GET", url: "/api/v1/webhooks", expectedStatus: http.StatusOK},
+		{name: "Create", method: "POST", url: "/api/v1/webhooks", body: `{"url": "https://erp.example.com/new", "events": ["order.calculated"]}`, expectedStatus: http.StatusCreated},
+		{name: "Create with unknown event", method: "POST", url: "/api/v1/webhooks", body: `{"url": "https://erp.example.com/new", "events": ["order.shipped"]}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_event"},
+		{name: "Create with relative URL", method: "POST", url: "/api/v1/webhooks", body: `{"url": "/hooks", "events": ["order.calculated"]}`, expectedStatus: http.StatusBadRequest},
+		{name: "Create without events", method: "POST", url: "/api/v1/webhooks", body: `{"url": "https://erp.example.com/new", "events": []}`, expectedStatus: http.StatusBadRequest},
+		{name: "Get", method: "GET", url: "/api/v1/webhooks/1", expectedStatus: http.StatusOK},
+		{name: "Get missing", method: "GET", url: "/api/v1/webhooks/999", expectedStatus: http.StatusNotFound},
+		{name: "Get with invalid ID", method: "GET", url: "/api/v1/webhooks/abc", expectedStatus: http.StatusBadRequest, expectedCode: "invalid_integer"},
+		{name: "Update", method: "PUT", url: "/api/v1/webhooks/1", body: `{"url": "https://erp.example.com/v2", "events": ["pack_size.updated"], "active": false}`, expectedStatus: http.StatusOK},
+		{name: "Delete missing", method: "DELETE", url: "/api/v1/webhooks/999", expectedStatus: http.StatusNotFound},
+		{name: "Deliveries", method: "GET", url: "/api/v1/webhooks/1/deliveries?status=dead&limit=10", expectedStatus: http.StatusOK},
+		{name: "Deliveries with unknown status", method: "GET", url: "/api/v1/webhooks/1/deliveries?status=failed", expectedStatus: http.StatusBadRequest, expectedCode: "invalid_value"},
+		{name: "Deliveries with too large limit", method: "GET", url: "/api/v1/webhooks/1/deliveries?limit=501", expectedStatus: http.StatusBadRequest, expectedCode: "out_of_range"},
+		{name: "Deliveries of missing webhook", method: "GET", url: "/api/v1/webhooks/999/deliveries", expectedStatus: http.StatusNotFound},
+		{name: "Retry", method: "POST", url: "/api/v1/webhooks/1/deliveries/7/retry", expectedStatus: http.StatusAccepted},
+		{name: "Retry missing delivery", method: "POST", url: "/api/v1/webhooks/1/deliveries/8/retry", expectedStatus: http.StatusNotFound},
+		{name: "Retry with invalid delivery ID", method: "POST", url: "/api/v1/webhooks/1/deliveries/x/retry", expectedStatus: http.StatusBadRequest, expectedCode: "invalid_integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newWebhookRouter(newMockWebhookRepository())
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" && !strings.Contains(w.Body.String(), `"code":"`+tt.expectedCode+`"`) {
+				t.Errorf("expected error code %s, got %s", tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_SecretOnlyOnCreate(t *testing.T) {
+	router := newWebhookRouter(newMockWebhookRepository())
+
+	req := httptest.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(`{"url": "https://erp.example.com/new", "events": ["order.calculated"]}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var created models.WebhookResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(created.Secret, "whsec_") {
+		t.Errorf("expected a generated secret, got %q", created.Secret)
+	}
+	if !created.Active {
+		t.Error("expected a new webhook to be active by default")
+	}
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/v1/webhooks/%d", created.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("expected no secret when getting a webhook, got %s", w.Body.String())
+	}
+}
+
+func TestWebhookHandler_DeliveryDefaults(t *testing.T) {
+	repo := newMockWebhookRepository()
+	router := newWebhookRouter(repo)
+
+	req := httptest.NewRequest("GET", "/api/v1/webhooks/1/deliveries", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if repo.lastStatus != "" || repo.lastLimit != DefaultDeliveryPageSize {
+		t.Errorf("expected all statuses and limit %d, got %q and %d", DefaultDeliveryPageSize, repo.lastStatus, repo.lastLimit)
+	}
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miloradbozic/packing-service/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.LoggingConfig
+		expectJSON  bool
+		expectError bool
+	}{
+		{name: "Defaults", cfg: config.LoggingConfig{}},
+		{name: "JSON", cfg: config.LoggingConfig{Level: "debug", Format: "json"}, expectJSON: true},
+		{name: "Invalid level", cfg: config.LoggingConfig{Level: "verbose"}, expectError: true},
+		{name: "Invalid format", cfg: config.LoggingConfig{Format: "xml"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(tt.cfg, &buf)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			logger.Info("hello")
+			if got := strings.HasPrefix(buf.String(), "{"); got != tt.expectJSON {
+				t.Errorf("expected JSON output %v, got %q", tt.expectJSON, buf.String())
+			}
+		})
+	}
+}
+
+func TestNew_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Format: "json"}, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logger.ErrorContext(WithRequestID(context.Background(), "req-1"), "failed")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to parse log line: %v", err)
+	}
+	if record["request_id"] != "req-1" {
+		t.Errorf("expected request_id 'req-1', got %v", record["request_id"])
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestID  string
+		expectedID string
+	}{
+		{name: "Propagates a client ID", requestID: "abc-123", expectedID: "abc-123"},
+		{name: "Generates a missing ID", requestID: ""},
+		{name: "Replaces an invalid ID", requestID: "bad id\n"},
+		{name: "Replaces an overly long ID", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Format: "json"}, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			var handlerID string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerID = RequestID(r.Context())
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte("short and stout"))
+			}))
+
+			req := httptest.NewRequest("GET", "/pack-sizes", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if tt.expectedID != "" && id != tt.expectedID {
+				t.Errorf("expected request ID '%s', got '%s'", tt.expectedID, id)
+			}
+			if tt.expectedID == "" && (len(id) != 32 || id == tt.requestID) {
+				t.Errorf("expected a generated request ID, got '%s'", id)
+			}
+			if handlerID != id {
+				t.Errorf("expected the handler context to carry '%s', got '%s'", id, handlerID)
+			}
+
+			var record map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("failed to parse access log line %q: %v", buf.String(), err)
+			}
+			if record["request_id"] != id || record["status"] != float64(http.StatusTeapot) ||
+				record["bytes"] != float64(15) || record["path"] != "/pack-sizes" {
+				t.Errorf("unexpected access log line: %v", record)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Calculation outcomes, by the kind of error the calculation ended with
+const (
+	OutcomeSuccess         = "success"
+	OutcomeValidationError = "validation_error"
+	OutcomeNoPackSizes     = "no_pack_sizes"
+	OutcomeUnreachable     = "unreachable"
+	OutcomeError           = "error"
+)
+
+// Metrics collects the Prometheus metrics of the service in its own registry
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	calculations        *prometheus.CounterVec
+	calculationDuration prometheus.Histogram
+	tableSize           prometheus.Histogram
+	orderQuantity       prometheus.Histogram
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route template and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		calculations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "packing_calculations_total",
+			Help: "Pack calculations by outcome.",
+		}, []string{"outcome"}),
+		calculationDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "packing_calculation_duration_seconds",
+			Help:    "Time taken by pack calculations, including loading the pack sizes.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+		tableSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "packing_solver_table_size",
+			Help:    "Number of cells in the dynamic programming table of the solver.",
+			Buckets: prometheus.ExponentialBuckets(100, 10, 8),
+		}),
+		orderQuantity: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "packing_order_quantity",
+			Help:    "Number of items ordered per calculation.",
+			Buckets: prometheus.ExponentialBuckets(1, 10, 9),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.calculations,
+		m.calculationDuration,
+		m.tableSize,
+		m.orderQuantity,
+	)
+
+	// Report every outcome from the start, so rates work before the first error
+	for _, outcome := range []string{OutcomeSuccess, OutcomeValidationError, OutcomeNoPackSizes, OutcomeUnreachable, OutcomeError} {
+		m.calculations.WithLabelValues(outcome)
+	}
+
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db as go_sql_* metrics
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Instrument counts and times every request to router by the template of the
+// route it matches, so /pack-sizes/1 and /pack-sizes/2 share a series. It wraps
+// the whole router, since router middleware never sees requests without a
+// matching route; those are labelled "unknown".
+func (m *Metrics) Instrument(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			if template, err := match.Route.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		router.ServeHTTP(sw, r)
+
+		status := strconv.Itoa(sw.status)
+		m.requests.WithLabelValues(r.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveCalculation records a pack calculation; it implements service.Observer
+func (m *Metrics) ObserveCalculation(items, tableSize int, duration time.Duration, err error) {
+	m.calculations.WithLabelValues(Outcome(err)).Inc()
+	m.calculationDuration.Observe(duration.Seconds())
+	if items > 0 {
+		m.orderQuantity.Observe(float64(items))
+	}
+	if tableSize > 0 {
+		m.tableSize.Observe(float64(tableSize))
+	}
+}
+
+// Outcome names the kind of error a calculation ended with
+func Outcome(err error) string {
+	var validationErr *service.ValidationError
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.As(err, &validationErr):
+		return OutcomeValidationError
+	case errors.Is(err, service.ErrNoPackSizes):
+		return OutcomeNoPackSizes
+	case errors.Is(err, service.ErrUnreachable):
+		return OutcomeUnreachable
+	default:
+		return OutcomeError
+	}
+}
+
+// statusWriter remembers the status code written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status = status
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the wrapper
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/service"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrument_LabelsByRouteTemplate(t *testing.T) {
+	m := New()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/pack-sizes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "404" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}).Methods("GET")
+	handler := m.Instrument(router)
+
+	requests := []struct{ method, path string }{
+		{"GET", "/pack-sizes/1"},
+		{"GET", "/pack-sizes/2"},
+		{"GET", "/pack-sizes/404"},
+		{"GET", "/missing"},
+		{"DELETE", "/pack-sizes/1"},
+	}
+	for _, req := range requests {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	tests := []struct {
+		method, route, status string
+		expected              float64
+	}{
+		{"GET", "/pack-sizes/{id}", "200", 2},
+		{"GET", "/pack-sizes/{id}", "404", 1},
+		{"GET", "unknown", "404", 1},
+		{"DELETE", "unknown", "405", 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(tt.method, tt.route, tt.status)); got != tt.expected {
+			t.Errorf("expected %v %s %s requests with status %s, got %v", tt.expected, tt.method, tt.route, tt.status, got)
+		}
+	}
+	if got := testutil.CollectAndCount(m.requestDuration); got != 4 {
+		t.Errorf("expected 4 latency series, got %d", got)
+	}
+}
+
+func TestObserveCalculation(t *testing.T) {
+	m := New()
+
+	m.ObserveCalculation(501, 5501, time.Millisecond, nil)
+	m.ObserveCalculation(0, 0, time.Microsecond, service.NewValidationError("items", "must_be_positive", "items ordered must be positive"))
+	m.ObserveCalculation(7, 12, time.Microsecond, service.ErrUnreachable)
+
+	tests := []struct {
+		outcome  string
+		expected float64
+	}{
+		{OutcomeSuccess, 1},
+		{OutcomeValidationError, 1},
+		{OutcomeUnreachable, 1},
+		{OutcomeNoPackSizes, 0},
+		{OutcomeError, 0},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.calculations.WithLabelValues(tt.outcome)); got != tt.expected {
+			t.Errorf("expected %v calculations with outcome %s, got %v", tt.expected, tt.outcome, got)
+		}
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"packing_calculation_duration_seconds_count 3",
+		"packing_solver_table_size_count 2",
+		"packing_solver_table_size_sum 5513",
+		"packing_order_quantity_count 2",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, OutcomeSuccess},
+		{service.NewValidationError("items", "too_large", "too large"), OutcomeValidationError},
+		{fmt.Errorf("tenant acme: %w", service.ErrNoPackSizes), OutcomeNoPackSizes},
+		{service.ErrUnreachable, OutcomeUnreachable},
+		{errors.New("connection refused"), OutcomeError},
+	}
+
+	for _, tt := range tests {
+		if got := Outcome(tt.err); got != tt.expected {
+			t.Errorf("Outcome(%v) = %s, expected %s", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
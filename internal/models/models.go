@@ -21,6 +21,26 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// Problem is an RFC 7807 problem details error response
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Code is a stable, machine-readable error code
+	Code string `json:"code"`
+	// Error repeats Detail for clients of the earlier ErrorResponse format
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a rejected request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type ConfigResponse struct {
 	PackSizes []int `json:"pack_sizes"`
 }
@@ -28,19 +48,148 @@ type ConfigResponse struct {
 // Pack size management models
 type PackSizeListResponse struct {
 	PackSizes []PackSizeResponse `json:"pack_sizes"`
+	// NextCursor fetches the next page; it is omitted on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type PackSizeResponse struct {
 	ID        int    `json:"id"`
 	Size      int    `json:"size"`
+	Active    bool   `json:"active"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
 
 type CreatePackSizeRequest struct {
-	Size     int  `json:"size"`
+	Size int `json:"size"`
+	// Active defaults to true
+	Active *bool `json:"active,omitempty"`
 }
 
 type UpdatePackSizeRequest struct {
-	Size     int  `json:"size"`
+	Size int `json:"size"`
+	// Active is left unchanged when omitted
+	Active *bool `json:"active,omitempty"`
+}
+
+type ImportPackSizesResponse struct {
+	Mode      string `json:"mode"`
+	DryRun    bool   `json:"dry_run"`
+	Created   []int  `json:"created"`
+	Updated   []int  `json:"updated"`
+	Deleted   []int  `json:"deleted"`
+	Unchanged []int  `json:"unchanged"`
+}
+
+// Tenant admin models
+type TenantListResponse struct {
+	Tenants []TenantResponse `json:"tenants"`
+}
+
+type TenantResponse struct {
+	ID        int    `json:"id"`
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+type CreateTenantRequest struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	PackSizes []int  `json:"pack_sizes,omitempty"`
+}
+
+// API key models
+type APIKeyListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
+
+type APIKeyResponse struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	RevokedAt  string   `json:"revoked_at,omitempty"`
+	// Key is the plaintext key, only returned when a key is created or rotated
+	Key string `json:"key,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// Health probe models
+type HealthResponse struct {
+	// Status is "ok" when every check passed, otherwise "fail"
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+type HealthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Admin config models
+type EffectiveConfigResponse struct {
+	// Config is the running configuration with secrets redacted, keyed like config.yaml
+	Config map[string]interface{} `json:"config"`
+	// LoadedAt is when the configuration was last loaded or reloaded
+	LoadedAt string `json:"loaded_at"`
+	// PendingRestart lists changed settings that only take effect after a restart
+	PendingRestart []string `json:"pending_restart"`
+}
+
+// Webhook models
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+type WebhookResponse struct {
+	ID        int      `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	// Secret signs the deliveries, only returned when a webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Active defaults to true
+	Active *bool `json:"active,omitempty"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Active is left unchanged when omitted
+	Active *bool `json:"active,omitempty"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID        int64  `json:"id"`
+	EventID   int64  `json:"event_id"`
+	EventType string `json:"event_type"`
+	// Status is pending, succeeded or dead
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// NextAttemptAt is only set while the delivery is pending
+	NextAttemptAt  string `json:"next_attempt_at,omitempty"`
+	LastStatusCode int    `json:"last_status_code,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	DeliveredAt    string `json:"delivered_at,omitempty"`
 }
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/miloradbozic/packing-service/internal/webhooks"
+)
+
+// Limits bounds the values accepted in requests
+type Limits struct {
+	// MaxOrderItems is the largest number of items a calculation accepts
+	MaxOrderItems int
+	// MaxPackSize is the largest pack size that can be created
+	MaxPackSize int
+}
+
+// Validator is implemented by request models. Validate returns every
+// rejected field, or nil when the request is valid.
+type Validator interface {
+	Validate(limits Limits) []FieldError
+}
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+func (r *CalculateRequest) Validate(limits Limits) []FieldError {
+	var errs []FieldError
+	switch {
+	case r.Items <= 0:
+		errs = append(errs, FieldError{Field: "items", Code: "must_be_positive", Message: "items must be positive"})
+	case limits.MaxOrderItems > 0 && r.Items > limits.MaxOrderItems:
+		errs = append(errs, FieldError{Field: "items", Code: "too_large", Message: fmt.Sprintf("items must be at most %d", limits.MaxOrderItems)})
+	}
+	return errs
+}
+
+func (r *CreatePackSizeRequest) Validate(limits Limits) []FieldError {
+	return validatePackSize("size", r.Size, limits, nil)
+}
+
+func (r *UpdatePackSizeRequest) Validate(limits Limits) []FieldError {
+	return validatePackSize("size", r.Size, limits, nil)
+}
+
+func (r *CreateTenantRequest) Validate(limits Limits) []FieldError {
+	var errs []FieldError
+	if !tenantSlugPattern.MatchString(r.Slug) {
+		errs = append(errs, FieldError{Field: "slug", Code: "invalid_format", Message: "slug must be 1-64 lowercase letters, digits or dashes"})
+	}
+	if len(r.Name) > 200 {
+		errs = append(errs, FieldError{Field: "name", Code: "too_long", Message: "name must be at most 200 characters"})
+	}
+	for i, size := range r.PackSizes {
+		errs = validatePackSize(fmt.Sprintf("pack_sizes[%d]", i), size, limits, errs)
+	}
+	return errs
+}
+
+func (r *CreateAPIKeyRequest) Validate(limits Limits) []FieldError {
+	var errs []FieldError
+	if strings.TrimSpace(r.Name) == "" {
+		errs = append(errs, FieldError{Field: "name", Code: "required", Message: "name is required"})
+	}
+	if len(r.Scopes) == 0 {
+		errs = append(errs, FieldError{Field: "scopes", Code: "required", Message: "at least one scope is required"})
+	}
+	return errs
+}
+
+func (r *CreateWebhookRequest) Validate(limits Limits) []FieldError {
+	return validateWebhook(r.URL, r.Events)
+}
+
+func (r *UpdateWebhookRequest) Validate(limits Limits) []FieldError {
+	return validateWebhook(r.URL, r.Events)
+}
+
+// validateWebhook checks the URL and event types of a webhook subscription
+func validateWebhook(rawURL string, events []string) []FieldError {
+	var errs []FieldError
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, FieldError{Field: "url", Code: "invalid_url", Message: "url must be an absolute http or https URL"})
+	} else if len(rawURL) > 2048 {
+		errs = append(errs, FieldError{Field: "url", Code: "too_long", Message: "url must be at most 2048 characters"})
+	}
+
+	if len(events) == 0 {
+		errs = append(errs, FieldError{Field: "events", Code: "required", Message: "at least one event type is required"})
+	}
+	for i, event := range events {
+		if !webhooks.IsEventType(event) {
+			errs = append(errs, FieldError{
+				Field:   fmt.Sprintf("events[%d]", i),
+				Code:    "invalid_event",
+				Message: fmt.Sprintf("unknown event type '%s': must be one of %s", event, strings.Join(webhooks.EventTypes, ", ")),
+			})
+		}
+	}
+	return errs
+}
+
+// ValidatePackSize checks a single pack size against the limits
+func ValidatePackSize(field string, size int, limits Limits) []FieldError {
+	return validatePackSize(field, size, limits, nil)
+}
+
+func validatePackSize(field string, size int, limits Limits, errs []FieldError) []FieldError {
+	switch {
+	case size <= 0:
+		errs = append(errs, FieldError{Field: field, Code: "must_be_positive", Message: fmt.Sprintf("%s must be positive", field)})
+	case limits.MaxPackSize > 0 && size > limits.MaxPackSize:
+		errs = append(errs, FieldError{Field: field, Code: "too_large", Message: fmt.Sprintf("%s must be at most %d", field, limits.MaxPackSize)})
+	}
+	return errs
+}
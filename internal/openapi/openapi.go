@@ -0,0 +1,132 @@
+// Package openapi holds the OpenAPI 3 description of the HTTP API and checks
+// responses against it, so that tests catch the two drifting apart.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the OpenAPI document as JSON
+func Spec() []byte {
+	return spec
+}
+
+// Document is the subset of an OpenAPI document needed to check responses
+type Document struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*Response `json:"responses"`
+	} `json:"components"`
+}
+
+// Operation describes a single method on a path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Response describes a response status, possibly as a reference to a shared one
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType holds the schema of a response body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Load parses the embedded OpenAPI document
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Operations lists every documented operation as "METHOD /path", sorted
+func (d *Document) Operations() []string {
+	var operations []string
+	for path, methods := range d.Paths {
+		for method := range methods {
+			operations = append(operations, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// ValidateResponse checks that a response is documented for the operation and
+// that its body matches the documented schema. path is the route template,
+// e.g. /api/v1/pack-sizes/{id}.
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	operation := d.Paths[path][strings.ToLower(method)]
+	if operation == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+
+	response := operation.Responses[strconv.Itoa(status)]
+	if response == nil {
+		response = operation.Responses["default"]
+	}
+	if response == nil {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+	response, err := d.resolveResponse(response)
+	if err != nil {
+		return err
+	}
+
+	if len(response.Content) == 0 {
+		if len(strings.TrimSpace(string(body))) != 0 {
+			return fmt.Errorf("%s %s: status %d is documented without a body", method, path, status)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid content type %q", method, path, contentType)
+	}
+	media, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s: content type %s is not documented for status %d", method, path, mediaType, status)
+	}
+	if media.Schema == nil || !strings.HasSuffix(mediaType, "json") {
+		return nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s: response body is not valid JSON: %w", method, path, err)
+	}
+	if err := d.validate(media.Schema, value, "body"); err != nil {
+		return fmt.Errorf("%s %s: status %d: %w", method, path, status, err)
+	}
+	return nil
+}
+
+func (d *Document) resolveResponse(response *Response) (*Response, error) {
+	if response.Ref == "" {
+		return response, nil
+	}
+	name := strings.TrimPrefix(response.Ref, "#/components/responses/")
+	resolved, ok := d.Components.Responses[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown response reference %s", response.Ref)
+	}
+	return resolved, nil
+}
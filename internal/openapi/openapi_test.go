@@ -0,0 +1,227 @@
+package openapi
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	doc, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load document: %v", err)
+	}
+	if len(doc.Operations()) == 0 {
+		t.Fatal("expected documented operations")
+	}
+}
+
+func TestSpec_ReferencesResolve(t *testing.T) {
+	var raw interface{}
+	if err := json.Unmarshal(Spec(), &raw); err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	var walk func(value interface{}, at string)
+	walk = func(value interface{}, at string) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok && !resolves(raw, ref) {
+				t.Errorf("%s: reference %s does not resolve", at, ref)
+			}
+			for key, child := range v {
+				walk(child, at+"/"+key)
+			}
+		case []interface{}:
+			for i, child := range v {
+				walk(child, at+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+	walk(raw, "#")
+}
+
+func resolves(root interface{}, ref string) bool {
+	current := root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if current, ok = object[part]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// TestSpec_CoversModels checks that every JSON type in internal/models has a
+// schema of the same name with exactly the same properties
+func TestSpec_CoversModels(t *testing.T) {
+	doc, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load document: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, "../models", nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse models: %v", err)
+	}
+
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				spec, ok := node.(*ast.TypeSpec)
+				if !ok {
+					return true
+				}
+				structType, ok := spec.Type.(*ast.StructType)
+				if !ok {
+					return false
+				}
+				fields := jsonFields(structType)
+				if len(fields) == 0 {
+					return false
+				}
+
+				schema, ok := doc.Components.Schemas[spec.Name.Name]
+				if !ok {
+					t.Errorf("models.%s has no schema", spec.Name.Name)
+					return false
+				}
+				properties := make([]string, 0, len(schema.Properties))
+				for name := range schema.Properties {
+					properties = append(properties, name)
+				}
+				sort.Strings(properties)
+				if !reflect.DeepEqual(fields, properties) {
+					t.Errorf("models.%s has fields %v, schema has %v", spec.Name.Name, fields, properties)
+				}
+				return false
+			})
+		}
+	}
+}
+
+func jsonFields(structType *ast.StructType) []string {
+	var fields []string
+	for _, field := range structType.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		name, _, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestDocument_ValidateResponse(t *testing.T) {
+	doc, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load document: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		status      int
+		contentType string
+		body        string
+		expectError bool
+	}{
+		{
+			name:        "Valid response",
+			method:      "GET",
+			path:        "/api/v1/config",
+			status:      200,
+			contentType: "application/json",
+			body:        `{"pack_sizes": [250, 500]}`,
+		},
+		{
+			name:        "Wrong property type",
+			method:      "GET",
+			path:        "/api/v1/config",
+			status:      200,
+			contentType: "application/json",
+			body:        `{"pack_sizes": ["250"]}`,
+			expectError: true,
+		},
+		{
+			name:        "Undocumented property",
+			method:      "GET",
+			path:        "/api/v1/pack-sizes/{id}",
+			status:      200,
+			contentType: "application/json",
+			body:        `{"id": 1, "size": 250, "created_at": "2026-01-01T00:00:00Z", "updated_at": "2026-01-01T00:00:00Z", "archived": true}`,
+			expectError: true,
+		},
+		{
+			name:        "Missing required property",
+			method:      "POST",
+			path:        "/api/v1/calculate",
+			status:      200,
+			contentType: "application/json",
+			body:        `{"items_ordered": 1}`,
+			expectError: true,
+		},
+		{
+			name:        "Problem through a shared response",
+			method:      "GET",
+			path:        "/api/v1/pack-sizes/{id}",
+			status:      404,
+			contentType: "application/problem+json",
+			body:        `{"type": "urn:packing-service:problem:not_found", "title": "Not Found", "status": 404, "code": "not_found", "error": "not found"}`,
+		},
+		{
+			name:        "Undocumented status",
+			method:      "GET",
+			path:        "/api/v1/config",
+			status:      418,
+			contentType: "application/json",
+			body:        `{}`,
+			expectError: true,
+		},
+		{
+			name:        "Undocumented content type",
+			method:      "GET",
+			path:        "/api/v1/config",
+			status:      200,
+			contentType: "text/plain",
+			body:        `OK`,
+			expectError: true,
+		},
+		{
+			name:   "Empty body",
+			method: "DELETE",
+			path:   "/api/v1/pack-sizes/{id}",
+			status: 204,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.ValidateResponse(tt.method, tt.path, tt.status, tt.contentType, []byte(tt.body))
+			if tt.expectError && err == nil {
+				t.Error("expected an error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
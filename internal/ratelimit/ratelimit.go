@@ -0,0 +1,59 @@
+// Package ratelimit implements token bucket rate limiting with pluggable bucket stores
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket holding up to Burst tokens, refilled at Rate tokens per second
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// Result is the outcome of taking tokens from a bucket
+type Result struct {
+	Allowed bool
+	Limit   float64
+	// Remaining is the number of tokens left after the request
+	Remaining float64
+	// RetryAfter is how long to wait until the request would be allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps bucket state. Take atomically refills the bucket for the time
+// elapsed since it was last used and removes cost tokens if enough are left.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, cost float64) (Result, error)
+}
+
+// Take applies a request of the given cost to a bucket that held tokens elapsed
+// ago, returning the new token count and the result. A cost above the burst is
+// capped at the burst, so even the most expensive request can pass with a full bucket.
+func Take(tokens float64, elapsed time.Duration, limit Limit, cost float64) (float64, Result) {
+	if cost > limit.Burst {
+		cost = limit.Burst
+	}
+
+	tokens = math.Min(limit.Burst, tokens+elapsed.Seconds()*limit.Rate)
+
+	result := Result{Limit: limit.Burst}
+	if tokens >= cost {
+		tokens -= cost
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((cost - tokens) / limit.Rate)
+	}
+
+	result.Remaining = tokens
+	result.Reset = secondsToDuration((limit.Burst - tokens) / limit.Rate)
+	return tokens, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
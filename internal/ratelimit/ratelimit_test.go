@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 10}
+
+	tests := []struct {
+		name              string
+		tokens            float64
+		elapsed           time.Duration
+		cost              float64
+		expectedAllowed   bool
+		expectedRemaining float64
+		expectedRetry     time.Duration
+	}{
+		{name: "Full bucket", tokens: 10, cost: 1, expectedAllowed: true, expectedRemaining: 9},
+		{name: "Refill is capped at burst", tokens: 10, elapsed: time.Hour, cost: 4, expectedAllowed: true, expectedRemaining: 6},
+		{name: "Refill over time", tokens: 0, elapsed: 2 * time.Second, cost: 3, expectedAllowed: true, expectedRemaining: 1},
+		{name: "Not enough tokens", tokens: 1, cost: 3, expectedAllowed: false, expectedRemaining: 1, expectedRetry: time.Second},
+		{name: "Cost above burst is capped", tokens: 10, cost: 1000, expectedAllowed: true, expectedRemaining: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, result := Take(tt.tokens, tt.elapsed, limit, tt.cost)
+
+			if result.Allowed != tt.expectedAllowed {
+				t.Errorf("expected allowed %v, got %v", tt.expectedAllowed, result.Allowed)
+			}
+			if tokens != tt.expectedRemaining || result.Remaining != tt.expectedRemaining {
+				t.Errorf("expected %v tokens left, got %v", tt.expectedRemaining, tokens)
+			}
+			if result.RetryAfter != tt.expectedRetry {
+				t.Errorf("expected retry after %v, got %v", tt.expectedRetry, result.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	for i, expected := range []bool{true, true, false} {
+		result, _ := store.Take(context.Background(), "client", limit, 1)
+		if result.Allowed != expected {
+			t.Fatalf("request %d: expected allowed %v, got %v", i+1, expected, result.Allowed)
+		}
+	}
+
+	// Other clients have their own bucket
+	if result, _ := store.Take(context.Background(), "other", limit, 1); !result.Allowed {
+		t.Errorf("expected another key to be allowed")
+	}
+
+	now = now.Add(time.Second)
+	if result, _ := store.Take(context.Background(), "client", limit, 1); !result.Allowed {
+		t.Errorf("expected the bucket to refill")
+	}
+
+	// Refilled buckets are swept
+	now = now.Add(time.Hour)
+	store.sweep(now)
+	if len(store.buckets) != 0 {
+		t.Errorf("expected idle buckets to be swept, %d left", len(store.buckets))
+	}
+}
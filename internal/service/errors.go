@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrNoPackSizes is returned when a calculation runs without any pack sizes configured
+	ErrNoPackSizes = errors.New("no pack sizes configured")
+	// ErrUnreachable is returned when no combination of pack sizes can fulfill an order
+	ErrUnreachable = errors.New("unable to fulfill order with current pack sizes")
+)
+
+// FieldError describes why a single input field was rejected
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// ValidationError is returned for invalid input and lists every rejected field
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError returns a validation error for a single field
+func NewValidationError(field, code, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
@@ -1,14 +1,32 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"sort"
+	"time"
 
 	"github.com/miloradbozic/packing-service/internal/database"
+	"github.com/miloradbozic/packing-service/internal/tracing"
+	"github.com/miloradbozic/packing-service/internal/webhooks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/miloradbozic/packing-service/internal/service")
+
 type PackingService struct {
 	packSizeRepo database.PackSizeRepositoryInterface
+	observer     Observer
+	events       EventRecorder
+}
+
+// Observer is told about every calculation, e.g. to record metrics. tableSize
+// is the number of cells in the solver's table, 0 when the solver did not run.
+type Observer interface {
+	ObserveCalculation(items, tableSize int, duration time.Duration, err error)
 }
 
 func NewPackingService(packSizeRepo database.PackSizeRepositoryInterface) *PackingService {
@@ -17,50 +35,128 @@ func NewPackingService(packSizeRepo database.PackSizeRepositoryInterface) *Packi
 	}
 }
 
+// SetObserver reports every calculation to observer
+func (ps *PackingService) SetObserver(observer Observer) {
+	ps.observer = observer
+}
+
+// EventRecorder stores events in the outbox for webhook delivery
+type EventRecorder interface {
+	Record(ctx context.Context, eventType string, data interface{}) error
+}
+
+// SetEventRecorder records an order.calculated event for every successful
+// calculation. Failures to record are only logged: a calculation changes
+// nothing, so it is not worth failing over a lost event.
+func (ps *PackingService) SetEventRecorder(events EventRecorder) {
+	ps.events = events
+}
+
+// calculationEvent is the payload of order.calculated events, shaped like the
+// calculation responses of the API
+type calculationEvent struct {
+	Items       int                    `json:"items"`
+	TotalItems  int                    `json:"total_items"`
+	TotalPacks  int                    `json:"total_packs"`
+	Packs       []calculationEventPack `json:"packs"`
+	ExcessItems int                    `json:"excess_items"`
+}
+
+type calculationEventPack struct {
+	Size     int `json:"size"`
+	Quantity int `json:"quantity"`
+}
+
 type PackSolution struct {
 	Packs      map[int]int
 	TotalItems int
 	TotalPacks int
 }
 
-func (ps *PackingService) CalculatePacks(itemsOrdered int) (*PackSolution, error) {
+func (ps *PackingService) CalculatePacks(ctx context.Context, itemsOrdered int) (*PackSolution, error) {
+	ctx, span := tracer.Start(ctx, "PackingService.CalculatePacks", trace.WithAttributes(attribute.Int("packing.items_ordered", itemsOrdered)))
+	defer span.End()
+
+	start := time.Now()
+	solution, tableSize, err := ps.calculatePacks(ctx, itemsOrdered)
+	if err == nil && ps.events != nil {
+		if recordErr := ps.recordCalculation(ctx, itemsOrdered, solution); recordErr != nil {
+			slog.WarnContext(ctx, "failed to record calculation", "items", itemsOrdered, "error", recordErr)
+		}
+	}
+	if ps.observer != nil {
+		ps.observer.ObserveCalculation(itemsOrdered, tableSize, time.Since(start), err)
+	}
+	tracing.RecordError(span, err)
+	return solution, err
+}
+
+// recordCalculation records the order.calculated event of a solution
+func (ps *PackingService) recordCalculation(ctx context.Context, itemsOrdered int, solution *PackSolution) error {
+	event := calculationEvent{
+		Items:       itemsOrdered,
+		TotalItems:  solution.TotalItems,
+		TotalPacks:  solution.TotalPacks,
+		Packs:       make([]calculationEventPack, 0, len(solution.Packs)),
+		ExcessItems: solution.TotalItems - itemsOrdered,
+	}
+	for size, quantity := range solution.Packs {
+		event.Packs = append(event.Packs, calculationEventPack{Size: size, Quantity: quantity})
+	}
+	sort.Slice(event.Packs, func(i, j int) bool { return event.Packs[i].Size > event.Packs[j].Size })
+
+	if err := ps.events.Record(ctx, webhooks.EventOrderCalculated, event); err != nil {
+		return fmt.Errorf("failed to record calculation: %w", err)
+	}
+	return nil
+}
+
+// calculatePacks returns the solution and the size of the solver's table
+func (ps *PackingService) calculatePacks(ctx context.Context, itemsOrdered int) (*PackSolution, int, error) {
 	if itemsOrdered <= 0 {
-		return nil, fmt.Errorf("items ordered must be positive")
+		return nil, 0, NewValidationError("items", "must_be_positive", "items ordered must be positive")
 	}
 
 	// Get all pack sizes from database
-	packSizeObjects, err := ps.packSizeRepo.GetAll()
+	packSizeObjects, err := ps.packSizeRepo.GetAll(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pack sizes: %w", err)
+		return nil, 0, fmt.Errorf("failed to get pack sizes: %w", err)
 	}
 
 	if len(packSizeObjects) == 0 {
-		return nil, fmt.Errorf("no pack sizes configured")
+		return nil, 0, ErrNoPackSizes
 	}
 
-	// Extract just the sizes for the algorithm and validate
+	// Extract just the active sizes for the algorithm and validate
 	packSizes := make([]int, 0, len(packSizeObjects))
 	for _, ps := range packSizeObjects {
+		if !ps.Active {
+			continue
+		}
 		if ps.Size <= 0 {
-			return nil, fmt.Errorf("invalid pack size: %d (must be positive)", ps.Size)
+			return nil, 0, fmt.Errorf("invalid pack size: %d (must be positive)", ps.Size)
 		}
 		packSizes = append(packSizes, ps.Size)
 	}
 
 	if len(packSizes) == 0 {
-		return nil, fmt.Errorf("no valid pack sizes configured")
+		return nil, 0, ErrNoPackSizes
 	}
 
-	solution := ps.findOptimalSolution(itemsOrdered, packSizes)
+	_, solverSpan := tracer.Start(ctx, "PackingService.findOptimalSolution", trace.WithAttributes(attribute.Int("packing.pack_sizes", len(packSizes))))
+	solution, tableSize := ps.findOptimalSolution(itemsOrdered, packSizes)
+	solverSpan.SetAttributes(attribute.Int("packing.table_size", tableSize))
+	solverSpan.End()
 
 	if solution == nil {
-		return nil, fmt.Errorf("unable to fulfill order with current pack sizes")
+		return nil, tableSize, ErrUnreachable
 	}
 
-	return solution, nil
+	return solution, tableSize, nil
 }
 
-func (ps *PackingService) findOptimalSolution(target int, packSizes []int) *PackSolution {
+// findOptimalSolution also returns the number of cells in its table
+func (ps *PackingService) findOptimalSolution(target int, packSizes []int) (*PackSolution, int) {
 	// Sort pack sizes in descending order for optimization
 	sizes := make([]int, len(packSizes))
 	copy(sizes, packSizes)
@@ -105,7 +201,7 @@ func (ps *PackingService) findOptimalSolution(target int, packSizes []int) *Pack
 	}
 
 	if minItems == -1 {
-		return nil
+		return nil, len(dp)
 	}
 
 	// Reconstruct the solution
@@ -124,19 +220,21 @@ func (ps *PackingService) findOptimalSolution(target int, packSizes []int) *Pack
 		Packs:      packs,
 		TotalItems: minItems,
 		TotalPacks: totalPacks,
-	}
+	}, len(dp)
 }
 
-func (ps *PackingService) GetPackSizes() ([]int, error) {
-	packSizeObjects, err := ps.packSizeRepo.GetAll()
+func (ps *PackingService) GetPackSizes(ctx context.Context) ([]int, error) {
+	packSizeObjects, err := ps.packSizeRepo.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract just the sizes
-	sizes := make([]int, len(packSizeObjects))
-	for i, ps := range packSizeObjects {
-		sizes[i] = ps.Size
+	// Extract just the active sizes
+	sizes := make([]int, 0, len(packSizeObjects))
+	for _, ps := range packSizeObjects {
+		if ps.Active {
+			sizes = append(sizes, ps.Size)
+		}
 	}
 
 	return sizes, nil
@@ -1,24 +1,33 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/miloradbozic/packing-service/internal/database"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // Mock repository for testing
 type mockPackSizeRepository struct {
-	sizes []int
+	sizes    []int
+	imported []database.PackSize
+	replaced bool
 }
 
-func (m *mockPackSizeRepository) GetAll() ([]database.PackSize, error) {
+func (m *mockPackSizeRepository) GetAll(ctx context.Context) ([]database.PackSize, error) {
 	// Convert sizes to PackSize objects for testing
 	packSizes := make([]database.PackSize, len(m.sizes))
 	for i, size := range m.sizes {
 		packSizes[i] = database.PackSize{
 			ID:        i + 1,
 			Size:      size,
+			Active:    true,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
@@ -26,19 +35,29 @@ func (m *mockPackSizeRepository) GetAll() ([]database.PackSize, error) {
 	return packSizes, nil
 }
 
-func (m *mockPackSizeRepository) GetByID(id int) (*database.PackSize, error) {
+func (m *mockPackSizeRepository) GetByID(ctx context.Context, id int) (*database.PackSize, error) {
 	return nil, nil
 }
 
-func (m *mockPackSizeRepository) Create(size int) (*database.PackSize, error) {
+func (m *mockPackSizeRepository) Create(ctx context.Context, size int, active bool) (*database.PackSize, error) {
 	return nil, nil
 }
 
-func (m *mockPackSizeRepository) Update(id int, size int) (*database.PackSize, error) {
+func (m *mockPackSizeRepository) Update(ctx context.Context, id int, size int, active *bool) (*database.PackSize, error) {
 	return nil, nil
 }
 
-func (m *mockPackSizeRepository) Delete(id int) error {
+func (m *mockPackSizeRepository) List(ctx context.Context, query database.PackSizeQuery) (*database.PackSizePage, error) {
+	return nil, nil
+}
+
+func (m *mockPackSizeRepository) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockPackSizeRepository) Import(ctx context.Context, packSizes []database.PackSize, replace bool) error {
+	m.imported = packSizes
+	m.replaced = replace
 	return nil
 }
 
@@ -241,7 +260,7 @@ func TestPackingService_CalculatePacks(t *testing.T) {
 			mockRepo := &mockPackSizeRepository{sizes: tt.packSizes}
 			service := NewPackingService(mockRepo)
 
-			solution, err := service.CalculatePacks(tt.itemsOrdered)
+			solution, err := service.CalculatePacks(context.Background(), tt.itemsOrdered)
 
 			if tt.expectError {
 				if err == nil {
@@ -275,12 +294,137 @@ func TestPackingService_CalculatePacks(t *testing.T) {
 	}
 }
 
+type recordedCalculation struct {
+	items     int
+	tableSize int
+	err       error
+}
+
+type mockObserver struct {
+	calculations []recordedCalculation
+}
+
+func (m *mockObserver) ObserveCalculation(items, tableSize int, duration time.Duration, err error) {
+	m.calculations = append(m.calculations, recordedCalculation{items: items, tableSize: tableSize, err: err})
+}
+
+func TestPackingService_Observer(t *testing.T) {
+	observer := &mockObserver{}
+	service := NewPackingService(&mockPackSizeRepository{sizes: []int{250, 500}})
+	service.SetObserver(observer)
+
+	service.CalculatePacks(context.Background(), 251)
+	service.CalculatePacks(context.Background(), -1)
+
+	if len(observer.calculations) != 2 {
+		t.Fatalf("expected 2 observed calculations, got %d", len(observer.calculations))
+	}
+	// The table covers the order plus one of the largest pack
+	if got := observer.calculations[0]; got.items != 251 || got.tableSize != 752 || got.err != nil {
+		t.Errorf("unexpected first calculation: %+v", got)
+	}
+	if got := observer.calculations[1]; got.tableSize != 0 || got.err == nil {
+		t.Errorf("expected a rejected calculation without a table, got %+v", got)
+	}
+}
+
+type mockEventRecorder struct {
+	events []interface{}
+	err    error
+}
+
+func (m *mockEventRecorder) Record(ctx context.Context, eventType string, data interface{}) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, data)
+	return nil
+}
+
+func TestPackingService_EventRecorder(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       int
+		recordErr   error
+		expected    []interface{}
+		expectError bool
+	}{
+		{
+			name:  "Calculation is recorded",
+			items: 501,
+			expected: []interface{}{calculationEvent{
+				Items:       501,
+				TotalItems:  750,
+				TotalPacks:  2,
+				Packs:       []calculationEventPack{{Size: 500, Quantity: 1}, {Size: 250, Quantity: 1}},
+				ExcessItems: 249,
+			}},
+		},
+		{
+			name:        "Rejected calculation is not recorded",
+			items:       -1,
+			expectError: true,
+		},
+		{
+			name:      "Failure to record keeps the calculation",
+			items:     251,
+			recordErr: errors.New("outbox unavailable"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &mockEventRecorder{err: tt.recordErr}
+			service := NewPackingService(&mockPackSizeRepository{sizes: []int{250, 500}})
+			service.SetEventRecorder(events)
+
+			solution, err := service.CalculatePacks(context.Background(), tt.items)
+			if tt.expectError {
+				if err == nil || solution != nil {
+					t.Errorf("expected an error and no solution, got %v and %+v", err, solution)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(events.events, tt.expected) {
+				t.Errorf("expected events %+v, got %+v", tt.expected, events.events)
+			}
+		})
+	}
+}
+
+func TestPackingService_Spans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	service := NewPackingService(&mockPackSizeRepository{sizes: []int{250, 500}})
+	if _, err := service.CalculatePacks(context.Background(), 251); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	// Spans are exported as they end, so the solver comes first
+	solver, calculation := spans[0], spans[1]
+	if solver.Name != "PackingService.findOptimalSolution" || calculation.Name != "PackingService.CalculatePacks" {
+		t.Fatalf("unexpected spans %s, %s", solver.Name, calculation.Name)
+	}
+	if solver.Parent.SpanID() != calculation.SpanContext.SpanID() {
+		t.Error("expected the solver span to be a child of the calculation span")
+	}
+}
+
 func TestPackingService_GetPackSizes(t *testing.T) {
 	packSizes := []int{250, 500, 1000, 2000, 5000}
 	mockRepo := &mockPackSizeRepository{sizes: packSizes}
 	service := NewPackingService(mockRepo)
 
-	sizes, err := service.GetPackSizes()
+	sizes, err := service.GetPackSizes(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -295,3 +439,81 @@ func TestPackingService_GetPackSizes(t *testing.T) {
 		}
 	}
 }
+
+func TestPackingService_ImportPackSizes(t *testing.T) {
+	imported := []database.PackSize{{Size: 250, Active: true}, {Size: 750, Active: true}, {Size: 1000, Active: false}}
+
+	tests := []struct {
+		name              string
+		mode              ImportMode
+		dryRun            bool
+		expectedCreated   []int
+		expectedUpdated   []int
+		expectedDeleted   []int
+		expectedUnchanged []int
+	}{
+		{
+			name:              "Merge",
+			mode:              ImportModeMerge,
+			expectedCreated:   []int{750},
+			expectedUpdated:   []int{1000},
+			expectedDeleted:   []int{},
+			expectedUnchanged: []int{250, 500},
+		},
+		{
+			name:              "Replace",
+			mode:              ImportModeReplace,
+			expectedCreated:   []int{750},
+			expectedUpdated:   []int{1000},
+			expectedDeleted:   []int{500},
+			expectedUnchanged: []int{250},
+		},
+		{
+			name:              "Replace dry run",
+			mode:              ImportModeReplace,
+			dryRun:            true,
+			expectedCreated:   []int{750},
+			expectedUpdated:   []int{1000},
+			expectedDeleted:   []int{500},
+			expectedUnchanged: []int{250},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockPackSizeRepository{sizes: []int{250, 500, 1000}}
+			service := NewPackingService(mockRepo)
+
+			result, err := service.ImportPackSizes(context.Background(), imported, tt.mode, tt.dryRun)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertInts(t, "created", tt.expectedCreated, result.Created)
+			assertInts(t, "updated", tt.expectedUpdated, result.Updated)
+			assertInts(t, "deleted", tt.expectedDeleted, result.Deleted)
+			assertInts(t, "unchanged", tt.expectedUnchanged, result.Unchanged)
+
+			if tt.dryRun && mockRepo.imported != nil {
+				t.Errorf("dry run should not write to the repository")
+			}
+			if !tt.dryRun && mockRepo.replaced != (tt.mode == ImportModeReplace) {
+				t.Errorf("expected replace=%v, got %v", tt.mode == ImportModeReplace, mockRepo.replaced)
+			}
+		})
+	}
+}
+
+func assertInts(t *testing.T, label string, expected, got []int) {
+	t.Helper()
+	if len(expected) != len(got) {
+		t.Errorf("expected %s %v, got %v", label, expected, got)
+		return
+	}
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Errorf("expected %s %v, got %v", label, expected, got)
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/miloradbozic/packing-service/internal/database"
+)
+
+// ImportMode controls how an imported pack size set is combined with the existing one
+type ImportMode string
+
+const (
+	// ImportModeMerge adds missing pack sizes and keeps the ones not imported
+	ImportModeMerge ImportMode = "merge"
+	// ImportModeReplace makes the stored pack sizes match the imported set exactly
+	ImportModeReplace ImportMode = "replace"
+)
+
+// ParseImportMode converts a user supplied mode name into an ImportMode
+func ParseImportMode(name string) (ImportMode, error) {
+	switch ImportMode(name) {
+	case "", ImportModeMerge:
+		return ImportModeMerge, nil
+	case ImportModeReplace:
+		return ImportModeReplace, nil
+	default:
+		return "", fmt.Errorf("unsupported import mode '%s': must be merge or replace", name)
+	}
+}
+
+// ImportResult describes the changes an import made, or would make on a dry run
+type ImportResult struct {
+	Mode      ImportMode
+	DryRun    bool
+	Created   []int
+	Updated   []int
+	Deleted   []int
+	Unchanged []int
+}
+
+// ExportPackSizes returns all pack sizes with their full metadata
+func (ps *PackingService) ExportPackSizes(ctx context.Context) ([]database.PackSize, error) {
+	packSizes, err := ps.packSizeRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pack sizes: %w", err)
+	}
+	return packSizes, nil
+}
+
+// ImportPackSizes applies an imported pack size set. With dryRun set the changes
+// are computed and returned without being written.
+func (ps *PackingService) ImportPackSizes(ctx context.Context, packSizes []database.PackSize, mode ImportMode, dryRun bool) (*ImportResult, error) {
+	existing, err := ps.packSizeRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pack sizes: %w", err)
+	}
+
+	result := planImport(existing, packSizes, mode)
+	result.DryRun = dryRun
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := ps.packSizeRepo.Import(ctx, packSizes, mode == ImportModeReplace); err != nil {
+		return nil, fmt.Errorf("failed to import pack sizes: %w", err)
+	}
+
+	return result, nil
+}
+
+func planImport(existing, imported []database.PackSize, mode ImportMode) *ImportResult {
+	result := &ImportResult{
+		Mode:      mode,
+		Created:   []int{},
+		Updated:   []int{},
+		Deleted:   []int{},
+		Unchanged: []int{},
+	}
+
+	current := make(map[int]database.PackSize, len(existing))
+	for _, p := range existing {
+		current[p.Size] = p
+	}
+
+	incoming := make(map[int]bool, len(imported))
+	for _, p := range imported {
+		incoming[p.Size] = true
+		stored, ok := current[p.Size]
+		switch {
+		case !ok:
+			result.Created = append(result.Created, p.Size)
+		case stored.Active != p.Active:
+			result.Updated = append(result.Updated, p.Size)
+		default:
+			result.Unchanged = append(result.Unchanged, p.Size)
+		}
+	}
+
+	for _, p := range existing {
+		if incoming[p.Size] {
+			continue
+		}
+		if mode == ImportModeReplace {
+			result.Deleted = append(result.Deleted, p.Size)
+		} else {
+			result.Unchanged = append(result.Unchanged, p.Size)
+		}
+	}
+
+	sort.Ints(result.Created)
+	sort.Ints(result.Updated)
+	sort.Ints(result.Deleted)
+	sort.Ints(result.Unchanged)
+
+	return result
+}
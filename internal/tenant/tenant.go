@@ -0,0 +1,22 @@
+package tenant
+
+import "context"
+
+// Tenant identifies the client company a request acts on behalf of
+type Tenant struct {
+	ID   int
+	Slug string
+}
+
+type contextKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant stored in ctx, if any
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(Tenant)
+	return t, ok
+}
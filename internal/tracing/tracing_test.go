@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/miloradbozic/packing-service/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useInMemoryProvider installs a global tracer provider recording into the returned exporter
+func useInMemoryProvider(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	if _, err := Setup(context.Background(), config.TracingConfig{}); err != nil {
+		t.Fatalf("failed to set up tracing: %v", err)
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(NewProvider(config.TracingConfig{}, sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := useInMemoryProvider(t)
+
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/pack-sizes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("expected the handler context to carry the request span")
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/pack-sizes/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "GET /pack-sizes/{id}" {
+		t.Errorf("expected span name 'GET /pack-sizes/{id}', got '%s'", span.Name)
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("expected a server span, got %v", span.SpanKind)
+	}
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the trace of the traceparent header, got %s", got)
+	}
+	if got := span.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("expected the parent span of the traceparent header, got %s", got)
+	}
+	if !hasAttribute(span.Attributes, attribute.String("http.route", "/pack-sizes/{id}")) {
+		t.Errorf("expected http.route attribute, got %v", span.Attributes)
+	}
+	if !hasAttribute(span.Attributes, attribute.Int("http.response.status_code", http.StatusNotFound)) {
+		t.Errorf("expected http.response.status_code attribute, got %v", span.Attributes)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.TracingConfig
+		expectError bool
+	}{
+		{name: "Disabled", cfg: config.TracingConfig{Exporter: "unknown"}},
+		{name: "Stdout exporter", cfg: config.TracingConfig{Enabled: true, Exporter: "stdout"}},
+		{name: "OTLP exporter", cfg: config.TracingConfig{Enabled: true, Endpoint: "http://localhost:4318"}},
+		{name: "Unknown exporter", cfg: config.TracingConfig{Enabled: true, Exporter: "zipkin"}, expectError: true},
+	}
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tt.cfg)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("unexpected shutdown error: %v", err)
+			}
+		})
+	}
+}
+
+func hasAttribute(attributes []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attributes {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/database"
+	"gopkg.in/yaml.v3"
+)
+
+// Format identifies a pack size export/import encoding
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatCSV  Format = "csv"
+)
+
+// DocumentVersion is the version written into JSON and YAML exports
+const DocumentVersion = 1
+
+var csvHeader = []string{"id", "size", "active", "created_at", "updated_at"}
+
+// legacyCSVHeader is the header of CSV exports made before the active column
+var legacyCSVHeader = []string{"id", "size", "created_at", "updated_at"}
+
+// Document is the envelope used for JSON and YAML exports
+type Document struct {
+	Version   int     `json:"version" yaml:"version"`
+	PackSizes []Entry `json:"pack_sizes" yaml:"pack_sizes"`
+}
+
+// Entry is an exported pack size. Active is always exported; imports without
+// it make the pack size active.
+type Entry struct {
+	ID        int       `json:"id" yaml:"id"`
+	Size      int       `json:"size" yaml:"size"`
+	Active    *bool     `json:"active,omitempty" yaml:"active,omitempty"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// ParseFormat converts a user supplied format name into a Format
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s': must be one of json, yaml, csv", name)
+	}
+}
+
+// FormatFromContentType guesses the format from an HTTP Content-Type header
+func FormatFromContentType(contentType string) (Format, bool) {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return FormatJSON, true
+	case strings.Contains(contentType, "yaml"):
+		return FormatYAML, true
+	case strings.Contains(contentType, "csv"):
+		return FormatCSV, true
+	default:
+		return "", false
+	}
+}
+
+// ContentType returns the MIME type for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatYAML:
+		return "application/yaml"
+	case FormatCSV:
+		return "text/csv"
+	default:
+		return "application/json"
+	}
+}
+
+// Encode writes the pack sizes to w in the given format
+func Encode(w io.Writer, format Format, packSizes []database.PackSize) error {
+	doc := Document{Version: DocumentVersion, PackSizes: make([]Entry, len(packSizes))}
+	for i, ps := range packSizes {
+		active := ps.Active
+		doc.PackSizes[i] = Entry{ID: ps.ID, Size: ps.Size, Active: &active, CreatedAt: ps.CreatedAt, UpdatedAt: ps.UpdatedAt}
+	}
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		defer encoder.Close()
+		return encoder.Encode(doc)
+	case FormatCSV:
+		return encodeCSV(w, packSizes)
+	default:
+		return fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// Decode reads pack sizes from r in the given format and validates them
+func Decode(r io.Reader, format Format) ([]database.PackSize, error) {
+	var packSizes []database.PackSize
+
+	switch format {
+	case FormatJSON, FormatYAML:
+		var doc Document
+		var err error
+		if format == FormatJSON {
+			err = json.NewDecoder(r).Decode(&doc)
+		} else {
+			err = yaml.NewDecoder(r).Decode(&doc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s document: %w", format, err)
+		}
+		if doc.Version != 0 && doc.Version != DocumentVersion {
+			return nil, fmt.Errorf("unsupported document version %d", doc.Version)
+		}
+		for _, entry := range doc.PackSizes {
+			ps := database.PackSize{ID: entry.ID, Size: entry.Size, Active: true, CreatedAt: entry.CreatedAt, UpdatedAt: entry.UpdatedAt}
+			if entry.Active != nil {
+				ps.Active = *entry.Active
+			}
+			packSizes = append(packSizes, ps)
+		}
+	case FormatCSV:
+		var err error
+		packSizes, err = decodeCSV(r)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+
+	if err := validate(packSizes); err != nil {
+		return nil, err
+	}
+
+	return packSizes, nil
+}
+
+func encodeCSV(w io.Writer, packSizes []database.PackSize) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, ps := range packSizes {
+		record := []string{
+			strconv.Itoa(ps.ID),
+			strconv.Itoa(ps.Size),
+			strconv.FormatBool(ps.Active),
+			ps.CreatedAt.Format(time.RFC3339Nano),
+			ps.UpdatedAt.Format(time.RFC3339Nano),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func decodeCSV(r io.Reader) ([]database.PackSize, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	hasActive := matchesHeader(header, csvHeader)
+	if !hasActive && !matchesHeader(header, legacyCSVHeader) {
+		return nil, fmt.Errorf("invalid csv header: expected %s", strings.Join(csvHeader, ","))
+	}
+	reader.FieldsPerRecord = len(header)
+	createdAt, updatedAt := 2, 3
+	if hasActive {
+		createdAt, updatedAt = 3, 4
+	}
+
+	var packSizes []database.PackSize
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		ps := database.PackSize{Active: true}
+		if record[0] != "" {
+			if ps.ID, err = strconv.Atoi(record[0]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid id '%s'", line, record[0])
+			}
+		}
+		if ps.Size, err = strconv.Atoi(record[1]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid size '%s'", line, record[1])
+		}
+		if hasActive && record[2] != "" {
+			if ps.Active, err = strconv.ParseBool(record[2]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid active '%s'", line, record[2])
+			}
+		}
+		if ps.CreatedAt, err = parseTime(record[createdAt]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid created_at '%s'", line, record[createdAt])
+		}
+		if ps.UpdatedAt, err = parseTime(record[updatedAt]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid updated_at '%s'", line, record[updatedAt])
+		}
+		packSizes = append(packSizes, ps)
+	}
+
+	return packSizes, nil
+}
+
+func matchesHeader(header, expected []string) bool {
+	if len(header) != len(expected) {
+		return false
+	}
+	for i, column := range expected {
+		if strings.TrimSpace(header[i]) != column {
+			return false
+		}
+	}
+	return true
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func validate(packSizes []database.PackSize) error {
+	seen := make(map[int]bool, len(packSizes))
+	for _, ps := range packSizes {
+		if ps.Size <= 0 {
+			return fmt.Errorf("invalid pack size: %d (must be positive)", ps.Size)
+		}
+		if seen[ps.Size] {
+			return fmt.Errorf("duplicate pack size: %d", ps.Size)
+		}
+		seen[ps.Size] = true
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package transfer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miloradbozic/packing-service/internal/database"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 1, 1, 10, 30, 0, 123456000, time.UTC)
+	updated := time.Date(2024, 2, 15, 8, 0, 0, 0, time.UTC)
+	packSizes := []database.PackSize{
+		{ID: 1, Size: 250, Active: true, CreatedAt: created, UpdatedAt: updated},
+		{ID: 7, Size: 5000, Active: false, CreatedAt: created, UpdatedAt: created},
+	}
+
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, format, packSizes); err != nil {
+				t.Fatalf("unexpected encode error: %v", err)
+			}
+
+			decoded, err := Decode(&buf, format)
+			if err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+
+			if len(decoded) != len(packSizes) {
+				t.Fatalf("expected %d pack sizes, got %d", len(packSizes), len(decoded))
+			}
+
+			for i, expected := range packSizes {
+				got := decoded[i]
+				if got.ID != expected.ID || got.Size != expected.Size || got.Active != expected.Active {
+					t.Errorf("expected pack size %+v, got %+v", expected, got)
+				}
+				if !got.CreatedAt.Equal(expected.CreatedAt) || !got.UpdatedAt.Equal(expected.UpdatedAt) {
+					t.Errorf("timestamps not preserved for size %d: got %v / %v", expected.Size, got.CreatedAt, got.UpdatedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestDecode_ActiveDefault(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{name: "JSON without active", format: FormatJSON, input: `{"version": 1, "pack_sizes": [{"size": 250}]}`},
+		{name: "YAML without active", format: FormatYAML, input: "pack_sizes:\n  - size: 250\n"},
+		{name: "Legacy csv", format: FormatCSV, input: "id,size,created_at,updated_at\n1,250,,\n"},
+		{name: "Empty csv active", format: FormatCSV, input: "id,size,active,created_at,updated_at\n1,250,,,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := Decode(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(decoded) != 1 || decoded[0].Size != 250 || !decoded[0].Active {
+				t.Errorf("expected an active pack size 250, got %+v", decoded)
+			}
+		})
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{name: "Negative size", format: FormatJSON, input: `{"version": 1, "pack_sizes": [{"size": -1}]}`},
+		{name: "Duplicate size", format: FormatYAML, input: "pack_sizes:\n  - size: 250\n  - size: 250\n"},
+		{name: "Unknown version", format: FormatJSON, input: `{"version": 99, "pack_sizes": []}`},
+		{name: "Bad csv header", format: FormatCSV, input: "size,id,created_at,updated_at\n"},
+		{name: "Bad csv size", format: FormatCSV, input: "id,size,created_at,updated_at\n1,abc,,\n"},
+		{name: "Bad csv active", format: FormatCSV, input: "id,size,active,created_at,updated_at\n1,250,maybe,,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(strings.NewReader(tt.input), tt.format); err == nil {
+				t.Errorf("expected error but got none")
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(""); err != nil || f != FormatJSON {
+		t.Errorf("expected json default, got %q (%v)", f, err)
+	}
+	if f, err := ParseFormat("YML"); err != nil || f != FormatYAML {
+		t.Errorf("expected yaml, got %q (%v)", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,327 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Delivery states
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	// StatusDead marks deliveries that failed every attempt. They stay in the
+	// delivery log until retried by hand or removed with their event.
+	StatusDead = "dead"
+)
+
+// Delivery is one event due to be sent to one subscription
+type Delivery struct {
+	ID             int64
+	SubscriptionID int
+	URL            string
+	Secret         string
+	EventID        int64
+	EventType      string
+	Tenant         string
+	// Payload is the JSON data of the event
+	Payload   json.RawMessage
+	CreatedAt time.Time
+	// Attempts is the number of attempts made before this one
+	Attempts int
+}
+
+// Attempt is the outcome of sending a delivery once
+type Attempt struct {
+	DeliveryID int64
+	// Status is the state of the delivery after the attempt
+	Status   string
+	Attempts int
+	// NextAttemptAt is when a pending delivery is tried again
+	NextAttemptAt time.Time
+	// StatusCode is the response status, 0 when no response was received
+	StatusCode int
+	Error      string
+}
+
+// Store moves events from the outbox to deliveries and keeps track of them
+type Store interface {
+	// DispatchEvents creates a pending delivery for every active subscription to
+	// each of up to limit undispatched events, and returns how many events it handled
+	DispatchEvents(ctx context.Context, limit int) (int, error)
+	// ClaimDeliveries returns up to limit due deliveries and postpones them by
+	// lease, so other replicas skip them while they are being sent
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	// RecordAttempt stores the outcome of an attempt
+	RecordAttempt(ctx context.Context, attempt Attempt) error
+	// Cleanup deletes finished events recorded before the given time, with their deliveries
+	Cleanup(ctx context.Context, before time.Time) error
+}
+
+// DispatcherConfig tunes delivery; zero values get the defaults of NewDispatcher
+type DispatcherConfig struct {
+	// PollInterval is how often the outbox and due deliveries are checked (default 5s)
+	PollInterval time.Duration
+	// Timeout bounds each request to a subscriber (default 10s)
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is tried before it is dead (default 8)
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failure, doubled after each
+	// further failure up to MaxBackoff (defaults 30s and 1h)
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Retention is how long finished events and their deliveries are kept (default 7 days)
+	Retention time.Duration
+	// BatchSize is the number of events and deliveries handled per poll (default 100)
+	BatchSize int
+	// Concurrency is the number of deliveries sent at once (default 4)
+	Concurrency int
+	// AllowedNetworks may be reached even though they are internal, e.g. for
+	// receivers in the same cluster; other internal addresses are refused
+	AllowedNetworks []netip.Prefix
+}
+
+// cleanupInterval is how often finished events are deleted
+const cleanupInterval = time.Hour
+
+// Dispatcher sends due deliveries in the background. Deliveries are sent at
+// least once: a delivery interrupted by a crash is sent again once its lease
+// expires, so receivers should deduplicate by the X-Webhook-Delivery header.
+type Dispatcher struct {
+	store  Store
+	config DispatcherConfig
+	client *http.Client
+	now    func() time.Time
+
+	lastCleanup time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDispatcher(store Store, cfg DispatcherConfig) *Dispatcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 30 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+
+	return &Dispatcher{
+		store:  store,
+		config: cfg,
+		client: &http.Client{
+			Transport: newTransport(cfg.AllowedNetworks),
+			Timeout:   cfg.Timeout,
+			// A redirect is reported as a failure instead of being followed, so
+			// the signed body is only ever sent to the subscribed URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now:         time.Now,
+		lastCleanup: time.Now(),
+	}
+}
+
+// Start polls for work every poll interval until Stop is called
+func (d *Dispatcher) Start() {
+	if d.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to deliver webhooks", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop aborts deliveries in flight and waits for the dispatcher to finish.
+// Aborted deliveries are sent again after their lease expires.
+func (d *Dispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+	d.cancel()
+	<-d.done
+	d.cancel = nil
+}
+
+// RunOnce moves new events from the outbox to deliveries, sends the due
+// deliveries and deletes old events once per cleanup interval
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	for {
+		dispatched, err := d.store.DispatchEvents(ctx, d.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to dispatch events: %w", err)
+		}
+		if dispatched < d.config.BatchSize {
+			break
+		}
+	}
+
+	// A claimed delivery may take the full timeout, and a little longer to record
+	lease := 2*d.config.Timeout + d.config.PollInterval
+	deliveries, err := d.store.ClaimDeliveries(ctx, d.config.BatchSize, lease)
+	if err != nil {
+		return fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	work := make(chan Delivery)
+	var wg sync.WaitGroup
+	for i := 0; i < d.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range work {
+				d.deliver(ctx, delivery)
+			}
+		}()
+	}
+	for _, delivery := range deliveries {
+		work <- delivery
+	}
+	close(work)
+	wg.Wait()
+
+	if now := d.now(); now.Sub(d.lastCleanup) >= cleanupInterval {
+		d.lastCleanup = now
+		if err := d.store.Cleanup(ctx, now.Add(-d.config.Retention)); err != nil {
+			return fmt.Errorf("failed to delete old webhook events: %w", err)
+		}
+	}
+	return nil
+}
+
+// envelope is the JSON body of every delivery
+type envelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Tenant    string          `json:"tenant"`
+	CreatedAt string          `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// deliver sends a delivery once and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	attempt := Attempt{DeliveryID: delivery.ID, Attempts: delivery.Attempts + 1}
+
+	statusCode, err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Shutting down: the delivery is sent again when its lease expires
+		return
+	}
+	attempt.StatusCode = statusCode
+
+	switch {
+	case err == nil:
+		attempt.Status = StatusSucceeded
+	case attempt.Attempts >= d.config.MaxAttempts:
+		attempt.Status = StatusDead
+		attempt.Error = err.Error()
+		slog.Error("Webhook delivery failed permanently", "delivery_id", delivery.ID, "url", delivery.URL, "attempts", attempt.Attempts, "error", err)
+	default:
+		attempt.Status = StatusPending
+		attempt.Error = err.Error()
+		attempt.NextAttemptAt = d.now().Add(Backoff(attempt.Attempts, d.config.InitialBackoff, d.config.MaxBackoff))
+		slog.Warn("Webhook delivery failed, retrying", "delivery_id", delivery.ID, "url", delivery.URL, "attempts", attempt.Attempts, "next_attempt_at", attempt.NextAttemptAt, "error", err)
+	}
+
+	if err := d.store.RecordAttempt(ctx, attempt); err != nil {
+		slog.Error("Failed to record webhook delivery attempt", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send posts the signed event and returns the response status. Any status
+// other than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) (int, error) {
+	body, err := json.Marshal(envelope{
+		ID:        strconv.FormatInt(delivery.EventID, 10),
+		Type:      delivery.EventType,
+		Tenant:    delivery.Tenant,
+		CreatedAt: delivery.CreatedAt.UTC().Format(time.RFC3339),
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "packing-service-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff returns the wait after the given number of failed attempts: initial
+// after the first, doubling with each further attempt up to max
+func Backoff(attempts int, initial, max time.Duration) time.Duration {
+	backoff := initial
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= max {
+			return max
+		}
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
@@ -0,0 +1,265 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockStore implements Store for testing
+type mockStore struct {
+	mu         sync.Mutex
+	events     int
+	dispatched int
+	deliveries []Delivery
+	attempts   []Attempt
+	cleanedUp  time.Time
+}
+
+func (m *mockStore) DispatchEvents(ctx context.Context, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := m.events
+	if n > limit {
+		n = limit
+	}
+	m.events -= n
+	m.dispatched += n
+	return n, nil
+}
+
+func (m *mockStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deliveries := m.deliveries
+	m.deliveries = nil
+	return deliveries, nil
+}
+
+func (m *mockStore) RecordAttempt(ctx context.Context, attempt Attempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts = append(m.attempts, attempt)
+	return nil
+}
+
+func (m *mockStore) Cleanup(ctx context.Context, before time.Time) error {
+	m.cleanedUp = before
+	return nil
+}
+
+// loopback lets the dispatcher reach the test servers
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func TestDispatcher_RunOnce(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		status         int
+		attempts       int
+		expectedStatus string
+		expectedNext   time.Time
+	}{
+		{name: "Success", status: http.StatusNoContent, expectedStatus: StatusSucceeded},
+		{name: "First failure", status: http.StatusInternalServerError, expectedStatus: StatusPending, expectedNext: now.Add(30 * time.Second)},
+		{name: "Third failure", status: http.StatusBadGateway, attempts: 2, expectedStatus: StatusPending, expectedNext: now.Add(2 * time.Minute)},
+		{name: "Redirect is a failure", status: http.StatusFound, expectedStatus: StatusPending, expectedNext: now.Add(30 * time.Second)},
+		{name: "Last attempt goes dead", status: http.StatusInternalServerError, attempts: 7, expectedStatus: StatusDead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *http.Request
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = io.ReadAll(r.Body)
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			store := &mockStore{events: 3, deliveries: []Delivery{{
+				ID:             42,
+				SubscriptionID: 1,
+				URL:            server.URL,
+				Secret:         "whsec_test",
+				EventID:        9,
+				EventType:      EventPackSizeCreated,
+				Tenant:         "acme",
+				Payload:        json.RawMessage(`{"id":5,"size":250}`),
+				CreatedAt:      now,
+				Attempts:       tt.attempts,
+			}}}
+			dispatcher := NewDispatcher(store, DispatcherConfig{AllowedNetworks: loopback})
+			dispatcher.now = func() time.Time { return now }
+
+			if err := dispatcher.RunOnce(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if store.dispatched != 3 {
+				t.Errorf("expected 3 dispatched events, got %d", store.dispatched)
+			}
+			if len(store.attempts) != 1 {
+				t.Fatalf("expected 1 recorded attempt, got %d", len(store.attempts))
+			}
+			attempt := store.attempts[0]
+			if attempt.Status != tt.expectedStatus || attempt.Attempts != tt.attempts+1 || attempt.StatusCode != tt.status {
+				t.Errorf("unexpected attempt %+v", attempt)
+			}
+			if !attempt.NextAttemptAt.Equal(tt.expectedNext) {
+				t.Errorf("expected next attempt at %v, got %v", tt.expectedNext, attempt.NextAttemptAt)
+			}
+			if (attempt.Error == "") != (tt.expectedStatus == StatusSucceeded) {
+				t.Errorf("unexpected error %q for status %s", attempt.Error, attempt.Status)
+			}
+
+			if received.Header.Get("X-Webhook-Event") != EventPackSizeCreated || received.Header.Get("X-Webhook-Delivery") != "42" {
+				t.Errorf("unexpected headers %v", received.Header)
+			}
+			if err := Verify("whsec_test", received.Header.Get(SignatureHeader), body, time.Minute, now); err != nil {
+				t.Errorf("expected a valid signature: %v", err)
+			}
+			var envelope map[string]interface{}
+			if err := json.Unmarshal(body, &envelope); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if envelope["id"] != "9" || envelope["type"] != EventPackSizeCreated || envelope["tenant"] != "acme" || envelope["created_at"] != "2026-01-01T12:00:00Z" {
+				t.Errorf("unexpected envelope %v", envelope)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	store := &mockStore{deliveries: []Delivery{{ID: 1, URL: url, Payload: json.RawMessage(`{}`)}}}
+	dispatcher := NewDispatcher(store, DispatcherConfig{AllowedNetworks: loopback})
+
+	if err := dispatcher.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.attempts) != 1 || store.attempts[0].Status != StatusPending || store.attempts[0].StatusCode != 0 {
+		t.Errorf("expected a pending attempt without status code, got %+v", store.attempts)
+	}
+}
+
+func TestDispatcher_RefusesInternalAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	store := &mockStore{deliveries: []Delivery{{ID: 1, URL: server.URL, Payload: json.RawMessage(`{}`)}}}
+	dispatcher := NewDispatcher(store, DispatcherConfig{})
+
+	if err := dispatcher.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requested {
+		t.Error("expected no request to reach the internal address")
+	}
+	if len(store.attempts) != 1 || store.attempts[0].Status != StatusPending || !strings.Contains(store.attempts[0].Error, ErrForbiddenAddress.Error()) {
+		t.Errorf("expected a pending attempt refused by address, got %+v", store.attempts)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}
+
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"10.0.0.5:80", false},
+		{"172.16.3.4:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"[fd00::1]:80", false},
+		{"[fe80::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"10.1.2.3:8080", true},
+	}
+
+	for _, tt := range tests {
+		err := checkAddress(tt.address, allowed)
+		if (err == nil) != tt.allowed {
+			t.Errorf("checkAddress(%s): expected allowed %v, got error %v", tt.address, tt.allowed, err)
+		}
+		if err != nil && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("checkAddress(%s): expected ErrForbiddenAddress, got %v", tt.address, err)
+		}
+	}
+}
+
+func TestDispatcher_DispatchesAllBatches(t *testing.T) {
+	store := &mockStore{events: 250}
+	dispatcher := NewDispatcher(store, DispatcherConfig{BatchSize: 100})
+
+	if err := dispatcher.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.dispatched != 250 {
+		t.Errorf("expected 250 dispatched events, got %d", store.dispatched)
+	}
+}
+
+func TestDispatcher_Cleanup(t *testing.T) {
+	store := &mockStore{}
+	dispatcher := NewDispatcher(store, DispatcherConfig{Retention: 24 * time.Hour})
+	now := dispatcher.lastCleanup
+	dispatcher.now = func() time.Time { return now }
+
+	dispatcher.RunOnce(context.Background())
+	if !store.cleanedUp.IsZero() {
+		t.Fatal("expected no cleanup before the cleanup interval")
+	}
+
+	now = now.Add(cleanupInterval)
+	dispatcher.RunOnce(context.Background())
+	if !store.cleanedUp.Equal(now.Add(-24 * time.Hour)) {
+		t.Errorf("expected cleanup of events before %v, got %v", now.Add(-24*time.Hour), store.cleanedUp)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts, 30*time.Second, time.Hour); got != tt.expected {
+			t.Errorf("Backoff(%d) = %v, expected %v", tt.attempts, got, tt.expected)
+		}
+	}
+}
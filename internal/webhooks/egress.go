@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for deliveries to internal addresses that
+// are not allowed by the configuration
+var ErrForbiddenAddress = errors.New("address not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range, internal to providers
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// newTransport returns a transport that only connects to public addresses and
+// the allowed networks. The check runs on the resolved address of every
+// connection, so host names resolving to internal addresses are refused as
+// well. Proxies are not used, since the check would then only apply to the
+// proxy.
+func newTransport(allowed []netip.Prefix) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkAddress(address, allowed)
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// checkAddress refuses loopback, private, shared, link-local, multicast and
+// unspecified addresses outside the allowed networks
+func checkAddress(address string, allowed []netip.Prefix) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", address, err)
+	}
+	ip := addrPort.Addr().Unmap()
+
+	for _, prefix := range allowed {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
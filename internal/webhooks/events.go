@@ -0,0 +1,29 @@
+// Package webhooks delivers the events recorded in the transactional outbox to
+// the HTTP endpoints tenants subscribed, signed with each subscription's secret.
+package webhooks
+
+// Event types that can be subscribed to
+const (
+	EventPackSizeCreated = "pack_size.created"
+	EventPackSizeUpdated = "pack_size.updated"
+	EventPackSizeDeleted = "pack_size.deleted"
+	EventOrderCalculated = "order.calculated"
+)
+
+// EventTypes lists every event type in the order they are documented
+var EventTypes = []string{
+	EventPackSizeCreated,
+	EventPackSizeUpdated,
+	EventPackSizeDeleted,
+	EventOrderCalculated,
+}
+
+// IsEventType reports whether name is a known event type
+func IsEventType(name string) bool {
+	for _, eventType := range EventTypes {
+		if name == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+	header := Sign("whsec_test", now, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "Valid", secret: "whsec_test", header: header, body: body, now: now},
+		{name: "Within tolerance", secret: "whsec_test", header: header, body: body, now: now.Add(4 * time.Minute)},
+		{name: "Too old", secret: "whsec_test", header: header, body: body, now: now.Add(6 * time.Minute), wantErr: true},
+		{name: "Wrong secret", secret: "whsec_other", header: header, body: body, now: now, wantErr: true},
+		{name: "Modified body", secret: "whsec_test", header: header, body: []byte(`{"id":"2"}`), now: now, wantErr: true},
+		{name: "Modified timestamp", secret: "whsec_test", header: strings.Replace(header, "t=1700000000", "t=1700000001", 1), body: body, now: now, wantErr: true},
+		{name: "Malformed header", secret: "whsec_test", header: "v1=abc", body: body, now: now, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	second, _ := GenerateSecret()
+
+	if !strings.HasPrefix(first, "whsec_") || len(first) != len("whsec_")+64 {
+		t.Errorf("unexpected secret format %q", first)
+	}
+	if first == second {
+		t.Error("expected different secrets")
+	}
+}
@@ -2,20 +2,19 @@ package main
 
 import (
 	"log"
+	"os"
 
-	"github.com/miloradbozic/packing-service/internal/app"
+	"github.com/miloradbozic/packing-service/internal/cli"
 )
 
 func main() {
-	// Create and initialize the application
-	application, err := app.New()
+	assets, err := embeddedAssets()
 	if err != nil {
-		log.Fatalf("Failed to initialize application: %v", err)
+		log.Fatalf("Failed to load embedded assets: %v", err)
 	}
-	defer application.Close()
 
-	// Start the server
-	if err := application.Run(); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	// Run the requested subcommand (serves HTTP by default)
+	if err := cli.Run(os.Args[1:], assets); err != nil {
+		log.Fatalf("%v", err)
 	}
 }
@@ -0,0 +1,6 @@
+-- Migration: Drop pack_sizes table
+-- Created: 2024-01-01
+
+DROP TRIGGER IF EXISTS update_pack_sizes_updated_at ON pack_sizes;
+DROP TABLE IF EXISTS pack_sizes;
+DROP FUNCTION IF EXISTS update_updated_at_column();
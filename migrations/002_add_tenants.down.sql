@@ -0,0 +1,15 @@
+-- Migration: Remove tenant scoping from pack sizes
+-- Created: 2026-10-18
+
+DROP POLICY IF EXISTS pack_sizes_tenant_isolation ON pack_sizes;
+ALTER TABLE pack_sizes DISABLE ROW LEVEL SECURITY;
+
+-- Only the default tenant's pack sizes survive the rollback
+DELETE FROM pack_sizes WHERE tenant_id <> (SELECT id FROM tenants WHERE slug = 'default');
+
+ALTER TABLE pack_sizes DROP CONSTRAINT IF EXISTS pack_sizes_tenant_id_size_key;
+ALTER TABLE pack_sizes ADD CONSTRAINT pack_sizes_size_key UNIQUE (size);
+CREATE INDEX IF NOT EXISTS idx_pack_sizes_size ON pack_sizes(size);
+
+ALTER TABLE pack_sizes DROP COLUMN tenant_id;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,34 @@
+-- Migration: Scope pack sizes to tenants
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing pack sizes belong to the default tenant
+INSERT INTO tenants (slug, name) VALUES ('default', 'Default')
+ON CONFLICT (slug) DO NOTHING;
+
+-- The backfill is not a change to the pack sizes, so it keeps their updated_at
+ALTER TABLE pack_sizes ADD COLUMN tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE pack_sizes DISABLE TRIGGER update_pack_sizes_updated_at;
+UPDATE pack_sizes SET tenant_id = (SELECT id FROM tenants WHERE slug = 'default');
+ALTER TABLE pack_sizes ENABLE TRIGGER update_pack_sizes_updated_at;
+ALTER TABLE pack_sizes ALTER COLUMN tenant_id SET NOT NULL;
+
+-- Sizes are unique per tenant instead of globally
+ALTER TABLE pack_sizes DROP CONSTRAINT IF EXISTS pack_sizes_size_key;
+ALTER TABLE pack_sizes ADD CONSTRAINT pack_sizes_tenant_id_size_key UNIQUE (tenant_id, size);
+DROP INDEX IF EXISTS idx_pack_sizes_size;
+
+-- Row-level security for deployments that connect as a non-owner role.
+-- The table owner bypasses these policies; the application sets app.tenant_id
+-- per transaction when database.row_level_security is enabled.
+ALTER TABLE pack_sizes ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS pack_sizes_tenant_isolation ON pack_sizes;
+CREATE POLICY pack_sizes_tenant_isolation ON pack_sizes
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::INTEGER)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::INTEGER);
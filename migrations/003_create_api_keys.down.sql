@@ -0,0 +1,4 @@
+-- Migration: Drop api_keys table
+-- Created: 2026-10-18
+
+DROP TABLE IF EXISTS api_keys;
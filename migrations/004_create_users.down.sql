@@ -0,0 +1,4 @@
+-- Migration: Drop users table
+-- Created: 2026-10-18
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,16 @@
+-- Migration: Create users table for web UI logins
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    -- PBKDF2-SHA256 hash: pbkdf2-sha256$<iterations>$<salt>$<hash>
+    password_hash TEXT NOT NULL,
+    -- Comma separated list of scopes: calculate, read, manage, admin
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id);
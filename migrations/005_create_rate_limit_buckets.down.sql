@@ -0,0 +1,4 @@
+-- Migration: Drop rate_limit_buckets table
+-- Created: 2026-10-18
+
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,4 @@
+-- Migration: Drop idempotency_keys table
+-- Created: 2026-10-18
+
+DROP TABLE IF EXISTS idempotency_keys;
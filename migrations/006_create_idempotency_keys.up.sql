@@ -0,0 +1,19 @@
+-- Migration: Create idempotency_keys table for replaying retried POST requests
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    -- The caller the key belongs to: tenant and principal
+    scope TEXT NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    -- SHA-256 of the method, path and body of the first request
+    fingerprint CHAR(64) NOT NULL,
+    -- NULL while the first request is still being processed
+    status_code INTEGER,
+    content_type TEXT,
+    body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
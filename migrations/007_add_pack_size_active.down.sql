@@ -0,0 +1,6 @@
+-- Migration: Remove the pack size active flag and listing indexes
+-- Created: 2026-10-18
+
+DROP INDEX IF EXISTS idx_pack_sizes_tenant_updated_at;
+DROP INDEX IF EXISTS idx_pack_sizes_tenant_created_at;
+ALTER TABLE pack_sizes DROP COLUMN IF EXISTS active;
@@ -0,0 +1,9 @@
+-- Migration: Add an active flag to pack sizes and indexes for paged listings
+-- Created: 2026-10-18
+
+-- Inactive pack sizes are kept but not used for calculations
+ALTER TABLE pack_sizes ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Listings page through a tenant's pack sizes by (sort column, id)
+CREATE INDEX IF NOT EXISTS idx_pack_sizes_tenant_created_at ON pack_sizes(tenant_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_pack_sizes_tenant_updated_at ON pack_sizes(tenant_id, updated_at, id);
@@ -0,0 +1,6 @@
+-- Migration: Drop webhook subscriptions, the event outbox and the delivery log
+-- Created: 2026-10-18
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS webhook_subscriptions;
@@ -0,0 +1,4 @@
+-- Migration: Remove the idempotency key lease
+-- Created: 2026-10-18
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS locked_until;
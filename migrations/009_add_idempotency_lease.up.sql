@@ -0,0 +1,5 @@
+-- Migration: Add a lease to idempotency keys, so claims of crashed requests can be taken over
+-- Created: 2026-10-18
+
+-- While status_code is NULL, the claim is only held until locked_until
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;